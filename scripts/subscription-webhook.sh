@@ -0,0 +1,33 @@
+#!/usr/bin/env bash
+# Act as the payment provider, sending a signed subscription event to a local API
+#
+# Usage: scripts/subscription-webhook.sh <type> <subscription id> <user id> <price id> [period end]
+# e.g.   scripts/subscription-webhook.sh created sub_123 60f0a1b2c3d4e5f6a7b8c9d0 price_tier1
+#
+# Environment:
+#   API_URL         the API's base URL (default: http://localhost:8080/v2)
+#   WEBHOOK_SECRET  the subscriptions.webhook_secret of the API's config
+set -euo pipefail
+
+if [ $# -lt 4 ]; then
+	sed -n '4,5p' "$0" | sed 's/^# //'
+	exit 1
+fi
+
+API_URL="${API_URL:-http://localhost:8080/v2}"
+: "${WEBHOOK_SECRET:?WEBHOOK_SECRET must be set}"
+
+TYPE="subscription.$1"
+PERIOD_END="${5:-$(date -u -d '+30 days' +%Y-%m-%dT%H:%M:%SZ)}"
+EVENT_ID="evt_$(date +%s%N)"
+
+BODY=$(printf '{"id":"%s","type":"%s","data":{"subscription_id":"%s","user_id":"%s","price_id":"%s","current_period_end":"%s"}}' \
+	"$EVENT_ID" "$TYPE" "$2" "$3" "$4" "$PERIOD_END")
+TIMESTAMP=$(date +%s)
+SIGNATURE=$(printf '%s.%s' "$TIMESTAMP" "$BODY" | openssl dgst -sha256 -hmac "$WEBHOOK_SECRET" | sed 's/^.* //')
+
+curl -sS -X POST "$API_URL/subscriptions/webhook" \
+	-H "Content-Type: application/json" \
+	-H "X-Signature: t=$TIMESTAMP,v1=$SIGNATURE" \
+	-d "$BODY"
+echo
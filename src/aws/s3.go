@@ -3,6 +3,7 @@ package aws
 import (
 	"bytes"
 	"fmt"
+	"io"
 
 	"github.com/SevenTV/ServerGo/src/configure"
 	"github.com/sirupsen/logrus"
@@ -45,6 +46,32 @@ func UploadFile(bucket, key string, body []byte, contentType *string) error {
 	return nil
 }
 
+// UploadPrivateFile uploads a file which is not publicly readable and must not be cached, such as an internal archive
+func UploadPrivateFile(bucket, key string, body []byte, contentType *string) error {
+	result, err := uploader.Upload(&s3manager.UploadInput{
+		Bucket:      aws.String(bucket),
+		Key:         aws.String(key),
+		Body:        bytes.NewReader(body),
+		ACL:         aws.String("private"),
+		ContentType: contentType,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to upload file, %v", err)
+	}
+	logrus.Debugf("file uploaded to, %s", result.Location)
+	return nil
+}
+
+func GetFile(bucket, key string) ([]byte, error) {
+	out, err := svc.GetObject(&s3.GetObjectInput{Bucket: aws.String(bucket), Key: aws.String(key)})
+	if err != nil {
+		return nil, fmt.Errorf("unable to get object %q from bucket %q, %v", key, bucket, err)
+	}
+	defer out.Body.Close()
+
+	return io.ReadAll(out.Body)
+}
+
 func Expire(bucket, key string, number int) error {
 	obj := fmt.Sprintf("deleted/%s/%vx", key, number)
 
@@ -133,6 +133,55 @@ func SendEmoteMerge(emote1 datastructure.Emote, emote2 datastructure.Emote, acto
 	})
 }
 
+func SendBanCascade(victim datastructure.User, actor datastructure.User, mode string, emotes int, failed int, reason *string) {
+	fields := []*dgo.MessageEmbedField{
+		{Name: "Mode", Value: mode, Inline: true},
+		{Name: "Emotes", Value: fmt.Sprint(emotes), Inline: true},
+		{Name: "Failed", Value: fmt.Sprint(failed), Inline: true},
+	}
+	if reason != nil && len(*reason) > 0 {
+		fields = append(fields, &dgo.MessageEmbedField{
+			Name:  "Reason",
+			Value: *reason,
+		})
+	}
+
+	_ = SendWebhook("activity", &dgo.WebhookParams{
+		Content: fmt.Sprintf("**[activity]** 🔨 emotes of the banned user [%s](%v) handled by [%s](%v)", victim.DisplayName, utils.GetUserPageURL(victim.ID.Hex()), actor.DisplayName, utils.GetUserPageURL(actor.ID.Hex())),
+		Embeds: []*dgo.MessageEmbed{
+			{
+				Author: &dgo.MessageEmbedAuthor{
+					URL:     utils.GetUserPageURL(actor.ID.Hex()),
+					IconURL: actor.ProfileImageURL,
+					Name:    actor.DisplayName,
+				},
+				Fields: fields,
+				Color:  16725715,
+			},
+		},
+	})
+}
+
+func SendReportEscalation(emote datastructure.Emote, reporters int, score float64, unlisted bool) {
+	_ = SendWebhook("alerts", &dgo.WebhookParams{
+		Content: fmt.Sprintf("**[reports]** 🚨 emote [%s](%v) has been reported by %d users", emote.Name, utils.GetEmotePageURL(emote.ID.Hex()), reporters),
+		Embeds: []*dgo.MessageEmbed{
+			{
+				Title: emote.Name,
+				Thumbnail: &dgo.MessageEmbedThumbnail{
+					URL: utils.GetEmoteImageURL(emote.ID.Hex()),
+				},
+				Color: 16728642,
+				Fields: []*dgo.MessageEmbedField{
+					{Name: "Reporters", Value: fmt.Sprint(reporters), Inline: true},
+					{Name: "Weighted Score", Value: fmt.Sprintf("%.2f", score), Inline: true},
+					{Name: "Auto-Unlisted", Value: fmt.Sprint(unlisted), Inline: true},
+				},
+			},
+		},
+	})
+}
+
 func SendPopularityCheckUpdateNotice(wg *sync.WaitGroup) {
 	_ = SendWebhook("activity", &dgo.WebhookParams{
 		Content: "**[routine]** ⚙️ updating emote popularities...",
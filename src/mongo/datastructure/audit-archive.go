@@ -0,0 +1,31 @@
+package datastructure
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// AuditArchive is the summary of a batch of audit log entries which were moved out of the database into object storage
+//
+// The summary lists what the archived entries are about, so that archives can be found without downloading them
+type AuditArchive struct {
+	ID primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	// The object storage key of the archive, a gzip compressed NDJSON file with one entry per line
+	Key string `json:"key" bson:"key"`
+	// The ID of the first and last entries of the archive, in chronological order
+	FirstID primitive.ObjectID `json:"first_id" bson:"first_id"`
+	LastID  primitive.ObjectID `json:"last_id" bson:"last_id"`
+	// The time range covered by the archive
+	From time.Time `json:"from" bson:"from"`
+	To   time.Time `json:"to" bson:"to"`
+	// The amount of entries in the archive
+	Count int32 `json:"count" bson:"count"`
+	// The compressed size of the archive in bytes
+	Size int64 `json:"size" bson:"size"`
+	// The distinct types, actors and targets of the archived entries
+	Types       []int32              `json:"types" bson:"types"`
+	ActorIDs    []primitive.ObjectID `json:"actor_ids" bson:"actor_ids"`
+	TargetIDs   []primitive.ObjectID `json:"target_ids" bson:"target_ids"`
+	TargetTypes []string             `json:"target_types" bson:"target_types"`
+}
@@ -1,7 +1,10 @@
 package datastructure
 
 import (
+	"errors"
 	"fmt"
+	"math"
+	"net/url"
 	"time"
 
 	"github.com/SevenTV/ServerGo/src/configure"
@@ -125,6 +128,11 @@ type User struct {
 	Badge            *primitive.ObjectID `json:"badge" bson:"badge"`             // User's badge, if any
 	EmoteSlots       int32               `json:"emote_slots" bson:"emote_slots"` // User's maximum channel emote slots
 
+	MutedNotifications []NotificationCategory `json:"-" bson:"muted_notifications,omitempty"` // Categories of system notifications the user does not want to receive
+
+	// The emote set whose contents are the user's channel emotes, if the user made any emote sets
+	ActiveEmoteSetID *primitive.ObjectID `json:"active_emote_set_id,omitempty" bson:"active_emote_set_id,omitempty"`
+
 	// Relational Data
 	Emotes            *[]*Emote       `json:"emotes" bson:"-"`
 	OwnedEmotes       *[]*Emote       `json:"owned_emotes" bson:"-"`
@@ -217,6 +225,8 @@ const (
 	RolePermissionManageEntitlements                     // 4096 - (Elevated) Allows granting and revoking entitlements to and from users
 	RolePermissionUseZeroWidthEmote                      // 8192 - Allows zero-width emotes to be enabled
 	RolePermissionUseCustomAvatars                       // 16384 - Allows setting a custom avatar
+	RolePermissionSendNotifications                      // 32768 - (Elevated) Allows composing, scheduling and retracting notifications and announcements
+	RolePermissionManageCosmetics                        // 65536 - (Elevated) Allows creating, editing and deleting badges and paints
 
 	RolePermissionAll int64 = (1 << iota) - 1
 )
@@ -232,9 +242,56 @@ type Ban struct {
 	UserID     *primitive.ObjectID `json:"user_id" bson:"user_id"`
 	Reason     string              `json:"reason" bson:"reason"`
 	IssuedByID *primitive.ObjectID `json:"issued_by_id" bson:"issued_by_id"`
-	ExpireAt   time.Time           `json:"expire_at" bson:"expire_at"`
+	ExpireAt   *time.Time          `json:"expire_at" bson:"expire_at"`
+	Type       BanType             `json:"type" bson:"type,omitempty"`
+}
+
+// IsActive returns whether or not the ban is currently in effect. A ban without an expiry date is permanent
+func (b *Ban) IsActive() bool {
+	return b.ExpireAt == nil || b.ExpireAt.After(time.Now())
+}
+
+// GetType returns the restriction applied by the ban. Bans created before restriction types existed are full bans
+func (b *Ban) GetType() BanType {
+	if b.Type == "" {
+		return BanTypeFull
+	}
+	return b.Type
+}
+
+type BanType string
+
+const (
+	BanTypeFull   BanType = "FULL"   // Locks the user out of all authenticated actions
+	BanTypeUpload BanType = "UPLOAD" // Prevents the user from uploading emotes
+	BanTypeReport BanType = "REPORT" // Prevents the user from creating reports
+	BanTypeEditor BanType = "EDITOR" // Prevents the user from being added as a channel editor
+)
+
+var BanTypes = []BanType{BanTypeFull, BanTypeUpload, BanTypeReport, BanTypeEditor}
+
+type BanAppeal struct {
+	ID         primitive.ObjectID  `json:"id" bson:"_id,omitempty"`
+	BanID      primitive.ObjectID  `json:"ban_id" bson:"ban_id"`
+	UserID     primitive.ObjectID  `json:"user_id" bson:"user_id"`
+	Message    string              `json:"message" bson:"message"`
+	Status     BanAppealStatus     `json:"status" bson:"status"`
+	ReviewerID *primitive.ObjectID `json:"reviewer_id" bson:"reviewer_id"`
+	ReviewNote *string             `json:"review_note" bson:"review_note"`
+	ReviewedAt *time.Time          `json:"reviewed_at" bson:"reviewed_at"`
+
+	Ban *Ban `json:"ban" bson:"-"`
 }
 
+type BanAppealStatus string
+
+const (
+	BanAppealStatusPending  BanAppealStatus = "PENDING"  // Waiting for a moderator to review
+	BanAppealStatusAccepted BanAppealStatus = "ACCEPTED" // The ban was lifted
+	BanAppealStatusDenied   BanAppealStatus = "DENIED"   // The ban stays as is
+	BanAppealStatusReduced  BanAppealStatus = "REDUCED"  // The ban's duration was shortened
+)
+
 type AuditLog struct {
 	ID        primitive.ObjectID `json:"id" bson:"_id,omitempty"`
 	Type      int32              `json:"type" bson:"type"`
@@ -242,6 +299,8 @@ type AuditLog struct {
 	Changes   []*AuditLogChange  `json:"changes" bson:"changes"`
 	Reason    *string            `json:"reason" bson:"reason"`
 	CreatedBy primitive.ObjectID `json:"action_user_id" bson:"action_user"`
+	// The entry undone by this entry, if it is a revert
+	RevertOf *primitive.ObjectID `json:"revert_of,omitempty" bson:"revert_of,omitempty"`
 }
 
 type Target struct {
@@ -256,11 +315,20 @@ type AuditLogChange struct {
 }
 
 type Report struct {
-	ID         primitive.ObjectID  `json:"id" bson:"_id"`
-	ReporterID *primitive.ObjectID `json:"reporter_id" bson:"reporter_id"`
-	Reason     string              `json:"reason" bson:"target"`
-	Target     *Target             `json:"target" bson:"target"`
-	Cleared    bool                `json:"cleared" bson:"cleared"`
+	ID          primitive.ObjectID  `json:"id" bson:"_id"`
+	ReporterID  *primitive.ObjectID `json:"reporter_id" bson:"reporter_id"`
+	Reason      string              `json:"reason" bson:"reason"`
+	Category    ReportCategory      `json:"category" bson:"category,omitempty"`
+	Evidence    *ReportEvidence     `json:"evidence" bson:"evidence,omitempty"`
+	Target      *Target             `json:"target" bson:"target"`
+	Cleared     bool                `json:"cleared" bson:"cleared"`
+	Status      ReportStatus        `json:"status" bson:"status,omitempty"`
+	AssigneeID  *primitive.ObjectID `json:"assignee_id" bson:"assignee_id,omitempty"`
+	ActionTaken *string             `json:"action_taken" bson:"action_taken,omitempty"`
+	Notes       []*ReportNote       `json:"notes" bson:"notes,omitempty"`
+	ClosedAt    *time.Time          `json:"closed_at" bson:"closed_at,omitempty"`
+	// When the reporter last filed the report, which is refreshed when they report the same target again
+	LastReportedAt *time.Time `json:"last_reported_at" bson:"last_reported_at,omitempty"`
 
 	ETarget      *Emote       `json:"e_target" bson:"-"`
 	UTarget      *User        `json:"u_target" bson:"-"`
@@ -268,6 +336,64 @@ type Report struct {
 	AuditEntries *[]*AuditLog `json:"audit_entries" bson:"-"`
 }
 
+// GetStatus returns the state of the report. Reports created before statuses existed only have the cleared flag
+func (r *Report) GetStatus() ReportStatus {
+	if r.Status != "" {
+		return r.Status
+	}
+	if r.Cleared {
+		return ReportStatusResolved
+	}
+	return ReportStatusOpen
+}
+
+// GetCategory returns the category of the report. Reports created before categories existed only have a free-text reason
+func (r *Report) GetCategory() ReportCategory {
+	if r.Category == "" {
+		return ReportCategoryOther
+	}
+	return r.Category
+}
+
+type ReportCategory string
+
+const (
+	ReportCategoryHateful       ReportCategory = "HATEFUL"       // Hateful or harassing content
+	ReportCategoryCopyright     ReportCategory = "COPYRIGHT"     // Copyright infringement
+	ReportCategoryImpersonation ReportCategory = "IMPERSONATION" // Impersonation of another person or brand
+	ReportCategoryNSFW          ReportCategory = "NSFW"          // Sexual or otherwise explicit content
+	ReportCategorySpam          ReportCategory = "SPAM"          // Spam or misleading content
+	ReportCategoryOther         ReportCategory = "OTHER"         // Anything else, described by the reason
+)
+
+var ReportCategories = []ReportCategory{
+	ReportCategoryHateful,
+	ReportCategoryCopyright,
+	ReportCategoryImpersonation,
+	ReportCategoryNSFW,
+	ReportCategorySpam,
+	ReportCategoryOther,
+}
+
+type ReportEvidence struct {
+	Links   []string `json:"links" bson:"links,omitempty"`
+	ChatLog *string  `json:"chat_log" bson:"chat_log,omitempty"`
+}
+
+type ReportStatus string
+
+const (
+	ReportStatusOpen     ReportStatus = "OPEN"     // Waiting to be handled by a moderator
+	ReportStatusResolved ReportStatus = "RESOLVED" // Handled, with an action taken against the target
+	ReportStatusRejected ReportStatus = "REJECTED" // Dismissed without action
+)
+
+type ReportNote struct {
+	AuthorID  primitive.ObjectID `json:"author_id" bson:"author_id"`
+	Content   string             `json:"content" bson:"content"`
+	CreatedAt time.Time          `json:"created_at" bson:"created_at"`
+}
+
 const (
 	// Emotes (1-19)
 	AuditLogTypeEmoteCreate     = 1
@@ -292,6 +418,9 @@ const (
 	AuditLogTypeUserChannelEditorAdd    = 37
 	AuditLogTypeUserChannelEditorRemove = 38
 	AuditLogTypeUserChannelEmoteEdit    = 39
+	AuditLogTypeUserBanAppeal           = 40
+	AuditLogTypeUserBanAppealReview     = 41
+	AuditLogTypeUserBanCascade          = 42
 
 	// Admin (70-89)
 	AuditLogTypeAppMaintenanceMode = 70
@@ -304,8 +433,34 @@ const (
 	AuditLogTypeAppNodeUnref       = 76
 
 	// Reports (90-99)
-	AuditLogTypeReport      = 90
-	AuditLogTypeReportClear = 91
+	AuditLogTypeReport             = 90
+	AuditLogTypeReportClear        = 91
+	AuditLogTypeReportAssign       = 92
+	AuditLogTypeReportReopen       = 93
+	AuditLogTypeReportNote         = 94
+	AuditLogTypeReportAutoUnlist   = 95
+	AuditLogTypeReportAutoEscalate = 96
+	AuditLogTypeReportAutoRevert   = 97
+
+	// Notifications (100-109)
+	AuditLogTypeNotificationCreate  = 100
+	AuditLogTypeNotificationEdit    = 101
+	AuditLogTypeNotificationRetract = 102
+
+	// Cosmetics (110-119)
+	AuditLogTypeCosmeticCreate       = 110
+	AuditLogTypeCosmeticEdit         = 111
+	AuditLogTypeCosmeticDelete       = 112
+	AuditLogTypeCosmeticReprioritize = 113
+
+	// Entitlements (120-129)
+	AuditLogTypeEntitlementBulkGrant  = 120
+	AuditLogTypeEntitlementBulkRevoke = 121
+
+	// Emote Sets (130-139)
+	AuditLogTypeEmoteSetCreate   = 130
+	AuditLogTypeEmoteSetDelete   = 131
+	AuditLogTypeEmoteSetActivate = 132
 )
 
 type Cosmetic struct {
@@ -352,6 +507,106 @@ type CosmeticDataBadge struct {
 	Misc    bool               `json:"misc,omitempty" bson:"misc"`
 }
 
+type cosmeticUtil struct{}
+
+func (*cosmeticUtil) GetBadgeFilesMeta(fileDir string) [][]string {
+	// Define sizes to be generated, badges are square
+	// File path, badge size, badge width/height, quality factor
+	return [][]string{
+		{fmt.Sprintf("%s/1x", fileDir), "1x", "18x18", "100"},
+		{fmt.Sprintf("%s/2x", fileDir), "2x", "36x36", "100"},
+		{fmt.Sprintf("%s/3x", fileDir), "3x", "72x72", "95"},
+	}
+}
+
+const (
+	CosmeticPaintMaxStops       = 32
+	CosmeticPaintMaxDropShadows = 8
+	CosmeticPaintMaxKeyframes   = 64
+)
+
+// ValidatePaint: Verify that clients will be able to render the paint
+func (*cosmeticUtil) ValidatePaint(paint *CosmeticDataPaint) error {
+	known := false
+	for _, f := range CosmeticPaintFunctions {
+		known = known || paint.Function == f
+	}
+	if !known {
+		return errors.New("unknown function")
+	}
+
+	switch paint.Function {
+	case CosmeticPaintFunctionLinearGradient, CosmeticPaintFunctionRadialGradient:
+		if len(paint.Stops) < 2 {
+			return errors.New("a gradient needs at least 2 stops")
+		}
+	case CosmeticPaintFunctionImageURL:
+		u, err := url.Parse(paint.ImageURL)
+		if err != nil || u.Scheme != "https" || u.Host == "" {
+			return errors.New("an image paint needs an https image url")
+		}
+	}
+	if paint.Function == CosmeticPaintFunctionRadialGradient && paint.Shape != "circle" && paint.Shape != "ellipse" {
+		return errors.New("a radial gradient needs a shape of circle or ellipse")
+	}
+
+	if len(paint.Stops) > CosmeticPaintMaxStops {
+		return errors.New("too many stops")
+	}
+	for i, s := range paint.Stops {
+		// Stops may share a position, which makes a hard transition between two colors
+		if !inUnitRange(s.At) || (i > 0 && s.At < paint.Stops[i-1].At) {
+			return errors.New("stops must be between 0 and 1, in ascending order")
+		}
+	}
+
+	if paint.Angle < 0 || paint.Angle > 360 {
+		return errors.New("angle must be between 0 and 360")
+	}
+
+	if len(paint.DropShadows) > CosmeticPaintMaxDropShadows {
+		return errors.New("too many drop shadows")
+	}
+	for _, s := range paint.DropShadows {
+		if s.Radius < 0 || !isFinite(s.Radius) || !isFinite(s.OffsetX) || !isFinite(s.OffsetY) {
+			return errors.New("drop shadows need finite offsets and a positive radius")
+		}
+	}
+
+	keyframes := paint.Animation.Keyframes
+	if len(keyframes) > 0 {
+		if paint.Animation.Speed <= 0 {
+			return errors.New("an animation needs a positive speed")
+		}
+		if len(keyframes) < 2 {
+			return errors.New("an animation needs at least 2 keyframes")
+		}
+		if len(keyframes) > CosmeticPaintMaxKeyframes {
+			return errors.New("too many keyframes")
+		}
+		for i, k := range keyframes {
+			if !inUnitRange(k.At) || (i > 0 && k.At <= keyframes[i-1].At) {
+				return errors.New("keyframes must be between 0 and 1, in strictly ascending order")
+			}
+			if !isFinite(k.X) || !isFinite(k.Y) {
+				return errors.New("keyframes need finite positions")
+			}
+		}
+	}
+
+	return nil
+}
+
+func inUnitRange(f float64) bool {
+	return f >= 0 && f <= 1
+}
+
+func isFinite(f float64) bool {
+	return !math.IsNaN(f) && !math.IsInf(f, 0)
+}
+
+var CosmeticUtil cosmeticUtil
+
 type CosmeticDataPaint struct {
 	ID primitive.ObjectID `json:"id" bson:"-"`
 	// The function used to generate the paint (i.e gradients or an image)
@@ -384,6 +639,12 @@ const (
 	CosmeticPaintFunctionImageURL       CosmeticPaintFunction = "url"
 )
 
+var CosmeticPaintFunctions = []CosmeticPaintFunction{
+	CosmeticPaintFunctionLinearGradient,
+	CosmeticPaintFunctionRadialGradient,
+	CosmeticPaintFunctionImageURL,
+}
+
 type CosmeticPaintGradientStop struct {
 	At    float64 `json:"at" bson:"at"`
 	Color int32   `json:"color" bson:"color"`
@@ -434,6 +695,14 @@ type Notification struct {
 
 	Title        string                    `json:"title" bson:"title"`                 // The notification's heading / title
 	MessageParts []NotificationMessagePart `json:"message_parts" bson:"message_parts"` // The parts making up the notification's formatted message
+	Category     NotificationCategory      `json:"category" bson:"category,omitempty"` // The category of system notifications, which users may mute
+
+	AuthorID    *primitive.ObjectID  `json:"author_id" bson:"author_id,omitempty"`       // The staff member who composed the notification. Nil for system notifications
+	Targets     []primitive.ObjectID `json:"targets" bson:"targets,omitempty"`           // The users a composed notification is sent to, in addition to holders of mentioned roles
+	ScheduledAt *time.Time           `json:"scheduled_at" bson:"scheduled_at,omitempty"` // When a composed notification is to be sent
+	Pending     bool                 `json:"pending" bson:"pending,omitempty"`           // Whether the notification is scheduled and was not sent yet
+	Retracted   bool                 `json:"retracted" bson:"retracted,omitempty"`       // Whether the notification was taken back by staff, hiding it from all users
+	ExpireAt    *time.Time           `json:"expire_at" bson:"expire_at,omitempty"`       // When the notification is hidden and later deleted along with its read states
 
 	Read   bool      `json:"read" bson:"read,omitempty"`
 	ReadAt time.Time `json:"read_at" bson:"read_at,omitempty"`
@@ -463,3 +732,19 @@ const (
 )
 
 type NotificationContentMessagePartType int8
+
+type NotificationCategory string
+
+const (
+	NotificationCategoryEmote      NotificationCategory = "EMOTE"      // Moderation of emotes the user owns: deletion, merges, visibility changes
+	NotificationCategoryChannel    NotificationCategory = "CHANNEL"    // Changes to the user's channel made by others: merged channel emotes, editors
+	NotificationCategoryModeration NotificationCategory = "MODERATION" // Bans and ban appeals
+	NotificationCategoryAccount    NotificationCategory = "ACCOUNT"    // Changes to the user's role, slots and entitlements
+)
+
+var NotificationCategories = []NotificationCategory{
+	NotificationCategoryEmote,
+	NotificationCategoryChannel,
+	NotificationCategoryModeration,
+	NotificationCategoryAccount,
+}
@@ -20,3 +20,12 @@ var DeletedUser *User = &User{
 }
 
 var deletedUserID, _ = primitive.ObjectIDFromHex("000000000000000000000001")
+
+// The user credited for automatic actions taken by the server
+var SystemUser *User = &User{
+	ID:          systemUserID,
+	Login:       "*system",
+	DisplayName: "System",
+}
+
+var systemUserID, _ = primitive.ObjectIDFromHex("000000000000000000000002")
@@ -0,0 +1,22 @@
+package datastructure
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// EmoteSet is a named collection of channel emotes owned by a user,
+// of which one at a time is the channel's active set
+//
+// The contents of the active set live on the user, in its emotes and emote aliases,
+// and are written back to the set when another set is activated
+type EmoteSet struct {
+	ID      primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	OwnerID primitive.ObjectID `json:"owner_id" bson:"owner_id"`
+	Name    string             `json:"name" bson:"name"`
+
+	EmoteIDs   []primitive.ObjectID `json:"emote_ids" bson:"emotes"`
+	EmoteAlias map[string]string    `json:"emote_alias" bson:"emote_alias"`
+	// The maximum amount of emotes in the set, or the channel's emote slots if 0.
+	// A set never holds more emotes than its owner has channel emote slots
+	Slots int32 `json:"slots" bson:"slots,omitempty"`
+}
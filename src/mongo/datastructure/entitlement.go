@@ -1,6 +1,8 @@
 package datastructure
 
 import (
+	"time"
+
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
@@ -18,6 +20,23 @@ type Entitlement struct {
 	UserID primitive.ObjectID `json:"user_id" bson:"user_id"`
 	// Wether this entitlement is currently inactive
 	Disabled bool `json:"disabled,omitempty" bson:"disabled,omitempty"`
+	// When the entitlement becomes active, if it isn't immediately
+	StartsAt *time.Time `json:"starts_at,omitempty" bson:"starts_at,omitempty"`
+	// When the entitlement stops being active, if ever
+	EndsAt *time.Time `json:"ends_at,omitempty" bson:"ends_at,omitempty"`
+	// The subscription which granted the entitlement, which is revoked along with it
+	SubscriptionID *primitive.ObjectID `json:"subscription_id,omitempty" bson:"subscription_id,omitempty"`
+}
+
+// IsActive: Whether the entitlement is enabled and within its start and end at the given time
+func (e *Entitlement) IsActive(at time.Time) bool {
+	if e.Disabled {
+		return false
+	}
+	if e.StartsAt != nil && e.StartsAt.After(at) {
+		return false
+	}
+	return e.EndsAt == nil || e.EndsAt.After(at)
 }
 
 // A string representing an Entitlement Kind
@@ -29,6 +48,7 @@ var (
 	EntitlementKindPaint        = EntitlementKind("PAINT")        // Nametag Paint Entitlement
 	EntitlementKindRole         = EntitlementKind("ROLE")         // Role Entitlement
 	EntitlementKindEmoteSet     = EntitlementKind("EMOTE_SET")    // Emote Set Entitlement
+	EntitlementKindEmoteSlots   = EntitlementKind("EMOTE_SLOTS")  // Additional Channel Emote Slots Entitlement
 )
 
 // (Data) Subscription binding in an Entitlement
@@ -63,12 +83,19 @@ type EntitledRole struct {
 	ObjectReference primitive.ObjectID `json:"-" bson:"ref"`
 }
 
+// (Data) Additional channel emote slots in an Entitlement
+type EntitledEmoteSlots struct {
+	Slots int32 `json:"slots" bson:"slots"`
+}
+
 // (Data) Emote Set binding in an Entitlement
 type EntitledEmoteSet struct {
 	ID              string               `json:"id" bson:"-"`
 	ObjectReference primitive.ObjectID   `json:"-" bson:"ref"`
 	UnicodeTag      string               `json:"unicode_tag" bson:"unicode_tag"`
 	EmoteIDs        []primitive.ObjectID `json:"emote_ids" bson:"emotes"`
+	// The amount of emotes the set can hold, or the configured default if 0
+	Slots int32 `json:"slots" bson:"slots,omitempty"`
 
 	// Relational
 
@@ -0,0 +1,48 @@
+package datastructure
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Job is a long running task executed in the background,
+// tracking its progress so that it can be followed by the user who started it
+type Job struct {
+	ID primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	// Kind represents what the job does
+	Kind JobKind `json:"kind" bson:"kind"`
+	// The current state of the job
+	Status JobStatus `json:"status" bson:"status"`
+	// The user who started the job
+	CreatedBy primitive.ObjectID `json:"created_by" bson:"created_by"`
+	// The object the job is acting on, if any
+	Target *Target `json:"target" bson:"target"`
+	// The amount of items the job will process
+	Total int32 `json:"total" bson:"total"`
+	// The amount of items processed so far
+	Processed int32 `json:"processed" bson:"processed"`
+	// The amount of items which could not be processed
+	Failed int32 `json:"failed" bson:"failed"`
+	// The error which stopped the job, if it failed
+	Error *string `json:"error" bson:"error"`
+	// When the job completed or failed
+	FinishedAt *time.Time `json:"finished_at" bson:"finished_at"`
+}
+
+// A string representing a Job Kind
+type JobKind string
+
+var (
+	JobKindBanCascade       = JobKind("BAN_CASCADE")       // Clean up the emotes and editor privileges of a banned user
+	JobKindBulkEntitlements = JobKind("BULK_ENTITLEMENTS") // Grant or revoke an entitlement for many users
+)
+
+// A string representing the state of a Job
+type JobStatus string
+
+var (
+	JobStatusRunning   = JobStatus("RUNNING")   // The job is in progress
+	JobStatusCompleted = JobStatus("COMPLETED") // The job finished
+	JobStatusFailed    = JobStatus("FAILED")    // The job was stopped by an error
+)
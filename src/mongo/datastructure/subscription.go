@@ -0,0 +1,54 @@
+package datastructure
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Subscription is a recurring payment made by a user through a payment provider,
+// which grants the entitlements bundled with its tier for as long as it is paid for
+type Subscription struct {
+	ID     primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	UserID primitive.ObjectID `json:"user_id" bson:"user_id"`
+	// The tier the user is subscribed to
+	TierID string `json:"tier_id" bson:"tier_id"`
+	// The payment provider handling the subscription, and the ID it gave the subscription
+	Provider   string `json:"provider" bson:"provider"`
+	ProviderID string `json:"provider_id" bson:"provider_id"`
+
+	Status SubscriptionStatus `json:"status" bson:"status"`
+	// When the subscription was first paid for
+	StartedAt time.Time `json:"started_at" bson:"started_at"`
+	// When the paid period ends, at which point the subscription renews unless it was canceled
+	CurrentPeriodEnd time.Time `json:"current_period_end" bson:"current_period_end"`
+	// When the user canceled the subscription, which then ends with the current period
+	CanceledAt *time.Time `json:"canceled_at,omitempty" bson:"canceled_at,omitempty"`
+	// When the subscription stopped granting its entitlements
+	EndedAt *time.Time `json:"ended_at,omitempty" bson:"ended_at,omitempty"`
+	// The ID of the last provider event applied to the subscription
+	LastEventID string `json:"-" bson:"last_event_id"`
+	// When the provider created the newest event applied to the subscription
+	LastEventAt time.Time `json:"-" bson:"last_event_at"`
+}
+
+type SubscriptionStatus string
+
+const (
+	SubscriptionStatusActive   SubscriptionStatus = "ACTIVE"   // Paid for, and will renew
+	SubscriptionStatusCanceled SubscriptionStatus = "CANCELED" // Paid for, but will end with the current period
+	SubscriptionStatusEnded    SubscriptionStatus = "ENDED"    // No longer grants anything
+)
+
+// SubscriptionTier is a level of subscription, defined in the config, and the entitlements it bundles
+type SubscriptionTier struct {
+	ID   string `mapstructure:"id" json:"id"`
+	Name string `mapstructure:"name" json:"name"`
+	// The prices of the payment provider which subscribe to this tier
+	PriceIDs []string `mapstructure:"price_ids" json:"-"`
+
+	RoleID     string `mapstructure:"role" json:"role,omitempty"`
+	BadgeID    string `mapstructure:"badge" json:"badge,omitempty"`
+	PaintID    string `mapstructure:"paint" json:"paint,omitempty"`
+	EmoteSlots int32  `mapstructure:"emote_slots" json:"emote_slots,omitempty"`
+}
@@ -26,6 +26,7 @@ var ErrNoDocuments = mongo.ErrNoDocuments
 
 type Pipeline = mongo.Pipeline
 type WriteModel = mongo.WriteModel
+type Cursor = mongo.Cursor
 
 func NewUpdateOneModel() *mongo.UpdateOneModel {
 	return mongo.NewUpdateOneModel()
@@ -86,10 +87,43 @@ func init() {
 		logrus.WithError(err).Fatal("mongo")
 	}
 
+	_, err = Collection(CollectionNameBanAppeals).Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.M{"ban_id": 1}},
+		{Keys: bson.M{"user_id": 1}},
+		{Keys: bson.M{"status": 1}},
+	})
+	if err != nil {
+		logrus.WithError(err).Fatal("mongo")
+	}
+
+	_, err = Collection(CollectionNameJobs).Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.M{"kind": 1}},
+		{Keys: bson.M{"created_by": 1}},
+	})
+	if err != nil {
+		logrus.WithError(err).Fatal("mongo")
+	}
+
+	_, err = Collection(CollectionNameAuditArchives).Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.M{"from": 1}},
+		{Keys: bson.M{"to": 1}},
+		{Keys: bson.M{"types": 1}},
+		{Keys: bson.M{"actor_ids": 1}},
+		{Keys: bson.M{"target_ids": 1}},
+	})
+	if err != nil {
+		logrus.WithError(err).Fatal("mongo")
+	}
+
 	_, err = Collection(CollectionNameAudit).Indexes().CreateMany(ctx, []mongo.IndexModel{
 		{Keys: bson.M{"type": 1}},
 		{Keys: bson.M{"target.type": 1}},
 		{Keys: bson.M{"target.id": 1}},
+		// Compound indexes for filtered queries, sorted by creation time
+		{Keys: bson.D{{Key: "target.id", Value: 1}, {Key: "target.type", Value: 1}, {Key: "_id", Value: -1}}},
+		{Keys: bson.D{{Key: "action_user", Value: 1}, {Key: "_id", Value: -1}}},
+		{Keys: bson.D{{Key: "type", Value: 1}, {Key: "_id", Value: -1}}},
+		{Keys: bson.M{"revert_of": 1}, Options: options.Index().SetSparse(true)},
 	})
 	if err != nil {
 		logrus.WithError(err).Fatal("mongo")
@@ -99,6 +133,11 @@ func init() {
 		{Keys: bson.M{"reporter_id": 1}},
 		{Keys: bson.M{"target.type": 1}},
 		{Keys: bson.M{"target.id": 1}},
+		{Keys: bson.M{"status": 1}},
+		{Keys: bson.M{"cleared": 1}},
+		{Keys: bson.M{"assignee_id": 1}},
+		{Keys: bson.M{"category": 1}},
+		{Keys: bson.M{"last_reported_at": 1}},
 	})
 	if err != nil {
 		logrus.WithError(err).Fatal("mongo")
@@ -106,15 +145,27 @@ func init() {
 
 	_, err = Collection(CollectionNameCosmetics).Indexes().CreateMany(ctx, []mongo.IndexModel{
 		{Keys: bson.M{"name": 1}},
+		{Keys: bson.M{"user_ids": 1}},
 	})
 	if err != nil {
 		logrus.WithError(err).Fatal("mongo")
 	}
 
 	_ = Database.CreateCollection(ctx, "notifications")
+	_, err = Collection(CollectionNameNotifications).Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "pending", Value: 1}, {Key: "scheduled_at", Value: 1}}, Options: options.Index().SetSparse(true)},
+		{Keys: bson.M{"announcement": 1}},
+		{Keys: bson.M{"author_id": 1}, Options: options.Index().SetSparse(true)},
+		{Keys: bson.M{"expire_at": 1}, Options: options.Index().SetSparse(true)},
+	})
+	if err != nil {
+		logrus.WithError(err).Fatal("mongo")
+	}
+
 	_, err = Collection(CollectionNameNotificationsRead).Indexes().CreateMany(ctx, []mongo.IndexModel{
 		{Keys: bson.M{"target": 1}},
 		{Keys: bson.M{"notification": 1}},
+		{Keys: bson.D{{Key: "target", Value: 1}, {Key: "notification", Value: -1}}},
 	})
 	if err != nil {
 		logrus.WithError(err).Fatal("mongo")
@@ -123,6 +174,25 @@ func init() {
 	_, err = Collection(CollectionNameEntitlements).Indexes().CreateMany(ctx, []mongo.IndexModel{
 		{Keys: bson.M{"user_id": 1}},
 		{Keys: bson.M{"data.ref": 1}},
+		{Keys: bson.M{"starts_at": 1}, Options: options.Index().SetSparse(true)},
+		{Keys: bson.M{"ends_at": 1}, Options: options.Index().SetSparse(true)},
+		{Keys: bson.M{"subscription_id": 1}, Options: options.Index().SetSparse(true)},
+	})
+	if err != nil {
+		logrus.WithError(err).Fatal("mongo")
+	}
+
+	_, err = Collection(CollectionNameSubscriptions).Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.M{"user_id": 1}},
+		{Keys: bson.D{{Key: "provider", Value: 1}, {Key: "provider_id", Value: 1}}, Options: options.Index().SetUnique(true)},
+	})
+	if err != nil {
+		logrus.WithError(err).Fatal("mongo")
+	}
+
+	_, err = Collection(CollectionNameEmoteSets).Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.M{"owner_id": 1}},
+		{Keys: bson.M{"emotes": 1}},
 	})
 	if err != nil {
 		logrus.WithError(err).Fatal("mongo")
@@ -139,6 +209,7 @@ var (
 	CollectionNameEmotes            = CollectionName("emotes")
 	CollectionNameUsers             = CollectionName("users")
 	CollectionNameBans              = CollectionName("bans")
+	CollectionNameBanAppeals        = CollectionName("ban_appeals")
 	CollectionNameReports           = CollectionName("reports")
 	CollectionNameCosmetics         = CollectionName("cosmetics")
 	CollectionNameRoles             = CollectionName("roles")
@@ -146,6 +217,10 @@ var (
 	CollectionNameEntitlements      = CollectionName("entitlements")
 	CollectionNameNotifications     = CollectionName("notifications")
 	CollectionNameNotificationsRead = CollectionName("notifications_read")
+	CollectionNameJobs              = CollectionName("jobs")
+	CollectionNameAuditArchives     = CollectionName("audit_archives")
+	CollectionNameSubscriptions     = CollectionName("subscriptions")
+	CollectionNameEmoteSets         = CollectionName("emote_sets")
 )
 
 func HexIDSliceToObjectID(arr []string) []primitive.ObjectID {
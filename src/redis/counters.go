@@ -0,0 +1,20 @@
+package redis
+
+import (
+	"context"
+)
+
+var (
+	incrIfExistsLuaScriptSHA1 string
+)
+
+// IncrIfExists increments a counter by the given amount if it exists, returning the new value.
+// Returns -1 if the counter does not exist or would drop below zero, in which case it should be recomputed
+func IncrIfExists(ctx context.Context, key string, by int64) (int64, error) {
+	return Client.EvalSha(
+		ctx,
+		incrIfExistsLuaScriptSHA1, // scriptSHA1
+		[]string{key},             // KEYS
+		by,                        // ARGV[1]
+	).Int64()
+}
@@ -0,0 +1,17 @@
+local key = KEYS[1]
+local by = tonumber(ARGV[1])
+
+-- A missing counter is recomputed by the reader, so don't create it here
+if redis.call("EXISTS", key) == 0 then
+    return -1
+end
+
+local count = redis.call("INCRBY", key, by)
+
+-- The counter drifted, drop it so it gets recomputed
+if count < 0 then
+    redis.call("DEL", key)
+    return -1
+end
+
+return count
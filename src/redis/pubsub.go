@@ -75,6 +75,17 @@ type EventApiV1ChannelEmotes struct {
 	Emote   *EventApiV1ChannelEmotesEmote `json:"emote"`
 }
 
+type EventApiV1PersonalEmotes struct {
+	// The Twitch ID of the user the personal emote set belongs to
+	User       string                        `json:"user"`
+	SetID      string                        `json:"set_id"`
+	UnicodeTag string                        `json:"unicode_tag"`
+	EmoteID    string                        `json:"emote_id"`
+	Action     string                        `json:"action"`
+	Actor      string                        `json:"actor"`
+	Emote      *EventApiV1ChannelEmotesEmote `json:"emote"`
+}
+
 type EventApiV1ChannelEmotesEmote struct {
 	Name       string                            `json:"name"`
 	Visibility int32                             `json:"visibility"`
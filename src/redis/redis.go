@@ -129,6 +129,16 @@ func ReloadScripts() error {
 	}
 	RateLimitScriptSHA1 = v
 
+	incrIfExistsLuaScript, err := box.FindString("incr-if-exists.lua")
+	if err != nil {
+		logrus.WithError(err).Fatal("redis failed")
+	}
+	v, err = Client.ScriptLoad(ctx, incrIfExistsLuaScript).Result()
+	if err != nil {
+		logrus.WithError(err).Fatal("redis failed")
+	}
+	incrIfExistsLuaScriptSHA1 = v
+
 	return nil
 }
 
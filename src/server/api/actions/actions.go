@@ -3,11 +3,15 @@ package actions
 import (
 	"context"
 	"sync"
+	"time"
 
 	"github.com/SevenTV/ServerGo/src/mongo/datastructure"
+	jsoniter "github.com/json-iterator/go"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+var json = jsoniter.ConfigCompatibleWithStandardLibrary
+
 type emotes struct{}
 
 var Emotes emotes = emotes{}
@@ -20,6 +24,9 @@ type NotificationBuilder struct {
 	MentionedEmotes []primitive.ObjectID
 	MentionedRoles  []primitive.ObjectID
 	TargetUsers     []primitive.ObjectID
+
+	DedupKey    string        // Users who received a notification with the same key within the window are skipped
+	DedupWindow time.Duration // How long a dedup key is remembered for
 }
 
 var Notifications notifications = notifications{}
@@ -46,12 +53,52 @@ type UserBuilder struct {
 
 var Users users = users{}
 
+type audit struct{}
+
+// AuditLogFilter: Criteria for selecting audit log entries. Nil fields are not filtered on
+type AuditLogFilter struct {
+	Types      []int32
+	ActorID    *primitive.ObjectID
+	TargetID   *primitive.ObjectID
+	TargetType *string
+	After      *time.Time
+	Before     *time.Time
+	// Only match entries older than the entry with this ID, to paginate from newest to oldest
+	Cursor *primitive.ObjectID
+}
+
+var Audit audit = audit{}
+
+type reports struct{}
+
+var Reports reports = reports{}
+
+type cosmetics struct{}
+
+var Cosmetics cosmetics = cosmetics{}
+
+type jobs struct{}
+
+type JobTracker struct {
+	Job datastructure.Job
+}
+
+var Jobs jobs = jobs{}
+
+type subscriptions struct{}
+
+var Subscriptions subscriptions = subscriptions{}
+
+type emoteSets struct{}
+
+var EmoteSets emoteSets = emoteSets{}
+
 type bans struct {
-	BannedUsers map[primitive.ObjectID]*datastructure.Ban
+	BannedUsers map[primitive.ObjectID]map[datastructure.BanType]*datastructure.Ban
 	Mtx         *sync.Mutex
 }
 
 var Bans *bans = &bans{
-	BannedUsers: map[primitive.ObjectID]*datastructure.Ban{},
+	BannedUsers: map[primitive.ObjectID]map[datastructure.BanType]*datastructure.Ban{},
 	Mtx:         &sync.Mutex{},
 }
@@ -0,0 +1,150 @@
+package actions
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/SevenTV/ServerGo/src/aws"
+	"github.com/SevenTV/ServerGo/src/configure"
+	"github.com/SevenTV/ServerGo/src/mongo"
+	"github.com/SevenTV/ServerGo/src/mongo/datastructure"
+	"github.com/SevenTV/ServerGo/src/utils"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Archive: Move the oldest audit log entries created before the given id into object storage,
+// leaving a summary of the archive in the database
+//
+// At most limit entries are archived. Returns nil if there was nothing to archive
+func (*audit) Archive(ctx context.Context, before primitive.ObjectID, limit int64) (*datastructure.AuditArchive, error) {
+	logs := []*datastructure.AuditLog{}
+	cur, err := mongo.Collection(mongo.CollectionNameAudit).Find(ctx, bson.M{
+		"_id": bson.M{"$lt": before},
+	}, options.Find().SetSort(bson.M{"_id": 1}).SetLimit(limit))
+	if err == nil {
+		err = cur.All(ctx, &logs)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(logs) == 0 {
+		return nil, nil
+	}
+
+	first := logs[0]
+	last := logs[len(logs)-1]
+	archive := &datastructure.AuditArchive{
+		Key:         fmt.Sprintf("audit/%s-%s.ndjson.gz", first.ID.Hex(), last.ID.Hex()),
+		FirstID:     first.ID,
+		LastID:      last.ID,
+		From:        first.ID.Timestamp(),
+		To:          last.ID.Timestamp(),
+		Count:       int32(len(logs)),
+		Types:       []int32{},
+		ActorIDs:    []primitive.ObjectID{},
+		TargetIDs:   []primitive.ObjectID{},
+		TargetTypes: []string{},
+	}
+
+	// Write the entries as extended json, so that the bson types survive the trip
+	buf := bytes.Buffer{}
+	zw := gzip.NewWriter(&buf)
+	ids := make([]primitive.ObjectID, len(logs))
+	types := map[int32]bool{}
+	actors := map[primitive.ObjectID]bool{}
+	targets := map[primitive.ObjectID]bool{}
+	targetTypes := map[string]bool{}
+	for i, l := range logs {
+		b, err := bson.MarshalExtJSON(l, true, false)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := zw.Write(append(b, '\n')); err != nil {
+			return nil, err
+		}
+
+		ids[i] = l.ID
+		if !types[l.Type] {
+			types[l.Type] = true
+			archive.Types = append(archive.Types, l.Type)
+		}
+		if !actors[l.CreatedBy] {
+			actors[l.CreatedBy] = true
+			archive.ActorIDs = append(archive.ActorIDs, l.CreatedBy)
+		}
+		if l.Target != nil {
+			if l.Target.ID != nil && !targets[*l.Target.ID] {
+				targets[*l.Target.ID] = true
+				archive.TargetIDs = append(archive.TargetIDs, *l.Target.ID)
+			}
+			if !targetTypes[l.Target.Type] {
+				targetTypes[l.Target.Type] = true
+				archive.TargetTypes = append(archive.TargetTypes, l.Target.Type)
+			}
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	archive.Size = int64(buf.Len())
+
+	// Upload the archive before removing anything from the database
+	if err := aws.UploadPrivateFile(configure.Config.GetString("aws_audit_bucket"), archive.Key, buf.Bytes(), utils.StringPointer("application/gzip")); err != nil {
+		return nil, err
+	}
+
+	res, err := mongo.Collection(mongo.CollectionNameAuditArchives).InsertOne(ctx, archive)
+	if err != nil {
+		return nil, err
+	}
+	archive.ID, _ = res.InsertedID.(primitive.ObjectID)
+
+	if _, err := mongo.Collection(mongo.CollectionNameAudit).DeleteMany(ctx, bson.M{
+		"_id": bson.M{"$in": ids},
+	}); err != nil {
+		return nil, err
+	}
+
+	return archive, nil
+}
+
+// Rehydrate: Download an archive and read back the audit log entries matching a filter
+func (*audit) Rehydrate(ctx context.Context, archive *datastructure.AuditArchive, filter *AuditLogFilter) ([]*datastructure.AuditLog, error) {
+	b, err := aws.GetFile(configure.Config.GetString("aws_audit_bucket"), archive.Key)
+	if err != nil {
+		return nil, err
+	}
+
+	zr, err := gzip.NewReader(bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+
+	data, err := io.ReadAll(zr)
+	if err != nil {
+		return nil, err
+	}
+
+	logs := []*datastructure.AuditLog{}
+	for _, line := range bytes.Split(data, []byte{'\n'}) {
+		if len(line) == 0 {
+			continue
+		}
+
+		l := &datastructure.AuditLog{}
+		if err := bson.UnmarshalExtJSON(line, true, l); err != nil {
+			return nil, err
+		}
+		if filter == nil || filter.Match(l) {
+			logs = append(logs, l)
+		}
+	}
+
+	return logs, nil
+}
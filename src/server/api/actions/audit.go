@@ -0,0 +1,84 @@
+package actions
+
+import (
+	"time"
+
+	"github.com/SevenTV/ServerGo/src/mongo/datastructure"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Query: Create the mongo query matching audit log entries with a filter
+//
+// The time range is derived from the creation time embedded in the object ids
+func (*audit) Query(filter *AuditLogFilter) bson.M {
+	query := bson.M{}
+	if len(filter.Types) > 0 {
+		query["type"] = bson.M{"$in": filter.Types}
+	}
+	if filter.ActorID != nil {
+		query["action_user"] = *filter.ActorID
+	}
+	if filter.TargetID != nil {
+		query["target.id"] = *filter.TargetID
+	}
+	if filter.TargetType != nil {
+		query["target.type"] = *filter.TargetType
+	}
+
+	idRange := bson.M{}
+	if filter.After != nil {
+		idRange["$gte"] = primitive.NewObjectIDFromTimestamp(*filter.After)
+	}
+	if filter.Before != nil {
+		idRange["$lt"] = primitive.NewObjectIDFromTimestamp(*filter.Before)
+	}
+	if filter.Cursor != nil {
+		if lt, ok := idRange["$lt"].(primitive.ObjectID); !ok || filter.Cursor.Hex() < lt.Hex() {
+			idRange["$lt"] = *filter.Cursor
+		}
+	}
+	if len(idRange) > 0 {
+		query["_id"] = idRange
+	}
+
+	return query
+}
+
+// Match: Whether or not an audit log entry matches a filter
+func (f *AuditLogFilter) Match(l *datastructure.AuditLog) bool {
+	if len(f.Types) > 0 {
+		found := false
+		for _, t := range f.Types {
+			if t == l.Type {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if f.ActorID != nil && l.CreatedBy != *f.ActorID {
+		return false
+	}
+	if f.TargetID != nil && (l.Target == nil || l.Target.ID == nil || *l.Target.ID != *f.TargetID) {
+		return false
+	}
+	if f.TargetType != nil && (l.Target == nil || l.Target.Type != *f.TargetType) {
+		return false
+	}
+
+	created := l.ID.Timestamp()
+	if f.After != nil && created.Before(f.After.Truncate(time.Second)) {
+		return false
+	}
+	if f.Before != nil && !created.Before(f.Before.Truncate(time.Second)) {
+		return false
+	}
+	if f.Cursor != nil && l.ID.Hex() >= f.Cursor.Hex() {
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,158 @@
+package actions
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/SevenTV/ServerGo/src/discord"
+	"github.com/SevenTV/ServerGo/src/mongo"
+	"github.com/SevenTV/ServerGo/src/mongo/datastructure"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// A string representing what happens to the emotes of a banned user
+type BanCascadeMode string
+
+var (
+	BanCascadeModeUnlist = BanCascadeMode("UNLIST") // Emotes are unlisted but stay in channels
+	BanCascadeModeDelete = BanCascadeMode("DELETE") // Emotes are deleted and removed from all channels
+)
+
+type BanCascadeOptions struct {
+	Victim primitive.ObjectID
+	Actor  primitive.ObjectID
+	Mode   BanCascadeMode
+	Reason *string
+}
+
+// Cascade: Clean up after a banned user in the background, handling all the emotes they own
+// and removing them from the editors of every channel. The returned job can be used to track progress
+func (b *bans) Cascade(ctx context.Context, opts BanCascadeOptions) (*JobTracker, error) {
+	job, err := Jobs.Start(ctx, datastructure.JobKindBanCascade, opts.Actor, &datastructure.Target{ID: &opts.Victim, Type: "users"})
+	if err != nil {
+		return nil, err
+	}
+
+	go func() {
+		ctx := context.Background()
+		emotesAffected, editorsRemoved, err := b.cascade(ctx, job, opts)
+		job.Finish(ctx, err)
+		if err != nil {
+			logrus.WithError(err).WithField("job", job.Job.ID).Error("ban cascade")
+		}
+
+		// Record the outcome
+		if _, err := mongo.Collection(mongo.CollectionNameAudit).InsertOne(ctx, &datastructure.AuditLog{
+			Type:      datastructure.AuditLogTypeUserBanCascade,
+			CreatedBy: opts.Actor,
+			Target:    &datastructure.Target{ID: &opts.Victim, Type: "users"},
+			Changes: []*datastructure.AuditLogChange{
+				{Key: "job_id", OldValue: nil, NewValue: job.Job.ID},
+				{Key: "status", OldValue: nil, NewValue: job.Job.Status},
+				{Key: "mode", OldValue: nil, NewValue: opts.Mode},
+				{Key: "emotes", OldValue: nil, NewValue: emotesAffected},
+				{Key: "emotes_failed", OldValue: nil, NewValue: job.Job.Failed},
+				{Key: "editor_of", OldValue: nil, NewValue: editorsRemoved},
+			},
+			Reason: opts.Reason,
+		}); err != nil {
+			logrus.WithError(err).Error("mongo")
+		}
+
+		b.notifyCascade(ctx, opts, len(emotesAffected), int(job.Job.Failed))
+	}()
+
+	return job, nil
+}
+
+// notifyCascade: Log the outcome of a ban cascade to Discord and let the banned user know about their unlisted emotes
+func (*bans) notifyCascade(ctx context.Context, opts BanCascadeOptions, emotes int, failed int) {
+	actorUB, err := Users.GetByID(ctx, opts.Actor)
+	if err != nil {
+		logrus.WithError(err).Error("ban cascade, failed to get actor")
+		return
+	}
+	victimUB, err := Users.GetByID(ctx, opts.Victim)
+	if err != nil {
+		logrus.WithError(err).Error("ban cascade, failed to get victim")
+		return
+	}
+
+	go discord.SendBanCascade(victimUB.User, actorUB.User, string(opts.Mode), emotes, failed, opts.Reason)
+
+	if opts.Mode != BanCascadeModeUnlist || emotes == 0 {
+		return
+	}
+	if err := Notifications.Create().
+		SetTitle("Emotes Unlisted").
+		SetCategory(datastructure.NotificationCategoryEmote).
+		AddTargetUsers(opts.Victim).
+		AddTextMessagePart(fmt.Sprintf("%d of your emotes were unlisted by ", emotes)).
+		AddUserMentionPart(opts.Actor).
+		AddTextMessagePart(" following your ban. They can still be added to channels, but will not appear in public listings.").
+		Write(ctx); err != nil {
+		logrus.WithError(err).Error("failed to create notification")
+	}
+}
+
+func (*bans) cascade(ctx context.Context, job *JobTracker, opts BanCascadeOptions) ([]primitive.ObjectID, int64, error) {
+	emotes := []*datastructure.Emote{}
+	cur, err := mongo.Collection(mongo.CollectionNameEmotes).Find(ctx, bson.M{
+		"owner": opts.Victim,
+		"status": bson.M{
+			"$ne": datastructure.EmoteStatusDeleted,
+		},
+	})
+	if err == nil {
+		err = cur.All(ctx, &emotes)
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+
+	job.SetTotal(ctx, int32(len(emotes)))
+
+	affected := []primitive.ObjectID{}
+	for _, emote := range emotes {
+		var err error
+		switch opts.Mode {
+		case BanCascadeModeDelete:
+			err = Emotes.Delete(ctx, emote)
+		case BanCascadeModeUnlist:
+			visibility := emote.Visibility | datastructure.EmoteVisibilityUnlisted
+			if visibility == emote.Visibility {
+				break
+			}
+			// The owner is notified once about the whole cascade rather than about each emote
+			err = Emotes.apply(ctx, emote, bson.M{"visibility": visibility}, []*datastructure.AuditLogChange{
+				{Key: "visibility", OldValue: emote.Visibility, NewValue: visibility},
+			}, opts.Actor, opts.Reason)
+		default:
+			return affected, 0, fmt.Errorf("unknown cascade mode %v", opts.Mode)
+		}
+		if err != nil {
+			logrus.WithError(err).WithField("emote", emote.ID).Error("ban cascade")
+			job.Progress(ctx, 1, 1)
+			continue
+		}
+
+		affected = append(affected, emote.ID)
+		job.Progress(ctx, 1, 0)
+	}
+
+	// Strip the victim from editors lists
+	res, err := mongo.Collection(mongo.CollectionNameUsers).UpdateMany(ctx, bson.M{
+		"editors": opts.Victim,
+	}, bson.M{
+		"$pull": bson.M{
+			"editors": opts.Victim,
+		},
+	})
+	if err != nil {
+		return affected, 0, err
+	}
+
+	return affected, res.ModifiedCount, nil
+}
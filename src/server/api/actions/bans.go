@@ -6,15 +6,26 @@ import (
 
 	"github.com/SevenTV/ServerGo/src/mongo"
 	"github.com/SevenTV/ServerGo/src/mongo/datastructure"
+	"github.com/SevenTV/ServerGo/src/redis"
+	"github.com/sirupsen/logrus"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+// The redis channel on which ban state changes are broadcasted to all pods
+const banEventsChannel = "events:bans"
+
+type banEvent struct {
+	Removed bool                    `json:"removed"`
+	UserID  primitive.ObjectID      `json:"user_id"`
+	Types   []datastructure.BanType `json:"types"`
+	Ban     *datastructure.Ban      `json:"ban"`
+}
+
 // FetchBans gets the current active bans stored in database and store them in memory
+//
+// The in-memory list is replaced entirely, so this can be called again to resync with the database
 func (b *bans) FetchBans(ctx context.Context) error {
-	b.Mtx.Lock()
-	defer b.Mtx.Unlock()
-
 	banList := []*datastructure.Ban{}
 	cur, err := mongo.Collection(mongo.CollectionNameBans).Find(ctx, bson.M{
 		"$or": bson.A{
@@ -30,27 +41,156 @@ func (b *bans) FetchBans(ctx context.Context) error {
 		return err
 	}
 
+	bannedUsers := make(map[primitive.ObjectID]map[datastructure.BanType]*datastructure.Ban, len(banList))
 	for _, ban := range banList {
-		userID := ban.UserID
+		if ban.UserID == nil {
+			continue
+		}
 
-		b.BannedUsers[*userID] = ban
+		if bannedUsers[*ban.UserID] == nil {
+			bannedUsers[*ban.UserID] = map[datastructure.BanType]*datastructure.Ban{}
+		}
+		bannedUsers[*ban.UserID][ban.GetType()] = ban
 	}
 
+	b.Mtx.Lock()
+	b.BannedUsers = bannedUsers
+	b.Mtx.Unlock()
+
 	return nil
 }
 
+// Add a ban to the in-memory list and broadcast it to the other pods
+func (b *bans) Add(ctx context.Context, ban *datastructure.Ban) {
+	if ban.UserID == nil {
+		return
+	}
+
+	b.Mtx.Lock()
+	b.set(ban)
+	b.Mtx.Unlock()
+
+	if err := redis.Publish(ctx, banEventsChannel, &banEvent{
+		UserID: *ban.UserID,
+		Ban:    ban,
+	}); err != nil {
+		logrus.WithError(err).Error("redis, failed to publish ban")
+	}
+}
+
+// Remove a user's restrictions of the given types from the in-memory list and broadcast the removal to the other pods
+//
+// All restrictions are lifted if no type is specified
+func (b *bans) Remove(ctx context.Context, userID primitive.ObjectID, types ...datastructure.BanType) {
+	b.Mtx.Lock()
+	b.unset(userID, types)
+	b.Mtx.Unlock()
+
+	if err := redis.Publish(ctx, banEventsChannel, &banEvent{
+		Removed: true,
+		UserID:  userID,
+		Types:   types,
+	}); err != nil {
+		logrus.WithError(err).Error("redis, failed to publish unban")
+	}
+}
+
+// Listen for ban events sent by other pods and apply them to the in-memory list, until the context is canceled
+func (b *bans) Listen(ctx context.Context) {
+	ch := make(chan []byte)
+	redis.Subscribe(ctx, ch, banEventsChannel)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case data := <-ch:
+			ev := &banEvent{}
+			if err := json.Unmarshal(data, ev); err != nil {
+				logrus.WithError(err).Error("bans, invalid event")
+				continue
+			}
+
+			b.Mtx.Lock()
+			if ev.Removed || ev.Ban == nil {
+				b.unset(ev.UserID, ev.Types)
+			} else {
+				b.set(ev.Ban)
+			}
+			b.Mtx.Unlock()
+		}
+	}
+}
+
+// IsUserBanned returns whether or not the user is under an active full ban
 func (b *bans) IsUserBanned(id primitive.ObjectID) (bool, string) {
+	return b.IsUserRestricted(id, datastructure.BanTypeFull)
+}
+
+// IsUserRestricted returns whether or not the user is under an active restriction of the given type
+//
+// A full ban implies every other restriction
+func (b *bans) IsUserRestricted(id primitive.ObjectID, banType datastructure.BanType) (bool, string) {
+	b.Mtx.Lock()
+	defer b.Mtx.Unlock()
+
+	for _, t := range []datastructure.BanType{datastructure.BanTypeFull, banType} {
+		if ban := b.get(id, t); ban != nil {
+			return true, ban.Reason
+		}
+	}
+
+	return false, ""
+}
+
+// GetRestrictions returns the active restrictions of a user
+func (b *bans) GetRestrictions(id primitive.ObjectID) []*datastructure.Ban {
 	b.Mtx.Lock()
 	defer b.Mtx.Unlock()
 
-	ban, ok := b.BannedUsers[id]
+	result := []*datastructure.Ban{}
+	for _, t := range datastructure.BanTypes {
+		if ban := b.get(id, t); ban != nil {
+			result = append(result, ban)
+		}
+	}
+
+	return result
+}
+
+// get an active ban, dropping it from memory if it has expired. Mtx must be held
+func (b *bans) get(id primitive.ObjectID, banType datastructure.BanType) *datastructure.Ban {
+	ban, ok := b.BannedUsers[id][banType]
 	if !ok {
-		return false, ""
+		return nil
+	}
+	if !ban.IsActive() {
+		b.unset(id, []datastructure.BanType{banType})
+		return nil
+	}
+
+	return ban
+}
+
+// set a ban in memory. Mtx must be held
+func (b *bans) set(ban *datastructure.Ban) {
+	if b.BannedUsers[*ban.UserID] == nil {
+		b.BannedUsers[*ban.UserID] = map[datastructure.BanType]*datastructure.Ban{}
 	}
-	if time.Now().After(ban.ExpireAt) {
+	b.BannedUsers[*ban.UserID][ban.GetType()] = ban
+}
+
+// unset a user's bans of the given types in memory, or all of them if none are specified. Mtx must be held
+func (b *bans) unset(id primitive.ObjectID, types []datastructure.BanType) {
+	if len(types) == 0 {
 		delete(b.BannedUsers, id)
-		return false, ""
+		return
 	}
 
-	return true, ban.Reason
+	for _, t := range types {
+		delete(b.BannedUsers[id], t)
+	}
+	if len(b.BannedUsers[id]) == 0 {
+		delete(b.BannedUsers, id)
+	}
 }
@@ -0,0 +1,311 @@
+package actions
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/SevenTV/ServerGo/src/mongo"
+	"github.com/SevenTV/ServerGo/src/mongo/datastructure"
+	"github.com/SevenTV/ServerGo/src/redis"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	cosmeticsChangedChannel     = "events:cosmetics:changed"
+	cosmeticsSnapshotVersionKey = "cosmetics:snapshot:version"
+	cosmeticsSnapshotTTL        = 7 * 24 * time.Hour
+	// The amount of versions for which the changed users are kept, older versions can only get the full snapshot
+	cosmeticsSnapshotChangesKept = 500
+)
+
+// CosmeticsSnapshot is the badge and paint displayed by every user who has any, at a version
+type CosmeticsSnapshot struct {
+	Version   int64                     `bson:"version"`
+	CreatedAt time.Time                 `bson:"created_at"`
+	Cosmetics []*datastructure.Cosmetic `bson:"cosmetics"` // Sorted by priority, highest first
+	Users     []*CosmeticsSnapshotUser  `bson:"users"`
+}
+
+// CosmeticsSnapshotUser is a user and the cosmetics they display in a snapshot
+type CosmeticsSnapshotUser struct {
+	ID       primitive.ObjectID  `bson:"_id"`
+	TwitchID string              `bson:"id"`
+	Login    string              `bson:"login"`
+	BadgeID  *primitive.ObjectID `bson:"badge_id"`
+	PaintID  *primitive.ObjectID `bson:"paint_id"`
+}
+
+// User: Get the user as a partial user document, with only their identifiers
+func (u *CosmeticsSnapshotUser) User() *datastructure.User {
+	return &datastructure.User{ID: u.ID, TwitchID: u.TwitchID, Login: u.Login}
+}
+
+type cosmeticsSnapshotChanges struct {
+	Users []*CosmeticsSnapshotUser `bson:"users"`
+}
+
+func cosmeticsSnapshotKey(version int64) string {
+	return fmt.Sprintf("cosmetics:snapshot:%d", version)
+}
+
+func cosmeticsSnapshotChangesKey(version int64) string {
+	return fmt.Sprintf("cosmetics:snapshot:changes:%d", version)
+}
+
+// NotifyChanged: Let the snapshot task know that what users display may have changed
+func (*cosmetics) NotifyChanged(ctx context.Context) {
+	if err := redis.Publish(ctx, cosmeticsChangedChannel, struct{}{}); err != nil {
+		logrus.WithError(err).Error("redis, failed to publish cosmetics change")
+	}
+}
+
+// SubscribeChanges: Receive a message whenever cosmetics or entitlements change, until the context is cancelled
+func (*cosmetics) SubscribeChanges(ctx context.Context, ch chan []byte) {
+	redis.Subscribe(ctx, ch, cosmeticsChangedChannel)
+}
+
+// GetSnapshot: Get the current cosmetics snapshot, or nil if none was made yet
+func (*cosmetics) GetSnapshot(ctx context.Context) (*CosmeticsSnapshot, error) {
+	version, err := redis.Client.Get(ctx, cosmeticsSnapshotVersionKey).Int64()
+	if err == redis.ErrNil {
+		return nil, nil
+	}
+	if err != nil {
+		logrus.WithError(err).Error("redis")
+		return nil, err
+	}
+
+	b, err := redis.Client.Get(ctx, cosmeticsSnapshotKey(version)).Bytes()
+	if err == redis.ErrNil {
+		return nil, nil
+	}
+	if err != nil {
+		logrus.WithError(err).Error("redis")
+		return nil, err
+	}
+
+	snapshot := &CosmeticsSnapshot{}
+	if err := bson.Unmarshal(b, snapshot); err != nil {
+		logrus.WithError(err).Error("bson")
+		return nil, err
+	}
+	return snapshot, nil
+}
+
+// GetSnapshotChanges: Get the users whose badge or paint changed after the given version, up to the given snapshot.
+// Returns false if the changes are no longer known, in which case the full snapshot should be used
+func (*cosmetics) GetSnapshotChanges(ctx context.Context, since int64, snapshot *CosmeticsSnapshot) ([]*CosmeticsSnapshotUser, bool, error) {
+	if since > snapshot.Version || since < snapshot.Version-cosmeticsSnapshotChangesKept {
+		return nil, false, nil
+	}
+	if since == snapshot.Version {
+		return []*CosmeticsSnapshotUser{}, true, nil
+	}
+
+	keys := []string{}
+	for v := since + 1; v <= snapshot.Version; v++ {
+		keys = append(keys, cosmeticsSnapshotChangesKey(v))
+	}
+	values, err := redis.Client.MGet(ctx, keys...).Result()
+	if err != nil {
+		logrus.WithError(err).Error("redis")
+		return nil, false, err
+	}
+
+	// Later versions hold the user's most recent identifiers
+	byID := make(map[primitive.ObjectID]*CosmeticsSnapshotUser)
+	for _, v := range values {
+		s, ok := v.(string)
+		if !ok {
+			return nil, false, nil
+		}
+
+		changes := cosmeticsSnapshotChanges{}
+		if err := bson.Unmarshal([]byte(s), &changes); err != nil {
+			logrus.WithError(err).Error("bson")
+			return nil, false, err
+		}
+		for _, u := range changes.Users {
+			byID[u.ID] = u
+		}
+	}
+
+	result := make([]*CosmeticsSnapshotUser, 0, len(byID))
+	for _, u := range byID {
+		result = append(result, u)
+	}
+	return result, true, nil
+}
+
+// RefreshSnapshot: Recompute what every user displays, writing a new snapshot version if anything changed.
+// This must only be run by one instance at a time
+func (*cosmetics) RefreshSnapshot(ctx context.Context) (int64, error) {
+	computed, err := Cosmetics.computeUserCosmetics(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	userIDs := make([]primitive.ObjectID, 0, len(computed))
+	cosmeticIDs := []primitive.ObjectID{}
+	seenCosmetics := make(map[primitive.ObjectID]bool)
+	for id, uc := range computed {
+		userIDs = append(userIDs, id)
+		for _, ref := range []*primitive.ObjectID{uc.BadgeID, uc.PaintID} {
+			if ref != nil && !seenCosmetics[*ref] {
+				cosmeticIDs = append(cosmeticIDs, *ref)
+				seenCosmetics[*ref] = true
+			}
+		}
+	}
+
+	users := []*CosmeticsSnapshotUser{}
+	cur, err := mongo.Collection(mongo.CollectionNameUsers).Find(ctx, bson.M{
+		"_id": bson.M{"$in": userIDs},
+	}, options.Find().SetProjection(bson.M{"_id": 1, "id": 1, "login": 1}))
+	if err != nil {
+		logrus.WithError(err).Error("mongo")
+		return 0, err
+	}
+	if err = cur.All(ctx, &users); err != nil {
+		logrus.WithError(err).Error("mongo")
+		return 0, err
+	}
+	for _, u := range users {
+		uc := computed[u.ID]
+		u.BadgeID = uc.BadgeID
+		u.PaintID = uc.PaintID
+	}
+	sort.Slice(users, func(i, j int) bool {
+		return bytes.Compare(users[i].ID[:], users[j].ID[:]) < 0
+	})
+
+	cosmetics := []*datastructure.Cosmetic{}
+	cur, err = mongo.Collection(mongo.CollectionNameCosmetics).Find(ctx, bson.M{
+		"_id": bson.M{"$in": cosmeticIDs},
+	}, options.Find().SetProjection(bson.M{"user_ids": 0}))
+	if err != nil {
+		logrus.WithError(err).Error("mongo")
+		return 0, err
+	}
+	if err = cur.All(ctx, &cosmetics); err != nil {
+		logrus.WithError(err).Error("mongo")
+		return 0, err
+	}
+	sort.Slice(cosmetics, func(i, j int) bool {
+		if cosmetics[i].Priority != cosmetics[j].Priority {
+			return cosmetics[i].Priority > cosmetics[j].Priority
+		}
+		return bytes.Compare(cosmetics[i].ID[:], cosmetics[j].ID[:]) < 0
+	})
+
+	snapshot := &CosmeticsSnapshot{
+		CreatedAt: time.Now(),
+		Cosmetics: cosmetics,
+		Users:     users,
+	}
+	previous, err := Cosmetics.GetSnapshot(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	// Without a previous snapshot the changes are unknown, so clients on older versions will get the full snapshot
+	changes := cosmeticsSnapshotChanges{}
+	if previous != nil {
+		changes.Users = diffCosmeticsSnapshots(previous, snapshot)
+		if len(changes.Users) == 0 {
+			// Nothing changed, keep the current version around
+			redis.Client.Expire(ctx, cosmeticsSnapshotKey(previous.Version), cosmeticsSnapshotTTL)
+			return previous.Version, nil
+		}
+		snapshot.Version = previous.Version + 1
+	} else {
+		// Continue from the last known version, so that clients don't mistake a new snapshot for an old one
+		snapshot.Version, err = redis.Client.Get(ctx, cosmeticsSnapshotVersionKey).Int64()
+		if err != nil && err != redis.ErrNil {
+			logrus.WithError(err).Error("redis")
+			return 0, err
+		}
+		snapshot.Version++
+	}
+
+	b, err := bson.Marshal(snapshot)
+	if err != nil {
+		logrus.WithError(err).Error("bson")
+		return 0, err
+	}
+	cb, err := bson.Marshal(changes)
+	if err != nil {
+		logrus.WithError(err).Error("bson")
+		return 0, err
+	}
+
+	// Write the new version before pointing to it. The previous one is kept shortly for requests still reading it
+	pipe := redis.Client.TxPipeline()
+	pipe.Set(ctx, cosmeticsSnapshotKey(snapshot.Version), b, cosmeticsSnapshotTTL)
+	pipe.Set(ctx, cosmeticsSnapshotVersionKey, snapshot.Version, 0)
+	if previous != nil {
+		pipe.Set(ctx, cosmeticsSnapshotChangesKey(snapshot.Version), cb, cosmeticsSnapshotTTL)
+		pipe.Expire(ctx, cosmeticsSnapshotKey(previous.Version), time.Minute)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		logrus.WithError(err).Error("redis")
+		return 0, err
+	}
+
+	return snapshot.Version, nil
+}
+
+// diffCosmeticsSnapshots: Find the users whose badge or paint differ between two snapshots,
+// including those who display a cosmetic that was edited
+func diffCosmeticsSnapshots(a, b *CosmeticsSnapshot) []*CosmeticsSnapshotUser {
+	oldCosmetics := make(map[primitive.ObjectID]*datastructure.Cosmetic, len(a.Cosmetics))
+	for _, cos := range a.Cosmetics {
+		oldCosmetics[cos.ID] = cos
+	}
+	editedCosmetics := make(map[primitive.ObjectID]bool)
+	for _, cos := range b.Cosmetics {
+		old, ok := oldCosmetics[cos.ID]
+		if !ok || old.Name != cos.Name || !bytes.Equal(old.Data, cos.Data) {
+			editedCosmetics[cos.ID] = true
+		}
+	}
+
+	sameRef := func(x, y *primitive.ObjectID) bool {
+		if x == nil || y == nil {
+			return x == y
+		}
+		return *x == *y
+	}
+	edited := func(ref *primitive.ObjectID) bool {
+		return ref != nil && editedCosmetics[*ref]
+	}
+
+	oldUsers := make(map[primitive.ObjectID]*CosmeticsSnapshotUser, len(a.Users))
+	for _, u := range a.Users {
+		oldUsers[u.ID] = u
+	}
+
+	changed := []*CosmeticsSnapshotUser{}
+	for _, u := range b.Users {
+		old, ok := oldUsers[u.ID]
+		delete(oldUsers, u.ID)
+		if ok && sameRef(old.BadgeID, u.BadgeID) && sameRef(old.PaintID, u.PaintID) &&
+			old.TwitchID == u.TwitchID && old.Login == u.Login &&
+			!edited(u.BadgeID) && !edited(u.PaintID) {
+			continue
+		}
+		changed = append(changed, u)
+	}
+
+	// Users remaining no longer display anything
+	for _, u := range oldUsers {
+		changed = append(changed, &CosmeticsSnapshotUser{ID: u.ID, TwitchID: u.TwitchID, Login: u.Login})
+	}
+	return changed
+}
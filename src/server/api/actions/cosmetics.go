@@ -0,0 +1,260 @@
+package actions
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/SevenTV/ServerGo/src/mongo"
+	"github.com/SevenTV/ServerGo/src/mongo/datastructure"
+	"github.com/SevenTV/ServerGo/src/redis"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	// Bumped whenever cosmetics are reprioritized or deleted, which may change what any user displays
+	cosmeticsGenerationKey = "cosmetics:generation"
+	userCosmeticsTTL       = 10 * time.Minute
+)
+
+// UserCosmetics are the badge and paint displayed by a user
+type UserCosmetics struct {
+	BadgeID *primitive.ObjectID `json:"badge_id,omitempty"`
+	PaintID *primitive.ObjectID `json:"paint_id,omitempty"`
+}
+
+// userCosmeticsKey: Get the key of a user's cached cosmetics, which is tied to the current cosmetics
+func userCosmeticsKey(gen int64, userID primitive.ObjectID) string {
+	return fmt.Sprintf("cosmetics:user:%d:%s", gen, userID.Hex())
+}
+
+// GetUserCosmetics: Get the badge and paint displayed by each of the users, computing those that aren't cached
+func (*cosmetics) GetUserCosmetics(ctx context.Context, userIDs []primitive.ObjectID) (map[primitive.ObjectID]UserCosmetics, error) {
+	result := make(map[primitive.ObjectID]UserCosmetics, len(userIDs))
+	if len(userIDs) == 0 {
+		return result, nil
+	}
+
+	gen, err := redis.Client.Get(ctx, cosmeticsGenerationKey).Int64()
+	if err != nil && err != redis.ErrNil {
+		logrus.WithError(err).Error("redis")
+		return nil, err
+	}
+
+	keys := make([]string, len(userIDs))
+	for i, id := range userIDs {
+		keys[i] = userCosmeticsKey(gen, id)
+	}
+	cached, err := redis.Client.MGet(ctx, keys...).Result()
+	if err != nil {
+		logrus.WithError(err).Error("redis")
+		return nil, err
+	}
+
+	missing := []primitive.ObjectID{}
+	for i, v := range cached {
+		s, ok := v.(string)
+		if !ok {
+			missing = append(missing, userIDs[i])
+			continue
+		}
+
+		uc := UserCosmetics{}
+		if err := json.UnmarshalFromString(s, &uc); err != nil {
+			missing = append(missing, userIDs[i])
+			continue
+		}
+		result[userIDs[i]] = uc
+	}
+	if len(missing) == 0 {
+		return result, nil
+	}
+
+	computed, err := Cosmetics.computeUserCosmetics(ctx, missing)
+	if err != nil {
+		return nil, err
+	}
+
+	// Users without cosmetics are cached too, as they are the majority
+	pipe := redis.Client.Pipeline()
+	for _, id := range missing {
+		uc := computed[id]
+		result[id] = uc
+
+		b, _ := json.Marshal(uc)
+		pipe.Set(ctx, userCosmeticsKey(gen, id), b, userCosmeticsTTL)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		logrus.WithError(err).Error("redis")
+	}
+
+	return result, nil
+}
+
+// computeUserCosmetics: Find the highest priority badge and paint of each user, among those directly assigned to them
+// and those they are entitled to and selected. Entitlements bound to a role only count if the user is entitled to the role.
+// If no users are given, the cosmetics of every user with any are computed instead
+func (*cosmetics) computeUserCosmetics(ctx context.Context, userIDs []primitive.ObjectID) (map[primitive.ObjectID]UserCosmetics, error) {
+	everyone := userIDs == nil
+	entitlementFilter := Entitlements.ActiveFilter(bson.M{
+		"kind": bson.M{"$in": bson.A{datastructure.EntitlementKindBadge, datastructure.EntitlementKindPaint, datastructure.EntitlementKindRole}},
+	})
+	if !everyone {
+		entitlementFilter["user_id"] = bson.M{"$in": userIDs}
+	}
+	cur, err := mongo.Collection(mongo.CollectionNameEntitlements).Find(ctx, entitlementFilter)
+	if err != nil {
+		logrus.WithError(err).Error("mongo")
+		return nil, err
+	}
+	entitlements := []*datastructure.Entitlement{}
+	if err := cur.All(ctx, &entitlements); err != nil {
+		logrus.WithError(err).Error("mongo")
+		return nil, err
+	}
+
+	roles := make(map[primitive.ObjectID][]primitive.ObjectID)
+	for _, e := range entitlements {
+		if e.Kind == datastructure.EntitlementKindRole {
+			roles[e.UserID] = append(roles[e.UserID], Entitlements.With(ctx, *e).ReadRoleData().ObjectReference)
+		}
+	}
+
+	candidates := make(map[primitive.ObjectID][]primitive.ObjectID)
+	refs := []primitive.ObjectID{}
+	for _, e := range entitlements {
+		if e.Kind != datastructure.EntitlementKindBadge && e.Kind != datastructure.EntitlementKindPaint {
+			continue
+		}
+
+		data := struct {
+			Ref      primitive.ObjectID `bson:"ref"`
+			Selected bool               `bson:"selected"`
+		}{}
+		if err := bson.Unmarshal(e.Data, &data); err != nil || !data.Selected {
+			continue
+		}
+
+		// Entitlements bound to a role require the user to be entitled to the role
+		if _, satisfied := Entitlements.EvaluateRoleBinding(e, roles[e.UserID]); !satisfied {
+			continue
+		}
+
+		candidates[e.UserID] = append(candidates[e.UserID], data.Ref)
+		refs = append(refs, data.Ref)
+	}
+
+	// Find the entitled cosmetics, as well as those assigned to the users directly
+	cosmeticFilter := bson.M{}
+	if !everyone {
+		cosmeticFilter["$or"] = bson.A{
+			bson.M{"_id": bson.M{"$in": refs}},
+			bson.M{"user_ids": bson.M{"$in": userIDs}},
+		}
+	}
+	cur, err = mongo.Collection(mongo.CollectionNameCosmetics).Find(ctx, cosmeticFilter, options.Find().SetProjection(bson.M{
+		"_id": 1, "kind": 1, "priority": 1, "user_ids": 1,
+	}))
+	if err != nil {
+		logrus.WithError(err).Error("mongo")
+		return nil, err
+	}
+	cosmetics := []*datastructure.Cosmetic{}
+	if err := cur.All(ctx, &cosmetics); err != nil {
+		logrus.WithError(err).Error("mongo")
+		return nil, err
+	}
+
+	byID := make(map[primitive.ObjectID]*datastructure.Cosmetic, len(cosmetics))
+	for _, cos := range cosmetics {
+		byID[cos.ID] = cos
+		for _, id := range cos.UserIDs {
+			candidates[id] = append(candidates[id], cos.ID)
+		}
+	}
+
+	// Higher priority wins, ties are broken by ID so the result is stable
+	outranks := func(a, b *datastructure.Cosmetic) bool {
+		if a.Priority != b.Priority {
+			return a.Priority > b.Priority
+		}
+		return bytes.Compare(a.ID[:], b.ID[:]) < 0
+	}
+
+	if everyone {
+		userIDs = make([]primitive.ObjectID, 0, len(candidates))
+		for id := range candidates {
+			userIDs = append(userIDs, id)
+		}
+	}
+
+	result := make(map[primitive.ObjectID]UserCosmetics, len(userIDs))
+	for _, userID := range userIDs {
+		var badge, paint *datastructure.Cosmetic
+		for _, ref := range candidates[userID] {
+			cos, ok := byID[ref]
+			if !ok {
+				continue
+			}
+
+			switch cos.Kind {
+			case datastructure.CosmeticKindBadge:
+				if badge == nil || outranks(cos, badge) {
+					badge = cos
+				}
+			case datastructure.CosmeticKindNametagPaint:
+				if paint == nil || outranks(cos, paint) {
+					paint = cos
+				}
+			}
+		}
+
+		uc := UserCosmetics{}
+		if badge != nil {
+			uc.BadgeID = &badge.ID
+		}
+		if paint != nil {
+			uc.PaintID = &paint.ID
+		}
+		if uc.BadgeID == nil && uc.PaintID == nil && everyone {
+			continue
+		}
+		result[userID] = uc
+	}
+
+	return result, nil
+}
+
+// InvalidateUsers: Clear the cached cosmetics of users whose entitlements or selection changed
+func (*cosmetics) InvalidateUsers(ctx context.Context, userIDs ...primitive.ObjectID) {
+	if len(userIDs) == 0 {
+		return
+	}
+	Cosmetics.NotifyChanged(ctx)
+
+	gen, err := redis.Client.Get(ctx, cosmeticsGenerationKey).Int64()
+	if err != nil && err != redis.ErrNil {
+		logrus.WithError(err).Error("redis")
+		return
+	}
+
+	keys := make([]string, len(userIDs))
+	for i, id := range userIDs {
+		keys[i] = userCosmeticsKey(gen, id)
+	}
+	if err := redis.Client.Del(ctx, keys...).Err(); err != nil {
+		logrus.WithError(err).Error("redis")
+	}
+}
+
+// InvalidateAll: Clear the cached cosmetics of every user, after a change to the cosmetics themselves
+func (*cosmetics) InvalidateAll(ctx context.Context) {
+	Cosmetics.NotifyChanged(ctx)
+	if err := redis.Client.Incr(ctx, cosmeticsGenerationKey).Err(); err != nil {
+		logrus.WithError(err).Error("redis")
+	}
+}
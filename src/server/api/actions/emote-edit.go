@@ -0,0 +1,116 @@
+package actions
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/SevenTV/ServerGo/src/discord"
+	"github.com/SevenTV/ServerGo/src/mongo"
+	"github.com/SevenTV/ServerGo/src/mongo/datastructure"
+	"github.com/SevenTV/ServerGo/src/utils"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Edit: Apply an update to an emote, record the changes in the audit log and notify about the edit
+func (*emotes) Edit(ctx context.Context, emote *datastructure.Emote, update bson.M, changes []*datastructure.AuditLogChange, actor *datastructure.User, reason *string) error {
+	oldVisibility := emote.Visibility
+	if err := Emotes.apply(ctx, emote, update, changes, actor.ID, reason); err != nil {
+		return err
+	}
+
+	Emotes.NotifyEdit(emote, oldVisibility, changes, actor, reason)
+	return nil
+}
+
+// apply: Apply an update to an emote and record the changes in the audit log, without notifying about the edit.
+// Used by actions which edit many emotes at once and notify about them together
+func (*emotes) apply(ctx context.Context, emote *datastructure.Emote, update bson.M, changes []*datastructure.AuditLogChange, actorID primitive.ObjectID, reason *string) error {
+	update["last_modified_date"] = time.Now()
+
+	after := options.After
+	doc := mongo.Collection(mongo.CollectionNameEmotes).FindOneAndUpdate(ctx, bson.M{
+		"_id": emote.ID,
+	}, bson.M{
+		"$set": update,
+	}, &options.FindOneAndUpdateOptions{
+		ReturnDocument: &after,
+	})
+	if err := doc.Decode(emote); err != nil {
+		logrus.WithError(err).WithField("id", emote.ID).Error("mongo")
+		return err
+	}
+
+	if _, err := mongo.Collection(mongo.CollectionNameAudit).InsertOne(ctx, &datastructure.AuditLog{
+		Type:      datastructure.AuditLogTypeEmoteEdit,
+		CreatedBy: actorID,
+		Target:    &datastructure.Target{ID: &emote.ID, Type: "emotes"},
+		Changes:   changes,
+		Reason:    reason,
+	}); err != nil {
+		logrus.WithError(err).Error("mongo")
+	}
+
+	return nil
+}
+
+// NotifyEdit: Let the emote owner know if another user changed the emote's listing or global state, and log the edit to Discord.
+// The emote must already hold its edited state
+func (*emotes) NotifyEdit(emote *datastructure.Emote, oldVisibility int32, changes []*datastructure.AuditLogChange, actor *datastructure.User, reason *string) {
+	if actor.ID != emote.OwnerID {
+		for _, notification := range emoteVisibilityNotifications(emote, actor, oldVisibility) {
+			notification := notification
+			go func() {
+				// Send the notification
+				if err := notification.Write(context.Background()); err != nil {
+					logrus.WithError(err).Error("failed to create notification")
+				}
+			}()
+		}
+	}
+
+	go discord.SendEmoteEdit(*emote, *actor, changes, reason)
+}
+
+// emoteVisibilityNotifications: Get the notifications to the emote owner about changes to the UNLISTED and GLOBAL flags
+func emoteVisibilityNotifications(emote *datastructure.Emote, actor *datastructure.User, oldVisibility int32) []NotificationBuilder {
+	changed := func(flag int32) (added bool, removed bool) {
+		had := utils.BitField.HasBits(int64(oldVisibility), int64(flag))
+		has := utils.BitField.HasBits(int64(emote.Visibility), int64(flag))
+		return !had && has, had && !has
+	}
+	create := func(title string, state string, text string) NotificationBuilder {
+		return Notifications.Create().
+			SetTitle(title).
+			SetCategory(datastructure.NotificationCategoryEmote).
+			SetDedupKey(fmt.Sprintf("emote-visibility:%s:%s", emote.ID.Hex(), state), NotificationDedupWindow).
+			AddTargetUsers(emote.OwnerID).
+			AddTextMessagePart("Your emote ").
+			AddEmoteMentionPart(emote.ID).
+			AddTextMessagePart(text).
+			AddUserMentionPart(actor.ID)
+	}
+
+	notifications := []NotificationBuilder{}
+	if unlisted, listed := changed(datastructure.EmoteVisibilityUnlisted); listed {
+		notifications = append(notifications, create("Emote Approved", "listed", " was approved by ").AddTextMessagePart("!"))
+	} else if unlisted {
+		notifications = append(notifications, create("Emote Unlisted", "unlisted", " was unlisted by ").
+			AddTextMessagePart(". It can still be added to channels, but will not appear in public listings."),
+		)
+	}
+	if global, unglobal := changed(datastructure.EmoteVisibilityGlobal); global {
+		notifications = append(notifications, create("Emote Made Global", "global", " was made global by ").
+			AddTextMessagePart(" and is now available in every channel!"),
+		)
+	} else if unglobal {
+		notifications = append(notifications, create("Emote No Longer Global", "unglobal", " was removed from the global emotes by ").
+			AddTextMessagePart("."),
+		)
+	}
+
+	return notifications
+}
@@ -124,12 +124,28 @@ func (*emotes) MergeEmote(ctx context.Context, opts MergeEmoteOptions) (*datastr
 		logInfo.Infof("Updated no users during merger of Emote(id=%v) into Emote(id=%v)", oldEmote.ID.Hex(), newEmote.ID.Hex())
 	}
 
+	// Switch the emote in the emote sets which aren't active, transferring aliases
+	if _, err := mongo.Collection(mongo.CollectionNameEmoteSets).UpdateMany(ctx, bson.M{
+		"emotes": oldEmote.ID,
+	}, bson.M{
+		"$set":    bson.M{"emotes.$[filter]": newEmote.ID},
+		"$rename": bson.M{fmt.Sprintf("emote_alias.%s", oldEmote.ID.Hex()): fmt.Sprintf("emote_alias.%s", newEmote.ID.Hex())},
+	}, options.Update().SetArrayFilters(options.ArrayFilters{
+		Filters: []interface{}{
+			bson.M{"filter": oldEmote.ID},
+		},
+	})); err != nil {
+		logrus.WithError(err).Error("mongo, failed to update emote sets during emote merger")
+	}
+
 	// Send notifications
 	{
 		// Send a notification to the old emote's owner that their emote was merged
 		go func() {
 			if err := Notifications.Create().
 				SetTitle("An Emote You Own Was Merged").
+				SetCategory(datastructure.NotificationCategoryEmote).
+				SetDedupKey(fmt.Sprintf("emote-merge:%s", oldEmote.ID.Hex()), NotificationDedupWindow).
 				AddTargetUsers(oldEmote.OwnerID).
 				AddTextMessagePart("Your emote ").
 				AddEmoteMentionPart(oldEmote.ID).
@@ -147,8 +163,13 @@ func (*emotes) MergeEmote(ctx context.Context, opts MergeEmoteOptions) (*datastr
 
 		// Send a notification to the channels affected
 		go func() {
+			if len(switchedChannels) == 0 {
+				return
+			}
 			if err := Notifications.Create().
 				SetTitle("A Channel Emote Was Merged").
+				SetCategory(datastructure.NotificationCategoryChannel).
+				SetDedupKey(fmt.Sprintf("emote-merge:%s", oldEmote.ID.Hex()), NotificationDedupWindow).
 				AddTargetUsers(switchedChannels...).
 				AddTextMessagePart("One of your active channel emotes, ").
 				AddEmoteMentionPart(oldEmote.ID).
@@ -167,7 +188,10 @@ func (*emotes) MergeEmote(ctx context.Context, opts MergeEmoteOptions) (*datastr
 		// Send a notification to the owner of the new emote
 		go func() {
 			if err := Notifications.Create().
-				SetTitle("An Emote Was Merged Into One You Own").AddTargetUsers(newEmote.OwnerID).
+				SetTitle("An Emote Was Merged Into One You Own").
+				SetCategory(datastructure.NotificationCategoryEmote).
+				SetDedupKey(fmt.Sprintf("emote-merge-into:%s", oldEmote.ID.Hex()), NotificationDedupWindow).
+				AddTargetUsers(newEmote.OwnerID).
 				AddTextMessagePart("The emote ").
 				AddEmoteMentionPart(oldEmote.ID).
 				AddTextMessagePart(", which was owned by ").
@@ -0,0 +1,541 @@
+package actions
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/SevenTV/ServerGo/src/configure"
+	"github.com/SevenTV/ServerGo/src/mongo"
+	"github.com/SevenTV/ServerGo/src/mongo/datastructure"
+	"github.com/SevenTV/ServerGo/src/redis"
+	"github.com/SevenTV/ServerGo/src/utils"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var (
+	ErrUnknownEmoteSet      = fmt.Errorf("unknown emote set")
+	ErrEmoteSetLimitReached = fmt.Errorf("emote set limit reached")
+	ErrEmoteSetFull         = fmt.Errorf("emote set is full")
+	ErrEmoteSetActive       = fmt.Errorf("the active emote set cannot be deleted")
+	ErrEmoteSetConflict     = fmt.Errorf("the channel's emotes or active emote set were changed at the same time")
+)
+
+// The name of the set made from a channel's emotes when it makes its first emote set
+const defaultEmoteSetName = "Main"
+
+type CreateEmoteSetOptions struct {
+	Channel    *datastructure.User
+	Name       string
+	EmoteIDs   []primitive.ObjectID
+	EmoteAlias map[string]string
+	Slots      int32
+
+	Actor *datastructure.User
+	// The set copied to create this one, if any
+	CopiedFrom *primitive.ObjectID
+	Reason     *string
+}
+
+// GetMaxSets: Get the amount of emote sets a channel can have
+func (emoteSets) GetMaxSets() int64 {
+	if max := configure.Config.GetInt64("limits.meta.emote_sets"); max > 0 {
+		return max
+	}
+	return 10
+}
+
+// GetSlots: Get the amount of emotes a set can hold for a channel with the given channel emote slots
+func (emoteSets) GetSlots(set *datastructure.EmoteSet, channelSlots int32) int32 {
+	if set.Slots > 0 && set.Slots < channelSlots {
+		return set.Slots
+	}
+	return channelSlots
+}
+
+// GetChannelEmoteSlots: Get the maximum amount of emotes the user's channel can have,
+// which is their emote slots limited by the slots of their active emote set
+func (b UserBuilder) GetChannelEmoteSlots() int32 {
+	slots := b.GetEmoteSlots()
+	if b.User.ActiveEmoteSetID == nil {
+		return slots
+	}
+
+	set := &datastructure.EmoteSet{}
+	if err := mongo.Collection(mongo.CollectionNameEmoteSets).FindOne(b.ctx, bson.M{
+		"_id": b.User.ActiveEmoteSetID,
+	}, options.FindOne().SetProjection(bson.M{"slots": 1})).Decode(set); err != nil {
+		if err != mongo.ErrNoDocuments {
+			logrus.WithError(err).Error("mongo")
+		}
+		return slots
+	}
+	return EmoteSets.GetSlots(set, slots)
+}
+
+// Get: Get an emote set. The contents of an active set are read from its owner
+func (emoteSets) Get(ctx context.Context, id primitive.ObjectID) (*datastructure.EmoteSet, error) {
+	set := &datastructure.EmoteSet{}
+	if err := mongo.Collection(mongo.CollectionNameEmoteSets).FindOne(ctx, bson.M{"_id": id}).Decode(set); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, ErrUnknownEmoteSet
+		}
+		return nil, err
+	}
+
+	owner := &datastructure.User{}
+	if err := mongo.Collection(mongo.CollectionNameUsers).FindOne(ctx, bson.M{"_id": set.OwnerID}).Decode(owner); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, ErrUnknownEmoteSet
+		}
+		return nil, err
+	}
+	EmoteSets.withLiveContents(set, owner)
+
+	return set, nil
+}
+
+// GetByOwner: Get the emote sets of a channel, oldest first. The contents of the active set are read from the channel
+func (emoteSets) GetByOwner(ctx context.Context, owner *datastructure.User) ([]*datastructure.EmoteSet, error) {
+	sets := []*datastructure.EmoteSet{}
+	cur, err := mongo.Collection(mongo.CollectionNameEmoteSets).Find(ctx, bson.M{
+		"owner_id": owner.ID,
+	}, options.Find().SetSort(bson.M{"_id": 1}))
+	if err == nil {
+		err = cur.All(ctx, &sets)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for _, set := range sets {
+		EmoteSets.withLiveContents(set, owner)
+	}
+	return sets, nil
+}
+
+// IsActive: Whether the set is the active emote set of its owner
+func (emoteSets) IsActive(set *datastructure.EmoteSet, owner *datastructure.User) bool {
+	return owner.ActiveEmoteSetID != nil && *owner.ActiveEmoteSetID == set.ID
+}
+
+func (emoteSets) withLiveContents(set *datastructure.EmoteSet, owner *datastructure.User) {
+	if !EmoteSets.IsActive(set, owner) {
+		return
+	}
+	set.EmoteIDs = owner.EmoteIDs
+	set.EmoteAlias = owner.EmoteAlias
+}
+
+// Create: Create an emote set for a channel.
+// The channel's current emotes are saved as its active set first, if it had no emote sets yet
+func (x emoteSets) Create(ctx context.Context, opts CreateEmoteSetOptions) (*datastructure.EmoteSet, error) {
+	if err := x.ensureActiveSet(ctx, opts.Channel); err != nil {
+		return nil, err
+	}
+
+	count, err := mongo.Collection(mongo.CollectionNameEmoteSets).CountDocuments(ctx, bson.M{"owner_id": opts.Channel.ID})
+	if err != nil {
+		return nil, err
+	}
+	if count >= x.GetMaxSets() {
+		return nil, ErrEmoteSetLimitReached
+	}
+
+	set := &datastructure.EmoteSet{
+		ID:         primitive.NewObjectID(),
+		OwnerID:    opts.Channel.ID,
+		Name:       opts.Name,
+		EmoteIDs:   opts.EmoteIDs,
+		EmoteAlias: opts.EmoteAlias,
+		Slots:      opts.Slots,
+	}
+	if set.EmoteIDs == nil {
+		set.EmoteIDs = []primitive.ObjectID{}
+	}
+	if set.EmoteAlias == nil {
+		set.EmoteAlias = map[string]string{}
+	}
+
+	ub, err := Users.With(ctx, opts.Channel)
+	if err != nil {
+		return nil, err
+	}
+	if len(set.EmoteIDs) > int(x.GetSlots(set, ub.GetEmoteSlots())) {
+		return nil, ErrEmoteSetFull
+	}
+
+	if _, err := mongo.Collection(mongo.CollectionNameEmoteSets).InsertOne(ctx, set); err != nil {
+		return nil, err
+	}
+
+	changes := []*datastructure.AuditLogChange{
+		{Key: "set_id", OldValue: nil, NewValue: set.ID},
+		{Key: "name", OldValue: nil, NewValue: set.Name},
+		{Key: "emotes", OldValue: nil, NewValue: len(set.EmoteIDs)},
+		{Key: "slots", OldValue: nil, NewValue: set.Slots},
+	}
+	if opts.CopiedFrom != nil {
+		changes = append(changes, &datastructure.AuditLogChange{Key: "copied_from", OldValue: nil, NewValue: opts.CopiedFrom})
+	}
+	if _, err := mongo.Collection(mongo.CollectionNameAudit).InsertOne(ctx, &datastructure.AuditLog{
+		Type:      datastructure.AuditLogTypeEmoteSetCreate,
+		CreatedBy: opts.Actor.ID,
+		Target:    &datastructure.Target{ID: &opts.Channel.ID, Type: "users"},
+		Changes:   changes,
+		Reason:    opts.Reason,
+	}); err != nil {
+		logrus.WithError(err).Error("mongo")
+	}
+
+	return set, nil
+}
+
+// Copy: Create an emote set for a channel with the emotes of another set, which may belong to any channel.
+// Emotes the channel may not use are left out
+func (x emoteSets) Copy(ctx context.Context, source *datastructure.EmoteSet, opts CreateEmoteSetOptions) (*datastructure.EmoteSet, error) {
+	emoteIDs, _, err := x.usableEmotes(ctx, opts.Channel, source.EmoteIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	opts.EmoteIDs = emoteIDs
+	opts.EmoteAlias = x.filterAlias(source.EmoteAlias, emoteIDs)
+	opts.Slots = source.Slots
+	opts.CopiedFrom = &source.ID
+	return x.Create(ctx, opts)
+}
+
+// Activate: Make a set the channel's active emote set, replacing the channel's emotes and aliases with those of the set.
+// The contents of the previously active set are saved to it, and emotes which can no longer be used are left out
+func (x emoteSets) Activate(ctx context.Context, channel *datastructure.User, set *datastructure.EmoteSet, actor *datastructure.User, reason *string) (*datastructure.User, error) {
+	// Work from the channel as it is now, so that emotes changed since it was read are saved to the previous set
+	channelUB, err := Users.GetByID(ctx, channel.ID)
+	if err != nil {
+		return nil, err
+	}
+	channel = &channelUB.User
+	if x.IsActive(set, channel) {
+		return channel, nil
+	}
+
+	emoteIDs, emotes, err := x.usableEmotes(ctx, channel, set.EmoteIDs)
+	if err != nil {
+		return nil, err
+	}
+	// The set must fit into the channel's emote slots
+	if int32(len(emoteIDs)) > x.GetSlots(set, channelUB.GetEmoteSlots()) {
+		return nil, ErrEmoteSetFull
+	}
+	alias := x.filterAlias(set.EmoteAlias, emoteIDs)
+
+	if err := x.ensureActiveSet(ctx, channel); err != nil {
+		return nil, err
+	}
+	previousID := *channel.ActiveEmoteSetID
+
+	// Save the contents of the set being replaced
+	res, err := mongo.Collection(mongo.CollectionNameEmoteSets).UpdateOne(ctx, bson.M{"_id": previousID}, bson.M{
+		"$set": bson.M{
+			"emotes":      utils.Ternary(channel.EmoteIDs == nil, []primitive.ObjectID{}, channel.EmoteIDs),
+			"emote_alias": utils.Ternary(channel.EmoteAlias == nil, map[string]string{}, channel.EmoteAlias),
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if res.MatchedCount == 0 {
+		// The set was deleted at the same time, so the channel's emotes would be lost
+		return nil, ErrEmoteSetConflict
+	}
+
+	// The channel's emotes must not have changed since they were saved to the previous set
+	var currentEmotes interface{} = channel.EmoteIDs
+	if len(channel.EmoteIDs) == 0 {
+		currentEmotes = bson.M{"$in": bson.A{nil, bson.A{}}}
+	}
+
+	before := *channel
+	updated := &datastructure.User{}
+	after := options.After
+	if err := mongo.Collection(mongo.CollectionNameUsers).FindOneAndUpdate(ctx, bson.M{
+		"_id":                 channel.ID,
+		"active_emote_set_id": previousID,
+		"emotes":              currentEmotes,
+	}, bson.M{
+		"$set": bson.M{
+			"emotes":              emoteIDs,
+			"emote_alias":         alias,
+			"active_emote_set_id": set.ID,
+		},
+	}, &options.FindOneAndUpdateOptions{
+		ReturnDocument: &after,
+	}).Decode(updated); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, ErrEmoteSetConflict
+		}
+		return nil, err
+	}
+
+	if _, err := mongo.Collection(mongo.CollectionNameAudit).InsertOne(ctx, &datastructure.AuditLog{
+		Type:      datastructure.AuditLogTypeEmoteSetActivate,
+		CreatedBy: actor.ID,
+		Target:    &datastructure.Target{ID: &channel.ID, Type: "users"},
+		Changes: []*datastructure.AuditLogChange{
+			{Key: "active_emote_set_id", OldValue: previousID, NewValue: set.ID},
+			{Key: "emotes", OldValue: len(before.EmoteIDs), NewValue: len(emoteIDs)},
+		},
+		Reason: reason,
+	}); err != nil {
+		logrus.WithError(err).Error("mongo")
+	}
+
+	x.publishActivation(&before, updated, emotes, actor)
+	return updated, nil
+}
+
+// Delete: Delete one of a channel's emote sets, which can't be its active set
+func (x emoteSets) Delete(ctx context.Context, set *datastructure.EmoteSet, channel *datastructure.User, actor *datastructure.User, reason *string) error {
+	channelUB, err := Users.GetByID(ctx, channel.ID)
+	if err != nil {
+		return err
+	}
+	if x.IsActive(set, &channelUB.User) {
+		return ErrEmoteSetActive
+	}
+
+	res, err := mongo.Collection(mongo.CollectionNameEmoteSets).DeleteOne(ctx, bson.M{"_id": set.ID})
+	if err != nil {
+		return err
+	}
+	if res.DeletedCount == 0 {
+		return ErrUnknownEmoteSet
+	}
+
+	// The set may have been activated at the same time, in which case it must be kept
+	// If that can't be checked, the set is kept as well
+	if channelUB, err = Users.GetByID(ctx, channel.ID); err != nil || x.IsActive(set, &channelUB.User) {
+		if err == nil {
+			err = ErrEmoteSetActive
+		}
+		if _, rerr := mongo.Collection(mongo.CollectionNameEmoteSets).ReplaceOne(ctx, bson.M{"_id": set.ID}, set, &options.ReplaceOptions{
+			Upsert: utils.BoolPointer(true),
+		}); rerr != nil {
+			logrus.WithError(rerr).Error("mongo, failed to restore emote set")
+		}
+		return err
+	}
+
+	if _, err := mongo.Collection(mongo.CollectionNameAudit).InsertOne(ctx, &datastructure.AuditLog{
+		Type:      datastructure.AuditLogTypeEmoteSetDelete,
+		CreatedBy: actor.ID,
+		Target:    &datastructure.Target{ID: &channel.ID, Type: "users"},
+		Changes: []*datastructure.AuditLogChange{
+			{Key: "set_id", OldValue: set.ID, NewValue: nil},
+			{Key: "name", OldValue: set.Name, NewValue: nil},
+			{Key: "emotes", OldValue: set.EmoteIDs, NewValue: nil},
+			{Key: "emote_alias", OldValue: set.EmoteAlias, NewValue: nil},
+		},
+		Reason: reason,
+	}); err != nil {
+		logrus.WithError(err).Error("mongo")
+	}
+
+	return nil
+}
+
+// ensureActiveSet: Save a channel's current emotes as its active emote set, if it has none yet
+func (emoteSets) ensureActiveSet(ctx context.Context, channel *datastructure.User) error {
+	if channel.ActiveEmoteSetID != nil {
+		return nil
+	}
+
+	set := &datastructure.EmoteSet{
+		ID:         primitive.NewObjectID(),
+		OwnerID:    channel.ID,
+		Name:       defaultEmoteSetName,
+		EmoteIDs:   channel.EmoteIDs,
+		EmoteAlias: channel.EmoteAlias,
+	}
+	if set.EmoteIDs == nil {
+		set.EmoteIDs = []primitive.ObjectID{}
+	}
+	if set.EmoteAlias == nil {
+		set.EmoteAlias = map[string]string{}
+	}
+	if _, err := mongo.Collection(mongo.CollectionNameEmoteSets).InsertOne(ctx, set); err != nil {
+		return err
+	}
+
+	res, err := mongo.Collection(mongo.CollectionNameUsers).UpdateOne(ctx, bson.M{
+		"_id":                 channel.ID,
+		"active_emote_set_id": nil,
+	}, bson.M{
+		"$set": bson.M{"active_emote_set_id": set.ID},
+	})
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		// The channel made its first emote set at the same time
+		if _, err := mongo.Collection(mongo.CollectionNameEmoteSets).DeleteOne(ctx, bson.M{"_id": set.ID}); err != nil {
+			logrus.WithError(err).Error("mongo")
+		}
+		return ErrEmoteSetConflict
+	}
+
+	channel.ActiveEmoteSetID = &set.ID
+	return nil
+}
+
+// usableEmotes: Filter a list of emotes to those which are live and which the channel may use, keeping their order
+func (emoteSets) usableEmotes(ctx context.Context, channel *datastructure.User, ids []primitive.ObjectID) ([]primitive.ObjectID, map[primitive.ObjectID]*datastructure.Emote, error) {
+	result := []primitive.ObjectID{}
+	byID := map[primitive.ObjectID]*datastructure.Emote{}
+	if len(ids) == 0 {
+		return result, byID, nil
+	}
+
+	emotes := []*datastructure.Emote{}
+	cur, err := mongo.Collection(mongo.CollectionNameEmotes).Find(ctx, bson.M{
+		"_id":    bson.M{"$in": ids},
+		"status": datastructure.EmoteStatusLive,
+	})
+	if err == nil {
+		err = cur.All(ctx, &emotes)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for _, e := range emotes {
+		if Emotes.CanUse(e, channel) != nil {
+			continue
+		}
+		byID[e.ID] = e
+	}
+	for _, id := range ids {
+		if _, ok := byID[id]; ok && !utils.ContainsObjectID(result, id) {
+			result = append(result, id)
+		}
+	}
+	return result, byID, nil
+}
+
+// filterAlias: Keep the aliases of the given emotes only
+func (emoteSets) filterAlias(alias map[string]string, ids []primitive.ObjectID) map[string]string {
+	result := map[string]string{}
+	for _, id := range ids {
+		if a, ok := alias[id.Hex()]; ok {
+			result[id.Hex()] = a
+		}
+	}
+	return result
+}
+
+// publishActivation: Let clients know about the emotes which were added, removed or renamed when the channel switched emote sets
+func (emoteSets) publishActivation(before *datastructure.User, after *datastructure.User, emotes map[primitive.ObjectID]*datastructure.Emote, actor *datastructure.User) {
+	ctx := context.Background()
+	go func() {
+		// Find the emotes which were removed, to name them in the events
+		removedIDs := []primitive.ObjectID{}
+		for _, id := range before.EmoteIDs {
+			if !utils.ContainsObjectID(after.EmoteIDs, id) {
+				removedIDs = append(removedIDs, id)
+			}
+		}
+		removed := []*datastructure.Emote{}
+		if len(removedIDs) > 0 {
+			cur, err := mongo.Collection(mongo.CollectionNameEmotes).Find(ctx, bson.M{"_id": bson.M{"$in": removedIDs}})
+			if err == nil {
+				err = cur.All(ctx, &removed)
+			}
+			if err != nil {
+				logrus.WithError(err).Error("mongo")
+				return
+			}
+		}
+
+		// Find the owners of the emotes of the new set
+		ownerIDs := []primitive.ObjectID{}
+		for _, e := range emotes {
+			ownerIDs = append(ownerIDs, e.OwnerID)
+		}
+		owners := []*datastructure.User{}
+		ownerMap := map[primitive.ObjectID]*datastructure.User{}
+		if len(ownerIDs) > 0 {
+			cur, err := mongo.Collection(mongo.CollectionNameUsers).Find(ctx, bson.M{"_id": bson.M{"$in": ownerIDs}})
+			if err == nil {
+				err = cur.All(ctx, &owners)
+			}
+			if err != nil {
+				logrus.WithError(err).Error("mongo")
+			}
+			for _, o := range owners {
+				ownerMap[o.ID] = o
+			}
+		}
+
+		nameIn := func(channel *datastructure.User, e *datastructure.Emote) string {
+			if v, ok := channel.EmoteAlias[e.ID.Hex()]; ok {
+				return v
+			}
+			return e.Name
+		}
+		publish := func(e *datastructure.Emote, name string, action string, withData bool) {
+			_ = redis.Publish(ctx, fmt.Sprintf("users:%v:emotes", after.Login), redis.PubSubPayloadUserEmotes{
+				Removed: action == "REMOVE",
+				ID:      e.ID.Hex(),
+				Actor:   actor.DisplayName,
+			})
+
+			event := redis.EventApiV1ChannelEmotes{
+				Channel: after.Login,
+				EmoteID: e.ID.Hex(),
+				Name:    name,
+				Action:  action,
+				Actor:   actor.DisplayName,
+			}
+			if withData {
+				owner := ownerMap[e.OwnerID]
+				if owner == nil {
+					owner = &datastructure.User{}
+				}
+				event.Emote = &redis.EventApiV1ChannelEmotesEmote{
+					Name:       e.Name,
+					Visibility: e.Visibility,
+					MIME:       e.Mime,
+					Tags:       e.Tags,
+					Width:      e.Width,
+					Height:     e.Height,
+					Animated:   e.Animated,
+					URLs:       datastructure.GetEmoteURLs(*e),
+					Owner: redis.EventApiV1ChannelEmotesEmoteOwner{
+						ID:          e.OwnerID.Hex(),
+						TwitchID:    owner.TwitchID,
+						DisplayName: owner.DisplayName,
+						Login:       owner.Login,
+					},
+				}
+			}
+			_ = redis.Publish(ctx, fmt.Sprintf("events-v1:channel-emotes:%s", after.Login), event)
+		}
+
+		for _, e := range removed {
+			publish(e, nameIn(before, e), "REMOVE", false)
+		}
+		for _, id := range after.EmoteIDs {
+			e, ok := emotes[id]
+			if !ok {
+				continue
+			}
+			if !utils.ContainsObjectID(before.EmoteIDs, id) {
+				publish(e, nameIn(after, e), "ADD", true)
+			} else if nameIn(before, e) != nameIn(after, e) {
+				publish(e, nameIn(after, e), "UPDATE", true)
+			}
+		}
+	}()
+}
@@ -0,0 +1,28 @@
+package actions
+
+import (
+	"fmt"
+
+	"github.com/SevenTV/ServerGo/src/mongo/datastructure"
+	"github.com/SevenTV/ServerGo/src/utils"
+)
+
+var (
+	ErrEmoteNotShared      = fmt.Errorf("emote is private and not shared with the user")
+	ErrZeroWidthNotAllowed = fmt.Errorf("user may not use zero-width emotes")
+)
+
+// CanUse: Check whether a user may add an emote to their channel or one of their emote sets.
+// Private emotes can only be used by their owner or the users they are shared with,
+// and zero-width emotes only by users with permission
+func (*emotes) CanUse(emote *datastructure.Emote, user *datastructure.User) error {
+	if utils.BitField.HasBits(int64(emote.Visibility), int64(datastructure.EmoteVisibilityPrivate)) {
+		if emote.OwnerID != user.ID && !utils.ContainsObjectID(emote.SharedWith, user.ID) {
+			return ErrEmoteNotShared
+		}
+	}
+	if utils.BitField.HasBits(int64(emote.Visibility), int64(datastructure.EmoteVisibilityZeroWidth)) && !user.HasPermission(datastructure.RolePermissionUseZeroWidthEmote) {
+		return ErrZeroWidthNotAllowed
+	}
+	return nil
+}
@@ -0,0 +1,298 @@
+package actions
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/SevenTV/ServerGo/src/mongo"
+	"github.com/SevenTV/ServerGo/src/mongo/datastructure"
+	"github.com/SevenTV/ServerGo/src/redis"
+	"github.com/bsm/redislock"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// The amount of users handled at once by a bulk entitlement job
+const bulkEntitlementBatchSize = 500
+
+var ErrBulkEntitlementRunning = fmt.Errorf("a bulk job for this item is already running")
+
+// A string representing what a bulk entitlement job does
+type BulkEntitlementAction string
+
+var (
+	BulkEntitlementActionGrant  = BulkEntitlementAction("GRANT")  // Entitle users who aren't yet
+	BulkEntitlementActionRevoke = BulkEntitlementAction("REVOKE") // Remove the entitlement from users who have it
+)
+
+type BulkEntitlementOptions struct {
+	Action BulkEntitlementAction
+	// The entitlement granted to each user, or the kind and data.ref of those revoked
+	Entitlement datastructure.Entitlement
+	// The users affected, who must match every condition given
+	UserIDs []primitive.ObjectID
+	RoleID  *primitive.ObjectID
+	EmoteID *primitive.ObjectID // Users with the emote added to their channel
+
+	Actor  primitive.ObjectID
+	Reason *string
+}
+
+// BulkEntitlementCount is the outcome of a bulk entitlement job, or what it would be
+type BulkEntitlementCount struct {
+	// The amount of users selected
+	Matched int32
+	// The amount of users who are granted or revoked the entitlement,
+	// the others already have it, or don't have it to begin with
+	Affected int32
+}
+
+// bulkRef: The item entitled, which together with the kind identifies the same entitlement across users
+func (opts BulkEntitlementOptions) bulkRef() primitive.ObjectID {
+	ref, _ := opts.Entitlement.Data.Lookup("ref").ObjectIDOK()
+	return ref
+}
+
+// userFilter: The query selecting the users affected
+func (opts BulkEntitlementOptions) userFilter() bson.M {
+	filter := bson.M{}
+	if opts.UserIDs != nil {
+		filter["_id"] = bson.M{"$in": opts.UserIDs}
+	}
+	if opts.RoleID != nil {
+		filter["role"] = opts.RoleID
+	}
+	if opts.EmoteID != nil {
+		filter["emotes"] = opts.EmoteID
+	}
+	return filter
+}
+
+// holderFilter: The query selecting the entitlements which count as already granted for some users.
+// Entitlements granted by subscriptions are left to them, and never revoked by a bulk job.
+// When granting, an expired or disabled entitlement doesn't count, so its user is granted a new one
+func (opts BulkEntitlementOptions) holderFilter(userIDs []primitive.ObjectID) bson.M {
+	filter := bson.M{
+		"kind":            opts.Entitlement.Kind,
+		"data.ref":        opts.bulkRef(),
+		"user_id":         bson.M{"$in": userIDs},
+		"subscription_id": nil,
+	}
+	if opts.Action == BulkEntitlementActionGrant {
+		filter = Entitlements.ActiveFilter(filter)
+	}
+	return filter
+}
+
+// CountBulk: Find out how many users a bulk entitlement job would select and affect, without changing anything
+func (x entitlements) CountBulk(ctx context.Context, opts BulkEntitlementOptions) (BulkEntitlementCount, error) {
+	count := BulkEntitlementCount{}
+	err := x.eachBulkBatch(ctx, opts, func(userIDs []primitive.ObjectID, holders map[primitive.ObjectID]bool) error {
+		count.Matched += int32(len(userIDs))
+		if opts.Action == BulkEntitlementActionRevoke {
+			count.Affected += int32(len(holders))
+		} else {
+			count.Affected += int32(len(userIDs) - len(holders))
+		}
+		return nil
+	})
+	return count, err
+}
+
+// ApplyBulk: Grant or revoke an entitlement for many users in the background, skipping users who already
+// have it when granting. The returned job can be used to track progress, and a single audit log entry records the outcome
+func (x entitlements) ApplyBulk(ctx context.Context, opts BulkEntitlementOptions) (*JobTracker, error) {
+	// Only one job may work on the same item at once, so that users aren't granted it twice
+	lockCtx := context.Background()
+	lock, err := redis.GetLocker().Obtain(lockCtx, fmt.Sprintf("lock:bulk-entitlements:%s:%s", opts.Entitlement.Kind, opts.bulkRef().Hex()), time.Minute, nil)
+	if err == redislock.ErrNotObtained {
+		return nil, ErrBulkEntitlementRunning
+	} else if err != nil {
+		return nil, err
+	}
+
+	ref := opts.bulkRef()
+	job, err := Jobs.Start(ctx, datastructure.JobKindBulkEntitlements, opts.Actor, &datastructure.Target{ID: &ref, Type: bulkEntitlementTargetType(opts.Entitlement.Kind)})
+	if err != nil {
+		_ = lock.Release(lockCtx)
+		return nil, err
+	}
+
+	go func() {
+		ctx := context.Background()
+		defer func() {
+			if err := lock.Release(lockCtx); err != nil {
+				logrus.WithError(err).Error("bulk entitlements, failed to release lock")
+			}
+		}()
+
+		// Keep the lock for as long as the job runs
+		done := make(chan struct{})
+		defer close(done)
+		go func() {
+			ticker := time.NewTicker(20 * time.Second)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-done:
+					return
+				case <-ticker.C:
+					if err := lock.Refresh(lockCtx, time.Minute, nil); err != nil {
+						logrus.WithError(err).Error("bulk entitlements, could not refresh lock")
+					}
+				}
+			}
+		}()
+
+		count, err := x.applyBulk(ctx, job, opts)
+		job.Finish(ctx, err)
+		if err != nil {
+			logrus.WithError(err).WithField("job", job.Job.ID).Error("bulk entitlements")
+		}
+
+		// Record the outcome
+		logType := int32(datastructure.AuditLogTypeEntitlementBulkGrant)
+		if opts.Action == BulkEntitlementActionRevoke {
+			logType = datastructure.AuditLogTypeEntitlementBulkRevoke
+		}
+		changes := []*datastructure.AuditLogChange{
+			{Key: "job_id", OldValue: nil, NewValue: job.Job.ID},
+			{Key: "status", OldValue: nil, NewValue: job.Job.Status},
+			{Key: "kind", OldValue: nil, NewValue: opts.Entitlement.Kind},
+			{Key: "matched", OldValue: nil, NewValue: count.Matched},
+			{Key: "affected", OldValue: nil, NewValue: count.Affected},
+			{Key: "failed", OldValue: nil, NewValue: job.Job.Failed},
+		}
+		if opts.UserIDs != nil {
+			changes = append(changes, &datastructure.AuditLogChange{Key: "user_ids", OldValue: nil, NewValue: len(opts.UserIDs)})
+		}
+		if opts.RoleID != nil {
+			changes = append(changes, &datastructure.AuditLogChange{Key: "role_id", OldValue: nil, NewValue: opts.RoleID})
+		}
+		if opts.EmoteID != nil {
+			changes = append(changes, &datastructure.AuditLogChange{Key: "emote_id", OldValue: nil, NewValue: opts.EmoteID})
+		}
+		if _, err := mongo.Collection(mongo.CollectionNameAudit).InsertOne(ctx, &datastructure.AuditLog{
+			Type:      logType,
+			CreatedBy: opts.Actor,
+			Target:    &datastructure.Target{ID: &ref, Type: bulkEntitlementTargetType(opts.Entitlement.Kind)},
+			Changes:   changes,
+			Reason:    opts.Reason,
+		}); err != nil {
+			logrus.WithError(err).Error("mongo")
+		}
+	}()
+
+	return job, nil
+}
+
+func (x entitlements) applyBulk(ctx context.Context, job *JobTracker, opts BulkEntitlementOptions) (BulkEntitlementCount, error) {
+	total, err := mongo.Collection(mongo.CollectionNameUsers).CountDocuments(ctx, opts.userFilter())
+	if err != nil {
+		return BulkEntitlementCount{}, err
+	}
+	job.SetTotal(ctx, int32(total))
+
+	count := BulkEntitlementCount{}
+	err = x.eachBulkBatch(ctx, opts, func(userIDs []primitive.ObjectID, holders map[primitive.ObjectID]bool) error {
+		count.Matched += int32(len(userIDs))
+
+		affected := int32(0)
+		failed := int32(0)
+		if opts.Action == BulkEntitlementActionRevoke {
+			if _, err := mongo.Collection(mongo.CollectionNameEntitlements).DeleteMany(ctx, opts.holderFilter(userIDs)); err != nil {
+				logrus.WithError(err).Error("mongo")
+				failed = int32(len(holders))
+			} else {
+				affected = int32(len(holders))
+			}
+		} else {
+			docs := []interface{}{}
+			for _, id := range userIDs {
+				if holders[id] {
+					continue
+				}
+				e := opts.Entitlement
+				e.ID = primitive.NewObjectID()
+				e.UserID = id
+				docs = append(docs, e)
+			}
+			if len(docs) > 0 {
+				res, err := mongo.Collection(mongo.CollectionNameEntitlements).InsertMany(ctx, docs, options.InsertMany().SetOrdered(false))
+				if res != nil {
+					affected = int32(len(res.InsertedIDs))
+				}
+				if err != nil {
+					logrus.WithError(err).Error("mongo")
+					failed = int32(len(docs)) - affected
+				}
+			}
+		}
+		count.Affected += affected
+
+		Cosmetics.InvalidateUsers(ctx, userIDs...)
+		job.Progress(ctx, int32(len(userIDs)), failed)
+		return nil
+	})
+	return count, err
+}
+
+// eachBulkBatch: Go through the users selected by a bulk entitlement job in batches,
+// along with which of them already have the entitlement
+func (entitlements) eachBulkBatch(ctx context.Context, opts BulkEntitlementOptions, f func(userIDs []primitive.ObjectID, holders map[primitive.ObjectID]bool) error) error {
+	cur, err := mongo.Collection(mongo.CollectionNameUsers).Find(ctx, opts.userFilter(), options.Find().
+		SetProjection(bson.M{"_id": 1}).
+		SetSort(bson.M{"_id": 1}).
+		SetBatchSize(bulkEntitlementBatchSize),
+	)
+	if err != nil {
+		return err
+	}
+	defer cur.Close(ctx)
+
+	handle := func(userIDs []primitive.ObjectID) error {
+		holderIDs, err := mongo.Collection(mongo.CollectionNameEntitlements).Distinct(ctx, "user_id", opts.holderFilter(userIDs))
+		if err != nil {
+			return err
+		}
+		holders := make(map[primitive.ObjectID]bool, len(holderIDs))
+		for _, id := range holderIDs {
+			if oid, ok := id.(primitive.ObjectID); ok {
+				holders[oid] = true
+			}
+		}
+		return f(userIDs, holders)
+	}
+
+	batch := make([]primitive.ObjectID, 0, bulkEntitlementBatchSize)
+	for cur.Next(ctx) {
+		id, ok := cur.Current.Lookup("_id").ObjectIDOK()
+		if !ok {
+			continue
+		}
+		batch = append(batch, id)
+		if len(batch) == bulkEntitlementBatchSize {
+			if err := handle(batch); err != nil {
+				return err
+			}
+			batch = make([]primitive.ObjectID, 0, bulkEntitlementBatchSize)
+		}
+	}
+	if err := cur.Err(); err != nil {
+		return err
+	}
+	if len(batch) > 0 {
+		return handle(batch)
+	}
+	return nil
+}
+
+func bulkEntitlementTargetType(kind datastructure.EntitlementKind) string {
+	if kind == datastructure.EntitlementKindRole {
+		return "roles"
+	}
+	return "cosmetics"
+}
@@ -0,0 +1,140 @@
+package actions
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/SevenTV/ServerGo/src/mongo"
+	"github.com/SevenTV/ServerGo/src/mongo/datastructure"
+	"github.com/SevenTV/ServerGo/src/redis"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type EntitlementEventType string
+
+const (
+	EntitlementEventActivated EntitlementEventType = "activated" // The entitlement reached its start time
+	EntitlementEventExpired   EntitlementEventType = "expired"   // The entitlement reached its end time
+)
+
+// EntitlementEvent is published to a user's channel when one of their entitlements activates or expires
+type EntitlementEvent struct {
+	Type        EntitlementEventType       `json:"type"`
+	Entitlement *datastructure.Entitlement `json:"entitlement"`
+}
+
+func entitlementEventsChannel(userID primitive.ObjectID) string {
+	return fmt.Sprintf("events:entitlements:%s", userID.Hex())
+}
+
+// ProcessSchedule: Find the entitlements which activated or expired within a period,
+// then let their users know and clear what depends on them. Returns the amount of entitlements processed
+func (entitlements) ProcessSchedule(ctx context.Context, from, to time.Time) (int, error) {
+	processed := 0
+	for _, event := range []EntitlementEventType{EntitlementEventActivated, EntitlementEventExpired} {
+		field := "starts_at"
+		if event == EntitlementEventExpired {
+			field = "ends_at"
+		}
+
+		cur, err := mongo.Collection(mongo.CollectionNameEntitlements).Find(ctx, bson.M{
+			"disabled": bson.M{"$ne": true},
+			field:      bson.M{"$gt": from, "$lte": to},
+		})
+		if err != nil {
+			logrus.WithError(err).Error("mongo")
+			return processed, err
+		}
+		items := []*datastructure.Entitlement{}
+		if err := cur.All(ctx, &items); err != nil {
+			logrus.WithError(err).Error("mongo")
+			return processed, err
+		}
+
+		userIDs := make([]primitive.ObjectID, len(items))
+		for i, e := range items {
+			userIDs[i] = e.UserID
+
+			// An entitlement ending before it got the chance to start is not worth telling about
+			if event == EntitlementEventActivated && !e.IsActive(to) {
+				continue
+			}
+			if event == EntitlementEventExpired && e.StartsAt != nil && e.StartsAt.After(from) {
+				continue
+			}
+
+			if err := redis.Publish(ctx, entitlementEventsChannel(e.UserID), EntitlementEvent{
+				Type:        event,
+				Entitlement: e,
+			}); err != nil {
+				logrus.WithError(err).Error("redis, failed to publish entitlement event")
+			}
+			Entitlements.notifySchedule(ctx, e, event)
+		}
+		Cosmetics.InvalidateUsers(ctx, userIDs...)
+		processed += len(items)
+	}
+
+	return processed, nil
+}
+
+// notifySchedule: Send a notification to the user of an entitlement which activated or expired
+func (entitlements) notifySchedule(ctx context.Context, e *datastructure.Entitlement, event EntitlementEventType) {
+	notify := Notifications.Create().
+		SetCategory(datastructure.NotificationCategoryAccount).
+		AddTargetUsers(e.UserID).
+		SetDedupKey(fmt.Sprintf("entitlement:%s:%s", e.ID.Hex(), event), 24*time.Hour)
+
+	b := Entitlements.With(ctx, *e)
+	switch e.Kind {
+	case datastructure.EntitlementKindBadge, datastructure.EntitlementKindPaint:
+		item, name, ref := "Chat Badge", "badge", b.ReadBadgeData().ObjectReference
+		if e.Kind == datastructure.EntitlementKindPaint {
+			item, name, ref = "Nametag Paint", "paint", b.ReadPaintData().ObjectReference
+		}
+		cosmetic := &datastructure.Cosmetic{}
+		if err := mongo.Collection(mongo.CollectionNameCosmetics).FindOne(ctx, bson.M{"_id": ref}).Decode(cosmetic); err != nil {
+			if err != mongo.ErrNoDocuments {
+				logrus.WithError(err).Error("mongo")
+			}
+			return
+		}
+
+		if event == EntitlementEventActivated {
+			notify = notify.SetTitle(fmt.Sprintf("%s Acquired", item)).
+				AddTextMessagePart(fmt.Sprintf("The %s \"%v\" has been added to your account", name, cosmetic.Name))
+		} else {
+			notify = notify.SetTitle(fmt.Sprintf("%s Expired", item)).
+				AddTextMessagePart(fmt.Sprintf("The %s \"%v\" has expired and was removed from your account", name, cosmetic.Name))
+		}
+	case datastructure.EntitlementKindRole:
+		role := b.ReadRoleData().ObjectReference
+		if event == EntitlementEventActivated {
+			notify = notify.SetTitle("Global Role Granted").
+				AddTextMessagePart("You've been granted the role").
+				AddRoleMentionPart(role)
+		} else {
+			notify = notify.SetTitle("Global Role Expired").
+				AddTextMessagePart("You no longer have the role").
+				AddRoleMentionPart(role)
+		}
+	case datastructure.EntitlementKindEmoteSlots:
+		slots := b.ReadEmoteSlotsData().Slots
+		if event == EntitlementEventActivated {
+			notify = notify.SetTitle("Emote Slots Granted").
+				AddTextMessagePart(fmt.Sprintf("You've been granted %d additional channel emote slots", slots))
+		} else {
+			notify = notify.SetTitle("Emote Slots Expired").
+				AddTextMessagePart(fmt.Sprintf("Your %d additional channel emote slots have expired", slots))
+		}
+	default:
+		return
+	}
+
+	if err := notify.Write(ctx); err != nil {
+		logrus.WithError(err).Error("notifications")
+	}
+}
@@ -3,6 +3,7 @@ package actions
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/SevenTV/ServerGo/src/mongo"
 	"github.com/SevenTV/ServerGo/src/mongo/datastructure"
@@ -29,6 +30,9 @@ func (b EntitlementBuilder) Write() (EntitlementBuilder, error) {
 		return b, err
 	}
 
+	// The entitlement may change which cosmetics the user displays
+	Cosmetics.InvalidateUsers(b.ctx, b.Entitlement.UserID)
+
 	return b, nil
 }
 
@@ -62,6 +66,27 @@ func (b EntitlementBuilder) SetUserID(id primitive.ObjectID) EntitlementBuilder
 	return b
 }
 
+// SetStartsAt: Change when the entitlement becomes active, or make it active immediately if nil
+func (b EntitlementBuilder) SetStartsAt(t *time.Time) EntitlementBuilder {
+	b.Entitlement.StartsAt = t
+
+	return b
+}
+
+// SetEndsAt: Change when the entitlement stops being active, or make it permanent if nil
+func (b EntitlementBuilder) SetEndsAt(t *time.Time) EntitlementBuilder {
+	b.Entitlement.EndsAt = t
+
+	return b
+}
+
+// SetSubscriptionID: Change the subscription which granted the entitlement
+func (b EntitlementBuilder) SetSubscriptionID(id *primitive.ObjectID) EntitlementBuilder {
+	b.Entitlement.SubscriptionID = id
+
+	return b
+}
+
 // SetSubscriptionData: Add a subscription reference to the entitlement
 func (b EntitlementBuilder) SetSubscriptionData(data datastructure.EntitledSubscription) EntitlementBuilder {
 	return b.marshalData(data)
@@ -87,6 +112,11 @@ func (b EntitlementBuilder) SetEmoteSetData(data datastructure.EntitledEmoteSet)
 	return b.marshalData(data)
 }
 
+// SetEmoteSlotsData: Add an amount of additional channel emote slots to the entitlement
+func (b EntitlementBuilder) SetEmoteSlotsData(data datastructure.EntitledEmoteSlots) EntitlementBuilder {
+	return b.marshalData(data)
+}
+
 func (b EntitlementBuilder) marshalData(data interface{}) EntitlementBuilder {
 	d, err := bson.Marshal(data)
 	if err != nil {
@@ -148,10 +178,21 @@ func (b EntitlementBuilder) ReadEmoteSetData() datastructure.EntitledEmoteSet {
 	return e
 }
 
+// ReadEmoteSlotsData: Read the data as Entitled Emote Slots
+func (b EntitlementBuilder) ReadEmoteSlotsData() datastructure.EntitledEmoteSlots {
+	var e datastructure.EntitledEmoteSlots
+	if err := bson.Unmarshal(b.Entitlement.Data, &e); err != nil {
+		logrus.WithError(err).Error("bson")
+		return e
+	}
+	return e
+}
+
 // Create: Get a new entitlement builder
 func (entitlements) Create(ctx context.Context) EntitlementBuilder {
 	return EntitlementBuilder{
 		Entitlement: datastructure.Entitlement{},
+		ctx:         ctx,
 	}
 }
 
@@ -159,9 +200,34 @@ func (entitlements) Create(ctx context.Context) EntitlementBuilder {
 func (entitlements) With(ctx context.Context, e datastructure.Entitlement) EntitlementBuilder {
 	return EntitlementBuilder{
 		Entitlement: e,
+		ctx:         ctx,
 	}
 }
 
+// ActiveFilter: Add the conditions for entitlements to be currently active to a query,
+// which are that they aren't disabled and that the current time is within their start and end
+func (entitlements) ActiveFilter(query bson.M) bson.M {
+	now := time.Now()
+	query["disabled"] = bson.M{"$ne": true}
+	query["$and"] = bson.A{
+		bson.M{"$or": bson.A{bson.M{"starts_at": nil}, bson.M{"starts_at": bson.M{"$lte": now}}}},
+		bson.M{"$or": bson.A{bson.M{"ends_at": nil}, bson.M{"ends_at": bson.M{"$gt": now}}}},
+	}
+	return query
+}
+
+// EvaluateRoleBinding: Find out whether a badge or paint entitlement is bound to a role, and if so whether
+// the user is entitled to it, given the roles of the user's active role entitlements.
+// A role binding which is set, even to null, requires the user to be entitled to the role
+func (entitlements) EvaluateRoleBinding(e *datastructure.Entitlement, roles []primitive.ObjectID) (bound bool, satisfied bool) {
+	binding, err := e.Data.LookupErr("role_binding")
+	if err != nil {
+		return false, true
+	}
+	id, ok := binding.ObjectIDOK()
+	return true, ok && utils.ContainsObjectID(roles, id)
+}
+
 // FetchEntitlements: gets entitlement of specified kind
 func (entitlements) FetchEntitlements(ctx context.Context, opts struct {
 	Kind            *datastructure.EntitlementKind
@@ -169,10 +235,9 @@ func (entitlements) FetchEntitlements(ctx context.Context, opts struct {
 }) ([]EntitlementBuilder, error) {
 	// Make a request to get the user's entitlements
 	var entitlements []*entitlementWithUser
-	query := bson.M{
-		"kind":     opts.Kind,
-		"disabled": bson.M{"$not": bson.M{"$eq": true}},
-	}
+	query := Entitlements.ActiveFilter(bson.M{
+		"kind": opts.Kind,
+	})
 	if !opts.ObjectReference.IsZero() {
 		query["data.ref"] = opts.ObjectReference
 	}
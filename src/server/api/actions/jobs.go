@@ -0,0 +1,73 @@
+package actions
+
+import (
+	"context"
+	"time"
+
+	"github.com/SevenTV/ServerGo/src/mongo"
+	"github.com/SevenTV/ServerGo/src/mongo/datastructure"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Start: Create a new running job and write it to database
+func (*jobs) Start(ctx context.Context, kind datastructure.JobKind, createdBy primitive.ObjectID, target *datastructure.Target) (*JobTracker, error) {
+	t := &JobTracker{
+		Job: datastructure.Job{
+			ID:        primitive.NewObjectID(),
+			Kind:      kind,
+			Status:    datastructure.JobStatusRunning,
+			CreatedBy: createdBy,
+			Target:    target,
+		},
+	}
+
+	if _, err := mongo.Collection(mongo.CollectionNameJobs).InsertOne(ctx, &t.Job); err != nil {
+		return nil, err
+	}
+
+	return t, nil
+}
+
+// SetTotal: Set the amount of items the job will process
+func (t *JobTracker) SetTotal(ctx context.Context, total int32) {
+	t.Job.Total = total
+	t.update(ctx, bson.M{"total": total})
+}
+
+// Progress: Record processed items. Failed items count as processed
+func (t *JobTracker) Progress(ctx context.Context, processed int32, failed int32) {
+	t.Job.Processed += processed
+	t.Job.Failed += failed
+	t.update(ctx, bson.M{
+		"processed": t.Job.Processed,
+		"failed":    t.Job.Failed,
+	})
+}
+
+// Finish: Mark the job as completed, or as failed if an error is passed
+func (t *JobTracker) Finish(ctx context.Context, err error) {
+	now := time.Now()
+	t.Job.FinishedAt = &now
+	t.Job.Status = datastructure.JobStatusCompleted
+	if err != nil {
+		msg := err.Error()
+		t.Job.Error = &msg
+		t.Job.Status = datastructure.JobStatusFailed
+	}
+
+	t.update(ctx, bson.M{
+		"status":      t.Job.Status,
+		"error":       t.Job.Error,
+		"finished_at": t.Job.FinishedAt,
+	})
+}
+
+func (t *JobTracker) update(ctx context.Context, set bson.M) {
+	if _, err := mongo.Collection(mongo.CollectionNameJobs).UpdateByID(ctx, t.Job.ID, bson.M{
+		"$set": set,
+	}); err != nil {
+		logrus.WithError(err).WithField("job", t.Job.ID).Error("mongo")
+	}
+}
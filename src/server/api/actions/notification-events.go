@@ -0,0 +1,269 @@
+package actions
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/SevenTV/ServerGo/src/mongo"
+	"github.com/SevenTV/ServerGo/src/mongo/datastructure"
+	"github.com/SevenTV/ServerGo/src/redis"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	notificationAnnouncementsChannel = "events:notifications:announcements"
+	// Bumped whenever an announcement is sent or retracted, which changes every user's unread count
+	notificationAnnouncementsGenerationKey = "notifications:announcements:generation"
+	notificationUnreadCountTTL             = 24 * time.Hour
+)
+
+type NotificationEventType string
+
+const (
+	NotificationEventCreated   NotificationEventType = "created"   // A notification was sent to the user
+	NotificationEventRead      NotificationEventType = "read"      // The user read one or more notifications, or all of them if no IDs are given
+	NotificationEventRetracted NotificationEventType = "retracted" // A notification was taken back by staff
+	NotificationEventCount     NotificationEventType = "count"     // The user's unread count, sent when a client connects
+)
+
+// NotificationEvent is published to the clients of a user whenever their notifications change
+type NotificationEvent struct {
+	Type            NotificationEventType       `json:"type"`
+	Notification    *datastructure.Notification `json:"notification,omitempty"`
+	NotificationIDs []string                    `json:"notification_ids,omitempty"`
+	UnreadCount     *int64                      `json:"unread_count,omitempty"` // Omitted for announcements, clients should adjust their own count
+}
+
+func notificationEventsChannel(userID primitive.ObjectID) string {
+	return fmt.Sprintf("events:notifications:%s", userID.Hex())
+}
+
+// Subscribe: Receive the notification events of a user, as well as announcements, until the context is cancelled
+func (*notifications) Subscribe(ctx context.Context, userID primitive.ObjectID, ch chan []byte) {
+	redis.Subscribe(ctx, ch, notificationEventsChannel(userID), notificationAnnouncementsChannel)
+}
+
+// publishEvent: Publish a notification event to a user, or to everyone if no user is given
+func (*notifications) publishEvent(ctx context.Context, userID *primitive.ObjectID, event NotificationEvent) {
+	channel := notificationAnnouncementsChannel
+	if userID != nil {
+		channel = notificationEventsChannel(*userID)
+	}
+
+	if err := redis.Publish(ctx, channel, event); err != nil {
+		logrus.WithError(err).Error("redis, failed to publish notification event")
+	}
+}
+
+// unreadCountKey: Get the key of a user's cached unread count, which is tied to the current announcements
+func unreadCountKey(ctx context.Context, userID primitive.ObjectID) (string, error) {
+	gen, err := redis.Client.Get(ctx, notificationAnnouncementsGenerationKey).Int64()
+	if err != nil && err != redis.ErrNil {
+		return "", err
+	}
+
+	return fmt.Sprintf("notifications:unread:%d:%s", gen, userID.Hex()), nil
+}
+
+// GetUnreadCount: Get the amount of notifications the user did not read yet, computing it if it isn't cached
+func (*notifications) GetUnreadCount(ctx context.Context, userID primitive.ObjectID) (int64, error) {
+	key, err := unreadCountKey(ctx, userID)
+	if err != nil {
+		logrus.WithError(err).Error("redis")
+		return 0, err
+	}
+
+	count, err := redis.Client.Get(ctx, key).Int64()
+	if err == nil {
+		return count, nil
+	}
+	if err != redis.ErrNil {
+		logrus.WithError(err).Error("redis")
+		return 0, err
+	}
+
+	// Count the unread read states, leaving out those of expired notifications which weren't deleted yet
+	cur, err := mongo.Collection(mongo.CollectionNameNotificationsRead).Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"target": userID, "read": false}}},
+		{{Key: "$lookup", Value: bson.M{
+			"from":         "notifications",
+			"localField":   "notification",
+			"foreignField": "_id",
+			"as":           "notification",
+		}}},
+		{{Key: "$unwind", Value: "$notification"}},
+		{{Key: "$match", Value: notExpiredFilter("notification.")}},
+		{{Key: "$count", Value: "count"}},
+	})
+	if err != nil {
+		logrus.WithError(err).Error("mongo")
+		return 0, err
+	}
+	counts := []struct {
+		Count int64 `bson:"count"`
+	}{}
+	if err := cur.All(ctx, &counts); err != nil {
+		logrus.WithError(err).Error("mongo")
+		return 0, err
+	}
+	count = 0
+	if len(counts) > 0 {
+		count = counts[0].Count
+	}
+	unreadAnnouncements, err := Notifications.CountUnreadAnnouncements(ctx, userID)
+	if err != nil {
+		return 0, err
+	}
+	count += unreadAnnouncements
+
+	if err := redis.Client.Set(ctx, key, count, notificationUnreadCountTTL).Err(); err != nil {
+		logrus.WithError(err).Error("redis")
+	}
+	return count, nil
+}
+
+// adjustUnreadCount: Change the user's cached unread count, returning the new count
+func (*notifications) adjustUnreadCount(ctx context.Context, userID primitive.ObjectID, by int64) (int64, error) {
+	key, err := unreadCountKey(ctx, userID)
+	if err != nil {
+		logrus.WithError(err).Error("redis")
+		return 0, err
+	}
+
+	count, err := redis.IncrIfExists(ctx, key, by)
+	if err != nil {
+		logrus.WithError(err).Error("redis")
+		return 0, err
+	}
+	if count < 0 { // Not cached, or drifted
+		return Notifications.GetUnreadCount(ctx, userID)
+	}
+	return count, nil
+}
+
+// invalidateUnreadCount: Drop the user's cached unread count, so it is computed again when next requested
+func (*notifications) invalidateUnreadCount(ctx context.Context, userID primitive.ObjectID) {
+	key, err := unreadCountKey(ctx, userID)
+	if err == nil {
+		err = redis.Client.Del(ctx, key).Err()
+	}
+	if err != nil {
+		logrus.WithError(err).Error("redis")
+	}
+}
+
+// onAnnouncementsChanged: Invalidate all cached unread counts, as announcements count towards everyone's
+func (*notifications) onAnnouncementsChanged(ctx context.Context) {
+	if err := redis.Client.Incr(ctx, notificationAnnouncementsGenerationKey).Err(); err != nil {
+		logrus.WithError(err).Error("redis")
+	}
+}
+
+// onSent: Update the unread counts of the notification's recipients and push it to their clients
+func (*notifications) onSent(ctx context.Context, notification datastructure.Notification, targets []primitive.ObjectID) {
+	if notification.Announcement && !notification.Pending {
+		Notifications.onAnnouncementsChanged(ctx)
+		Notifications.publishEvent(ctx, nil, NotificationEvent{
+			Type:         NotificationEventCreated,
+			Notification: &notification,
+		})
+	}
+
+	for _, id := range targets {
+		count, err := Notifications.adjustUnreadCount(ctx, id, 1)
+		if err != nil {
+			continue
+		}
+
+		id := id
+		Notifications.publishEvent(ctx, &id, NotificationEvent{
+			Type:         NotificationEventCreated,
+			Notification: &notification,
+			UnreadCount:  &count,
+		})
+	}
+}
+
+// OnRead: Update the user's unread count after they read notifications and let their other clients know
+func (*notifications) OnRead(ctx context.Context, userID primitive.ObjectID, ids []primitive.ObjectID, read int64) {
+	count, err := Notifications.adjustUnreadCount(ctx, userID, -read)
+	if err != nil {
+		return
+	}
+
+	hex := make([]string, len(ids))
+	for i, id := range ids {
+		hex[i] = id.Hex()
+	}
+	Notifications.publishEvent(ctx, &userID, NotificationEvent{
+		Type:            NotificationEventRead,
+		NotificationIDs: hex,
+		UnreadCount:     &count,
+	})
+}
+
+// Retract: Take back a notification, removing it from all users it was sent to
+func (*notifications) Retract(ctx context.Context, notification datastructure.Notification) error {
+	if _, err := mongo.Collection(mongo.CollectionNameNotifications).UpdateOne(ctx, bson.M{
+		"_id": notification.ID,
+	}, bson.M{
+		"$set": bson.M{
+			"retracted": true,
+			"pending":   false,
+		},
+	}); err != nil {
+		logrus.WithError(err).Error("mongo")
+		return err
+	}
+
+	// Find who received the notification, so their clients can be updated
+	cur, err := mongo.Collection(mongo.CollectionNameNotificationsRead).Find(ctx, bson.M{
+		"notification": notification.ID,
+	}, options.Find().SetProjection(bson.M{"target": 1, "read": 1}))
+	if err != nil {
+		logrus.WithError(err).Error("mongo")
+		return err
+	}
+	readStates := []*datastructure.NotificationReadState{}
+	if err := cur.All(ctx, &readStates); err != nil {
+		logrus.WithError(err).Error("mongo")
+		return err
+	}
+
+	if _, err := mongo.Collection(mongo.CollectionNameNotificationsRead).DeleteMany(ctx, bson.M{
+		"notification": notification.ID,
+	}); err != nil {
+		logrus.WithError(err).Error("mongo")
+		return err
+	}
+
+	event := NotificationEvent{
+		Type:            NotificationEventRetracted,
+		NotificationIDs: []string{notification.ID.Hex()},
+	}
+	if notification.Announcement {
+		Notifications.onAnnouncementsChanged(ctx)
+		Notifications.publishEvent(ctx, nil, event)
+		return nil
+	}
+
+	for _, rs := range readStates {
+		by := int64(0)
+		if !rs.Read {
+			by = -1
+		}
+		count, err := Notifications.adjustUnreadCount(ctx, rs.TargetUser, by)
+		if err != nil {
+			continue
+		}
+
+		event := event
+		event.UnreadCount = &count
+		Notifications.publishEvent(ctx, &rs.TargetUser, event)
+	}
+	return nil
+}
@@ -1,16 +1,30 @@
 package actions
 
 import (
+	"bytes"
 	"context"
+	"fmt"
+	"sort"
+	"time"
 
+	"github.com/SevenTV/ServerGo/src/configure"
 	"github.com/SevenTV/ServerGo/src/mongo"
 	"github.com/SevenTV/ServerGo/src/mongo/datastructure"
+	"github.com/SevenTV/ServerGo/src/redis"
+	"github.com/SevenTV/ServerGo/src/utils"
 	"github.com/sirupsen/logrus"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
+// NotificationDedupWindow is the default window in which a system notification about the same subject is sent only once
+const NotificationDedupWindow = time.Hour
+
+// Expired notifications are only deleted periodically, so read states are fetched with this many extra
+// to make up for the expired notifications which are skipped
+const notificationPageHeadroom = 25
+
 // GetMentionedUsers: Get the data of mentioned users in the notification's message parts
 func (b NotificationBuilder) GetMentionedUsers(ctx context.Context) (NotificationBuilder, map[primitive.ObjectID]bool) {
 	userIDs := make(map[primitive.ObjectID]bool)
@@ -53,25 +67,96 @@ func (b NotificationBuilder) GetMentionedEmotes(ctx context.Context) (Notificati
 	return b, emoteIDs
 }
 
+// GetMentionedRoles: Get the roles mentioned in the notification's message parts
+func (b NotificationBuilder) GetMentionedRoles(ctx context.Context) (NotificationBuilder, map[primitive.ObjectID]bool) {
+	roleIDs := make(map[primitive.ObjectID]bool)
+	for _, part := range b.Notification.MessageParts { // Check message parts for role mentions
+		if part.Type != datastructure.NotificationMessagePartTypeRoleMention {
+			continue
+		}
+		if part.Mention == nil {
+			continue
+		}
+
+		// Append unique role IDs to slice
+		mention := *part.Mention
+		if _, ok := roleIDs[mention]; !ok {
+			roleIDs[mention] = true
+			b.MentionedRoles = append(b.MentionedRoles, mention)
+		}
+	}
+	return b, roleIDs
+}
+
+// ExpandRoleMentions: Add all holders of the roles mentioned in the notification to its target users
+func (b NotificationBuilder) ExpandRoleMentions(ctx context.Context) (NotificationBuilder, error) {
+	b, _ = b.GetMentionedRoles(ctx)
+	if len(b.MentionedRoles) == 0 {
+		return b, nil
+	}
+
+	cur, err := mongo.Collection(mongo.CollectionNameUsers).Find(ctx, bson.M{
+		"role": bson.M{"$in": b.MentionedRoles},
+	}, options.Find().SetProjection(bson.M{"_id": 1}))
+	if err != nil {
+		logrus.WithError(err).Error("mongo")
+		return b, err
+	}
+
+	holders := []*datastructure.User{}
+	if err := cur.All(ctx, &holders); err != nil {
+		logrus.WithError(err).Error("mongo")
+		return b, err
+	}
+
+	for _, u := range holders {
+		if !utils.ContainsObjectID(b.TargetUsers, u.ID) {
+			b.TargetUsers = append(b.TargetUsers, u.ID)
+		}
+	}
+	return b, nil
+}
+
 // Write: Write the notification to database, creating it if it doesn't exist, or updating the existing one
 func (b NotificationBuilder) Write(ctx context.Context) error {
 	upsert := true
 
+	// Skip target users who muted the category or were already notified
+	if len(b.TargetUsers) > 0 {
+		targets, err := b.filterTargetUsers(ctx)
+		if err != nil {
+			return err
+		}
+		if len(targets) == 0 {
+			return nil
+		}
+		b.TargetUsers = targets
+	}
+
 	// Create new Object ID if this is a new notification
 	if b.Notification.ID.IsZero() {
 		b.Notification.ID = primitive.NewObjectID()
 	}
 
+	// System notifications expire after the configured age, composed notifications only if staff set an expiry
+	if b.Notification.ExpireAt == nil && b.Notification.AuthorID == nil {
+		if maxAge := configure.Config.GetDuration("notifications.system_max_age"); maxAge > 0 {
+			expireAt := time.Now().Add(maxAge)
+			b.Notification.ExpireAt = &expireAt
+		}
+	}
+
 	// Write the notification
-	if d, err := mongo.Collection(mongo.CollectionNameNotifications).UpdateByID(ctx, b.Notification.ID, bson.M{
+	if _, err := mongo.Collection(mongo.CollectionNameNotifications).UpdateByID(ctx, b.Notification.ID, bson.M{
 		"$set": b.Notification,
 	}, &options.UpdateOptions{
 		Upsert: &upsert,
 	}); err != nil {
 		logrus.WithError(err).Error("mongo")
+		b.releaseDedupKeys(b.TargetUsers)
 		return err
 	} else if len(b.TargetUsers) > 0 {
-		id := d.UpsertedID.(primitive.ObjectID) // Get the ID of the created notification
+		id := b.Notification.ID
 
 		// Create notification read states target users
 		readStates := make([]interface{}, len(b.TargetUsers))
@@ -87,12 +172,91 @@ func (b NotificationBuilder) Write(ctx context.Context) error {
 		// Write the read states to database
 		if _, err := mongo.Collection(mongo.CollectionNameNotificationsRead).InsertMany(ctx, readStates); err != nil {
 			logrus.WithError(err).Error("mongo")
+			b.releaseDedupKeys(b.TargetUsers)
+			return err
 		}
 	}
 
+	// Push the notification to connected clients
+	Notifications.onSent(ctx, b.Notification, b.TargetUsers)
 	return nil
 }
 
+// filterTargetUsers: Get the target users who should receive the notification,
+// leaving out those who muted its category and those who received a duplicate within the dedup window
+func (b NotificationBuilder) filterTargetUsers(ctx context.Context) ([]primitive.ObjectID, error) {
+	targets := b.TargetUsers
+
+	if b.Notification.Category != "" {
+		cur, err := mongo.Collection(mongo.CollectionNameUsers).Find(ctx, bson.M{
+			"_id":                 bson.M{"$in": targets},
+			"muted_notifications": b.Notification.Category,
+		}, options.Find().SetProjection(bson.M{"_id": 1}))
+		if err != nil {
+			logrus.WithError(err).Error("mongo")
+			return nil, err
+		}
+
+		muted := []*datastructure.User{}
+		if err := cur.All(ctx, &muted); err != nil {
+			logrus.WithError(err).Error("mongo")
+			return nil, err
+		}
+
+		mutedIDs := make([]primitive.ObjectID, len(muted))
+		for i, u := range muted {
+			mutedIDs[i] = u.ID
+		}
+
+		filtered := []primitive.ObjectID{}
+		for _, id := range targets {
+			if !utils.ContainsObjectID(mutedIDs, id) {
+				filtered = append(filtered, id)
+			}
+		}
+		targets = filtered
+	}
+
+	if b.DedupKey != "" && b.DedupWindow > 0 {
+		filtered := []primitive.ObjectID{}
+		for _, id := range targets {
+			ok, err := redis.Client.SetNX(ctx, b.dedupKey(id), "1", b.DedupWindow).Result()
+			if err != nil {
+				logrus.WithError(err).Error("redis")
+				b.releaseDedupKeys(filtered)
+				return nil, err
+			}
+			if ok {
+				filtered = append(filtered, id)
+			}
+		}
+		targets = filtered
+	}
+
+	return targets, nil
+}
+
+func (b NotificationBuilder) dedupKey(userID primitive.ObjectID) string {
+	return fmt.Sprintf("notifications:dedup:%s:%s", b.DedupKey, userID.Hex())
+}
+
+// releaseDedupKeys: Forget that the notification was sent to the target users, when writing it failed,
+// so that it isn't held back for the rest of the dedup window
+func (b NotificationBuilder) releaseDedupKeys(targets []primitive.ObjectID) {
+	if b.DedupKey == "" || b.DedupWindow <= 0 || len(targets) == 0 {
+		return
+	}
+
+	keys := make([]string, len(targets))
+	for i, id := range targets {
+		keys[i] = b.dedupKey(id)
+	}
+	// The write may have failed because the context was cancelled
+	if err := redis.Client.Del(context.Background(), keys...).Err(); err != nil {
+		logrus.WithError(err).Error("redis, failed to release notification dedup keys")
+	}
+}
+
 // SetTitle: Set the Notification's Title
 func (b NotificationBuilder) SetTitle(title string) NotificationBuilder {
 	b.Notification.Title = title
@@ -100,6 +264,21 @@ func (b NotificationBuilder) SetTitle(title string) NotificationBuilder {
 	return b
 }
 
+// SetCategory: Set the Notification's Category, allowing target users to mute it
+func (b NotificationBuilder) SetCategory(category datastructure.NotificationCategory) NotificationBuilder {
+	b.Notification.Category = category
+
+	return b
+}
+
+// SetDedupKey: Only send the notification to target users who did not receive one with the same key within the window
+func (b NotificationBuilder) SetDedupKey(key string, window time.Duration) NotificationBuilder {
+	b.DedupKey = key
+	b.DedupWindow = window
+
+	return b
+}
+
 // AddTextMessagePart: Append a Text part to the notification
 func (b NotificationBuilder) AddTextMessagePart(text string) NotificationBuilder {
 	b.Notification.MessageParts = append(b.Notification.MessageParts, datastructure.NotificationMessagePart{
@@ -167,6 +346,438 @@ func (*notifications) Create() NotificationBuilder {
 	return builder
 }
 
+// Send: Deliver a composed notification to its targets and to the holders of the roles it mentions.
+// Announcements are readable by everyone and are not delivered to individual users
+func (*notifications) Send(ctx context.Context, notification datastructure.Notification) error {
+	b := Notifications.CreateFrom(notification)
+	if !notification.Announcement {
+		var err error
+		for _, id := range notification.Targets {
+			if !utils.ContainsObjectID(b.TargetUsers, id) {
+				b.TargetUsers = append(b.TargetUsers, id)
+			}
+		}
+		if b, err = b.ExpandRoleMentions(ctx); err != nil {
+			return err
+		}
+	}
+
+	return b.Write(ctx)
+}
+
+// SendScheduled: Send the composed notifications whose scheduled time has passed, returning how many were sent
+func (*notifications) SendScheduled(ctx context.Context) (int, error) {
+	sent := 0
+	for ctx.Err() == nil {
+		// Claim the notification before sending it, so it can't be sent twice
+		notification := datastructure.Notification{}
+		res := mongo.Collection(mongo.CollectionNameNotifications).FindOneAndUpdate(ctx, bson.M{
+			"pending":      true,
+			"retracted":    bson.M{"$ne": true},
+			"scheduled_at": bson.M{"$lte": time.Now()},
+		}, bson.M{
+			"$set": bson.M{"pending": false},
+		}, options.FindOneAndUpdate().SetSort(bson.M{"scheduled_at": 1}))
+		err := res.Err()
+		if err == nil {
+			err = res.Decode(&notification)
+		}
+		if err == mongo.ErrNoDocuments {
+			break
+		}
+		if err != nil {
+			logrus.WithError(err).Error("mongo")
+			return sent, err
+		}
+
+		notification.Pending = false
+		if err := Notifications.Send(ctx, notification); err != nil {
+			// Release the claim so the notification is sent on the next run.
+			// This must go through even if sending failed because the context was cancelled
+			if _, err := mongo.Collection(mongo.CollectionNameNotifications).UpdateOne(context.Background(), bson.M{
+				"_id":     notification.ID,
+				"pending": false,
+			}, bson.M{
+				"$set": bson.M{"pending": true},
+			}); err != nil {
+				logrus.WithError(err).WithField("id", notification.ID).Error("mongo, failed to release scheduled notification")
+			}
+			return sent, err
+		}
+		sent++
+	}
+
+	return sent, nil
+}
+
+// notExpiredFilter matches notifications which have no expiry date or did not expire yet.
+// The prefix is the path of the notification within the document
+func notExpiredFilter(prefix string) bson.M {
+	return bson.M{"$or": bson.A{
+		bson.M{prefix + "expire_at": nil},
+		bson.M{prefix + "expire_at": bson.M{"$gt": time.Now()}},
+	}}
+}
+
+// liveAnnouncementsFilter matches announcements which were sent, not retracted and did not expire
+func liveAnnouncementsFilter() bson.M {
+	filter := notExpiredFilter("")
+	filter["announcement"] = true
+	filter["pending"] = bson.M{"$ne": true}
+	filter["retracted"] = bson.M{"$ne": true}
+	return filter
+}
+
+// GetUserNotifications: Get the user's notifications older than the cursor, newest first, including announcements
+func (*notifications) GetUserNotifications(ctx context.Context, userID primitive.ObjectID, cursor *primitive.ObjectID, limit int64, unreadOnly bool) ([]*datastructure.Notification, error) {
+	// Read states of expired notifications which weren't deleted yet are skipped,
+	// so keep reading until the page is full or the user has no older notifications
+	result := []*datastructure.Notification{}
+	before := cursor
+	now := time.Now()
+	for int64(len(result)) < limit {
+		size := limit - int64(len(result)) + notificationPageHeadroom
+		rows, err := Notifications.getReadStates(ctx, userID, before, size, unreadOnly)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, row := range rows {
+			id := row.NotificationID
+			before = &id
+			if len(row.Notification) == 0 {
+				continue
+			}
+
+			n := row.Notification[0]
+			if n.ExpireAt != nil && !n.ExpireAt.After(now) {
+				continue
+			}
+			n.Read = row.Read
+			n.ReadAt = row.ReadAt
+			result = append(result, n)
+			if int64(len(result)) == limit {
+				break
+			}
+		}
+		if int64(len(rows)) < size {
+			break
+		}
+	}
+
+	// Add announcements, which are readable by everyone and have no read state until read
+	announcements, err := Notifications.GetAnnouncements(ctx, userID, cursor, limit, unreadOnly)
+	if err != nil {
+		return nil, err
+	}
+	result = append(result, announcements...)
+	sort.Slice(result, func(i, j int) bool {
+		return bytes.Compare(result[i].ID[:], result[j].ID[:]) > 0
+	})
+	if int64(len(result)) > limit {
+		result = result[:limit]
+	}
+
+	return result, nil
+}
+
+// readStateRow: A read state joined with its notification, which is missing if the notification was deleted
+type readStateRow struct {
+	NotificationID primitive.ObjectID            `bson:"notification"`
+	Read           bool                          `bson:"read"`
+	ReadAt         time.Time                     `bson:"read_at"`
+	Notification   []*datastructure.Notification `bson:"doc"`
+}
+
+// getReadStates: Get up to size of the user's read states older than the cursor, newest first, along with their notifications
+func (*notifications) getReadStates(ctx context.Context, userID primitive.ObjectID, cursor *primitive.ObjectID, size int64, unreadOnly bool) ([]*readStateRow, error) {
+	match := bson.M{"target": userID}
+	if unreadOnly {
+		match["read"] = false
+	}
+	if cursor != nil {
+		match["notification"] = bson.M{"$lt": *cursor}
+	}
+
+	pipeline := mongo.Pipeline{
+		bson.D{ // Step 1: Match only readstates where the target is the user, before the cursor
+			bson.E{
+				Key:   "$match",
+				Value: match,
+			},
+		},
+		bson.D{ // Step 2: Take a page, newest first
+			bson.E{
+				Key:   "$sort",
+				Value: bson.M{"notification": -1},
+			},
+		},
+		bson.D{
+			bson.E{
+				Key:   "$limit",
+				Value: size,
+			},
+		},
+		bson.D{ // Step 3: Find the target notification from the other collection
+			bson.E{
+				Key: "$lookup",
+				Value: bson.M{
+					"from":         "notifications", // Target the collection containing notification data
+					"localField":   "notification",  // Use the notification field, which is the ID of the notification
+					"foreignField": "_id",           // Match with foreign collection's ObjectID
+					"as":           "doc",           // Output as "doc" field, keeping the ID to continue from
+				},
+			},
+		},
+	}
+	cur, err := mongo.Collection(mongo.CollectionNameNotificationsRead).Aggregate(ctx, pipeline)
+	if err != nil {
+		logrus.WithError(err).Error("mongo")
+		return nil, err
+	}
+
+	rows := []*readStateRow{}
+	if err := cur.All(ctx, &rows); err != nil {
+		logrus.WithError(err).Error("mongo")
+		return nil, err
+	}
+	return rows, nil
+}
+
+// GetAnnouncements: Get the announcements older than the cursor, newest first, with the user's read state applied
+func (*notifications) GetAnnouncements(ctx context.Context, userID primitive.ObjectID, cursor *primitive.ObjectID, limit int64, unreadOnly bool) ([]*datastructure.Notification, error) {
+	match := liveAnnouncementsFilter()
+	if cursor != nil {
+		match["_id"] = bson.M{"$lt": *cursor}
+	}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: match}},
+		{{Key: "$sort", Value: bson.M{"_id": -1}}},
+		// Announcements only have a read state once the user read them
+		{{Key: "$lookup", Value: bson.M{
+			"from": "notifications_read",
+			"let":  bson.M{"notification": "$_id"},
+			"pipeline": bson.A{
+				bson.M{"$match": bson.M{
+					"target": userID,
+					"$expr":  bson.M{"$eq": bson.A{"$notification", "$$notification"}},
+				}},
+			},
+			"as": "read_state",
+		}}},
+		{{Key: "$unwind", Value: bson.M{"path": "$read_state", "preserveNullAndEmptyArrays": true}}},
+		{{Key: "$addFields", Value: bson.M{
+			"read":    bson.M{"$ifNull": bson.A{"$read_state.read", false}},
+			"read_at": "$read_state.read_at",
+		}}},
+	}
+	if unreadOnly {
+		pipeline = append(pipeline, bson.D{{Key: "$match", Value: bson.M{"read": false}}})
+	}
+	pipeline = append(pipeline, bson.D{{Key: "$limit", Value: limit}})
+
+	cur, err := mongo.Collection(mongo.CollectionNameNotifications).Aggregate(ctx, pipeline)
+	if err != nil {
+		logrus.WithError(err).Error("mongo")
+		return nil, err
+	}
+
+	announcements := []*datastructure.Notification{}
+	if err := cur.All(ctx, &announcements); err != nil {
+		logrus.WithError(err).Error("mongo")
+		return nil, err
+	}
+
+	return announcements, nil
+}
+
+// CountUnreadAnnouncements: Get the amount of announcements the user did not read yet
+func (*notifications) CountUnreadAnnouncements(ctx context.Context, userID primitive.ObjectID) (int64, error) {
+	cur, err := mongo.Collection(mongo.CollectionNameNotifications).Find(ctx, liveAnnouncementsFilter(), options.Find().
+		SetProjection(bson.M{"_id": 1}),
+	)
+	if err != nil {
+		logrus.WithError(err).Error("mongo")
+		return 0, err
+	}
+
+	announcements := []*datastructure.Notification{}
+	if err := cur.All(ctx, &announcements); err != nil {
+		logrus.WithError(err).Error("mongo")
+		return 0, err
+	}
+	if len(announcements) == 0 {
+		return 0, nil
+	}
+
+	ids := make([]primitive.ObjectID, len(announcements))
+	for i, a := range announcements {
+		ids[i] = a.ID
+	}
+
+	read, err := mongo.Collection(mongo.CollectionNameNotificationsRead).CountDocuments(ctx, bson.M{
+		"target":       userID,
+		"notification": bson.M{"$in": ids},
+		"read":         true,
+	})
+	if err != nil {
+		logrus.WithError(err).Error("mongo")
+		return 0, err
+	}
+
+	return int64(len(ids)) - read, nil
+}
+
+// MarkAnnouncementsRead: Create read states for the announcements among the given notifications, which users don't have until they read them
+func (*notifications) MarkAnnouncementsRead(ctx context.Context, userID primitive.ObjectID, ids []primitive.ObjectID) (int64, error) {
+	filter := bson.M{"_id": bson.M{"$in": ids}}
+	for k, v := range liveAnnouncementsFilter() {
+		filter[k] = v
+	}
+
+	cur, err := mongo.Collection(mongo.CollectionNameNotifications).Find(ctx, filter, options.Find().
+		SetProjection(bson.M{"_id": 1}),
+	)
+	if err != nil {
+		logrus.WithError(err).Error("mongo")
+		return 0, err
+	}
+
+	announcements := []*datastructure.Notification{}
+	if err := cur.All(ctx, &announcements); err != nil {
+		logrus.WithError(err).Error("mongo")
+		return 0, err
+	}
+	if len(announcements) == 0 {
+		return 0, nil
+	}
+
+	now := time.Now()
+	ops := make([]mongo.WriteModel, len(announcements))
+	for i, a := range announcements {
+		ops[i] = mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"target": userID, "notification": a.ID}).
+			SetUpdate(bson.M{"$set": bson.M{"read": true, "read_at": now}}).
+			SetUpsert(true)
+	}
+
+	res, err := mongo.Collection(mongo.CollectionNameNotificationsRead).BulkWrite(ctx, ops)
+	if err != nil {
+		logrus.WithError(err).Error("mongo")
+		return 0, err
+	}
+
+	return res.UpsertedCount, nil
+}
+
+// DeleteExpired: Delete up to limit expired notifications along with their read states, returning how many were deleted
+func (*notifications) DeleteExpired(ctx context.Context, limit int64) (int64, error) {
+	cur, err := mongo.Collection(mongo.CollectionNameNotifications).Find(ctx, bson.M{
+		"expire_at": bson.M{"$lte": time.Now()},
+	}, options.Find().SetProjection(bson.M{"_id": 1, "announcement": 1}).SetLimit(limit))
+	if err != nil {
+		logrus.WithError(err).Error("mongo")
+		return 0, err
+	}
+
+	expired := []*datastructure.Notification{}
+	if err := cur.All(ctx, &expired); err != nil {
+		logrus.WithError(err).Error("mongo")
+		return 0, err
+	}
+	if len(expired) == 0 {
+		return 0, nil
+	}
+
+	ids := make([]primitive.ObjectID, len(expired))
+	announcements := false
+	for i, n := range expired {
+		ids[i] = n.ID
+		announcements = announcements || n.Announcement
+	}
+
+	// Find the users with unread read states, whose cached unread counts may still include the expired notifications
+	unread, err := mongo.Collection(mongo.CollectionNameNotificationsRead).Distinct(ctx, "target", bson.M{
+		"notification": bson.M{"$in": ids},
+		"read":         false,
+	})
+	if err != nil {
+		logrus.WithError(err).Error("mongo")
+		return 0, err
+	}
+
+	if _, err := mongo.Collection(mongo.CollectionNameNotificationsRead).DeleteMany(ctx, bson.M{
+		"notification": bson.M{"$in": ids},
+	}); err != nil {
+		logrus.WithError(err).Error("mongo")
+		return 0, err
+	}
+	res, err := mongo.Collection(mongo.CollectionNameNotifications).DeleteMany(ctx, bson.M{
+		"_id": bson.M{"$in": ids},
+	})
+	if err != nil {
+		logrus.WithError(err).Error("mongo")
+		return 0, err
+	}
+
+	for _, v := range unread {
+		if id, ok := v.(primitive.ObjectID); ok {
+			Notifications.invalidateUnreadCount(ctx, id)
+		}
+	}
+	if announcements {
+		Notifications.onAnnouncementsChanged(ctx)
+	}
+
+	return res.DeletedCount, nil
+}
+
+// MarkAllRead: Mark all of the user's notifications and the announcements as read, returning how many were unread
+func (*notifications) MarkAllRead(ctx context.Context, userID primitive.ObjectID) (int64, error) {
+	res, err := mongo.Collection(mongo.CollectionNameNotificationsRead).UpdateMany(ctx, bson.M{
+		"target": userID,
+		"read":   false,
+	}, bson.M{
+		"$set": bson.M{
+			"read":    true,
+			"read_at": time.Now(),
+		},
+	})
+	if err != nil {
+		logrus.WithError(err).Error("mongo")
+		return 0, err
+	}
+
+	cur, err := mongo.Collection(mongo.CollectionNameNotifications).Find(ctx, liveAnnouncementsFilter(), options.Find().
+		SetProjection(bson.M{"_id": 1}),
+	)
+	if err != nil {
+		logrus.WithError(err).Error("mongo")
+		return 0, err
+	}
+	announcements := []*datastructure.Notification{}
+	if err := cur.All(ctx, &announcements); err != nil {
+		logrus.WithError(err).Error("mongo")
+		return 0, err
+	}
+	ids := make([]primitive.ObjectID, len(announcements))
+	for i, a := range announcements {
+		ids[i] = a.ID
+	}
+
+	upserted := int64(0)
+	if len(ids) > 0 {
+		if upserted, err = Notifications.MarkAnnouncementsRead(ctx, userID, ids); err != nil {
+			return 0, err
+		}
+	}
+
+	read := res.ModifiedCount + upserted
+	Notifications.OnRead(ctx, userID, nil, read)
+	return read, nil
+}
+
 // CreateFrom: Get a NotificationBuilder populated with an existing notification
 func (*notifications) CreateFrom(notification datastructure.Notification) NotificationBuilder {
 	builder := NotificationBuilder{
@@ -0,0 +1,239 @@
+package actions
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/SevenTV/ServerGo/src/configure"
+	"github.com/SevenTV/ServerGo/src/mongo"
+	"github.com/SevenTV/ServerGo/src/mongo/datastructure"
+	"github.com/SevenTV/ServerGo/src/redis"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var (
+	ErrUnknownPersonalEmoteSet = fmt.Errorf("unknown personal emote set")
+	ErrPersonalEmoteSetFull    = fmt.Errorf("personal emote set is full")
+)
+
+// GetPersonalEmoteSlots: Get the amount of emotes a personal emote set can hold
+func (entitlements) GetPersonalEmoteSlots(set datastructure.EntitledEmoteSet) int32 {
+	if set.Slots > 0 {
+		return set.Slots
+	}
+	if slots := configure.Config.GetInt32("limits.meta.personal_emote_slots"); slots > 0 {
+		return slots
+	}
+	return 5
+}
+
+// GetPersonalEmoteSet: Get an active emote set entitlement, which makes up one of its user's personal emote sets
+func (entitlements) GetPersonalEmoteSet(ctx context.Context, id primitive.ObjectID) (EntitlementBuilder, error) {
+	e := datastructure.Entitlement{}
+	if err := mongo.Collection(mongo.CollectionNameEntitlements).FindOne(ctx, Entitlements.ActiveFilter(bson.M{
+		"_id":  id,
+		"kind": datastructure.EntitlementKindEmoteSet,
+	})).Decode(&e); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return EntitlementBuilder{}, ErrUnknownPersonalEmoteSet
+		}
+		logrus.WithError(err).Error("mongo")
+		return EntitlementBuilder{}, err
+	}
+
+	return Entitlements.With(ctx, e), nil
+}
+
+// GetPersonalEmoteSets: Get a user's personal emote sets, from their active emote set entitlements,
+// with the emotes which are still live
+func (entitlements) GetPersonalEmoteSets(ctx context.Context, userID primitive.ObjectID) ([]*datastructure.EntitledEmoteSet, error) {
+	ub := UserBuilder{User: datastructure.User{ID: userID}, ctx: ctx}
+	kind := datastructure.EntitlementKindEmoteSet
+	entitlements, err := ub.FetchEntitlements(&kind)
+	if err != nil {
+		return nil, err
+	}
+
+	sets := make([]*datastructure.EntitledEmoteSet, len(entitlements))
+	emoteIDs := []primitive.ObjectID{}
+	for i, e := range entitlements {
+		set := e.ReadEmoteSetData()
+		set.ID = e.Entitlement.ID.Hex()
+		set.Slots = Entitlements.GetPersonalEmoteSlots(set)
+		sets[i] = &set
+		emoteIDs = append(emoteIDs, set.EmoteIDs...)
+	}
+	if len(emoteIDs) == 0 {
+		return sets, nil
+	}
+
+	emotes := []*datastructure.Emote{}
+	cur, err := mongo.Collection(mongo.CollectionNameEmotes).Find(ctx, bson.M{
+		"_id":    bson.M{"$in": emoteIDs},
+		"status": datastructure.EmoteStatusLive,
+	})
+	if err == nil {
+		err = cur.All(ctx, &emotes)
+	}
+	if err != nil {
+		logrus.WithError(err).Error("mongo")
+		return nil, err
+	}
+	emoteMap := make(map[primitive.ObjectID]*datastructure.Emote, len(emotes))
+	for _, e := range emotes {
+		emoteMap[e.ID] = e
+	}
+
+	// Keep the order in which the emotes were added
+	for _, set := range sets {
+		set.Emotes = []*datastructure.Emote{}
+		for _, id := range set.EmoteIDs {
+			if e, ok := emoteMap[id]; ok {
+				set.Emotes = append(set.Emotes, e)
+			}
+		}
+	}
+	return sets, nil
+}
+
+// AddPersonalEmote: Add an emote to the personal emote set of this entitlement, if the set has a free slot
+func (b EntitlementBuilder) AddPersonalEmote(emote *datastructure.Emote, actor *datastructure.User) (EntitlementBuilder, error) {
+	set := b.ReadEmoteSetData()
+	for _, id := range set.EmoteIDs {
+		if id == emote.ID {
+			return b, nil
+		}
+	}
+
+	// Emotes can only be pushed to an array
+	if len(set.EmoteIDs) == 0 {
+		if _, err := mongo.Collection(mongo.CollectionNameEntitlements).UpdateOne(b.ctx, bson.M{
+			"_id":         b.Entitlement.ID,
+			"data.emotes": nil,
+		}, bson.M{
+			"$set": bson.M{"data.emotes": bson.A{}},
+		}); err != nil {
+			logrus.WithError(err).Error("mongo")
+			return b, err
+		}
+	}
+
+	// The set must not already hold as many emotes as it has slots for, checked atomically
+	lastSlot := fmt.Sprintf("data.emotes.%d", Entitlements.GetPersonalEmoteSlots(set)-1)
+	res, err := mongo.Collection(mongo.CollectionNameEntitlements).UpdateOne(b.ctx, bson.M{
+		"_id":         b.Entitlement.ID,
+		"data.emotes": bson.M{"$ne": emote.ID},
+		lastSlot:      bson.M{"$exists": false},
+	}, bson.M{
+		"$push": bson.M{"data.emotes": emote.ID},
+	})
+	if err != nil {
+		logrus.WithError(err).Error("mongo")
+		return b, err
+	}
+	if res.ModifiedCount == 0 {
+		// Either the set is full, or the emote was added concurrently
+		count, err := mongo.Collection(mongo.CollectionNameEntitlements).CountDocuments(b.ctx, bson.M{
+			"_id":         b.Entitlement.ID,
+			"data.emotes": emote.ID,
+		})
+		if err != nil {
+			logrus.WithError(err).Error("mongo")
+			return b, err
+		}
+		if count == 0 {
+			return b, ErrPersonalEmoteSetFull
+		}
+		return b, nil
+	}
+
+	set.EmoteIDs = append(set.EmoteIDs, emote.ID)
+	b = b.SetEmoteSetData(set)
+	b.publishPersonalEmoteEvent(set, emote, "ADD", actor)
+	return b, nil
+}
+
+// RemovePersonalEmote: Remove an emote from the personal emote set of this entitlement
+func (b EntitlementBuilder) RemovePersonalEmote(emoteID primitive.ObjectID, actor *datastructure.User) (EntitlementBuilder, error) {
+	res, err := mongo.Collection(mongo.CollectionNameEntitlements).UpdateOne(b.ctx, bson.M{
+		"_id": b.Entitlement.ID,
+	}, bson.M{
+		"$pull": bson.M{"data.emotes": emoteID},
+	})
+	if err != nil {
+		logrus.WithError(err).Error("mongo")
+		return b, err
+	}
+
+	set := b.ReadEmoteSetData()
+	if res.ModifiedCount == 0 {
+		return b, nil
+	}
+	emoteIDs := []primitive.ObjectID{}
+	for _, id := range set.EmoteIDs {
+		if id != emoteID {
+			emoteIDs = append(emoteIDs, id)
+		}
+	}
+	set.EmoteIDs = emoteIDs
+	b = b.SetEmoteSetData(set)
+
+	emote := &datastructure.Emote{}
+	if err := mongo.Collection(mongo.CollectionNameEmotes).FindOne(b.ctx, bson.M{"_id": emoteID}).Decode(emote); err != nil {
+		if err != mongo.ErrNoDocuments {
+			logrus.WithError(err).Error("mongo")
+		}
+		emote = &datastructure.Emote{ID: emoteID}
+	}
+	b.publishPersonalEmoteEvent(set, emote, "REMOVE", actor)
+	return b, nil
+}
+
+// publishPersonalEmoteEvent: Let chat clients know about a change to a personal emote set,
+// on a channel keyed by the Twitch ID of the set's user
+func (b EntitlementBuilder) publishPersonalEmoteEvent(set datastructure.EntitledEmoteSet, emote *datastructure.Emote, action string, actor *datastructure.User) {
+	ctx := context.Background()
+	go func() {
+		ub, err := Users.GetByID(ctx, b.Entitlement.UserID)
+		if err != nil {
+			logrus.WithError(err).Error("mongo")
+			return
+		}
+
+		owner := datastructure.User{}
+		if !emote.OwnerID.IsZero() {
+			if err := mongo.Collection(mongo.CollectionNameUsers).FindOne(ctx, bson.M{"_id": emote.OwnerID}).Decode(&owner); err != nil {
+				logrus.WithError(err).Error("mongo")
+			}
+		}
+
+		if err := redis.Publish(ctx, fmt.Sprintf("events-v1:personal-emotes:%s", ub.User.TwitchID), redis.EventApiV1PersonalEmotes{
+			User:       ub.User.TwitchID,
+			SetID:      b.Entitlement.ID.Hex(),
+			UnicodeTag: set.UnicodeTag,
+			EmoteID:    emote.ID.Hex(),
+			Action:     action,
+			Actor:      actor.DisplayName,
+			Emote: &redis.EventApiV1ChannelEmotesEmote{
+				Name:       emote.Name,
+				Visibility: emote.Visibility,
+				MIME:       emote.Mime,
+				Tags:       emote.Tags,
+				Width:      emote.Width,
+				Height:     emote.Height,
+				Animated:   emote.Animated,
+				URLs:       datastructure.GetEmoteURLs(*emote),
+				Owner: redis.EventApiV1ChannelEmotesEmoteOwner{
+					ID:          emote.OwnerID.Hex(),
+					TwitchID:    owner.TwitchID,
+					DisplayName: owner.DisplayName,
+					Login:       owner.Login,
+				},
+			},
+		}); err != nil {
+			logrus.WithError(err).Error("redis, failed to publish personal emote event")
+		}
+	}()
+}
@@ -0,0 +1,288 @@
+package actions
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/SevenTV/ServerGo/src/configure"
+	"github.com/SevenTV/ServerGo/src/discord"
+	"github.com/SevenTV/ServerGo/src/mongo"
+	"github.com/SevenTV/ServerGo/src/mongo/datastructure"
+	"github.com/SevenTV/ServerGo/src/redis"
+	"github.com/SevenTV/ServerGo/src/utils"
+	"github.com/bsm/redislock"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var ErrNoAutoUnlist = fmt.Errorf("emote was not automatically unlisted")
+
+// EvaluateEmote: Apply the automatic moderation rules to an emote, based on the amount of unique users who reported it recently.
+// The emote is unlisted and/or escalated to the alerts webhook once the weighted amount of reporters reaches the configured thresholds
+func (r *reports) EvaluateEmote(ctx context.Context, emoteID primitive.ObjectID) error {
+	window := configure.Config.GetDuration("reports.auto_moderation.window")
+	unlistAt := configure.Config.GetFloat64("reports.auto_moderation.unlist_threshold")
+	escalateAt := configure.Config.GetFloat64("reports.auto_moderation.escalate_threshold")
+	if window <= 0 || (unlistAt <= 0 && escalateAt <= 0) {
+		return nil // Automatic moderation is disabled
+	}
+
+	// Evaluate one report at a time for a given emote, so that actions don't get applied twice
+	lock, err := redis.GetLocker().Obtain(ctx, fmt.Sprintf("lock:reports:auto-moderation:%s", emoteID.Hex()), time.Second*30, &redislock.Options{
+		RetryStrategy: redislock.LimitRetry(redislock.LinearBackoff(time.Millisecond*250), 40),
+	})
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := lock.Release(ctx); err != nil {
+			logrus.WithError(err).Error("redis, failed to release lock")
+		}
+	}()
+
+	since := time.Now().Add(-window)
+	sinceID := primitive.NewObjectIDFromTimestamp(since)
+
+	// Find the unique users with an open report on the emote within the window.
+	// A repeated report keeps its original id, so the window applies to when it was last filed
+	ids, err := mongo.Collection(mongo.CollectionNameReports).Distinct(ctx, "reporter_id", bson.M{
+		"$or": bson.A{
+			bson.M{"last_reported_at": bson.M{"$gte": since}},
+			bson.M{"last_reported_at": bson.M{"$exists": false}, "_id": bson.M{"$gte": sinceID}},
+		},
+		"target.id":   emoteID,
+		"target.type": "emotes",
+		"cleared":     false,
+	})
+	if err != nil {
+		return err
+	}
+	reporterIDs := []primitive.ObjectID{}
+	for _, v := range ids {
+		if id, ok := v.(primitive.ObjectID); ok {
+			reporterIDs = append(reporterIDs, id)
+		}
+	}
+	if len(reporterIDs) == 0 {
+		return nil
+	}
+
+	weights, err := r.GetReporterWeights(ctx, reporterIDs)
+	if err != nil {
+		return err
+	}
+	score := 0.0
+	for _, w := range weights {
+		score += w
+	}
+
+	// Find the automatic actions already taken within the window.
+	// An automatic unlisting reverted by a moderator holds until the window has passed
+	logs := []*datastructure.AuditLog{}
+	cur, err := mongo.Collection(mongo.CollectionNameAudit).Find(ctx, bson.M{
+		"_id":         bson.M{"$gte": sinceID},
+		"target.id":   emoteID,
+		"target.type": "emotes",
+		"type": bson.M{"$in": []int32{
+			datastructure.AuditLogTypeReportAutoUnlist,
+			datastructure.AuditLogTypeReportAutoEscalate,
+			datastructure.AuditLogTypeReportAutoRevert,
+		}},
+	})
+	if err == nil {
+		err = cur.All(ctx, &logs)
+	}
+	if err != nil {
+		return err
+	}
+	done := map[int32]bool{}
+	for _, l := range logs {
+		done[l.Type] = true
+	}
+
+	emote := &datastructure.Emote{}
+	res := mongo.Collection(mongo.CollectionNameEmotes).FindOne(ctx, bson.M{
+		"_id":    emoteID,
+		"status": datastructure.EmoteStatusLive,
+	})
+	err = res.Err()
+	if err == nil {
+		err = res.Decode(emote)
+	}
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil
+		}
+		return err
+	}
+
+	unlisted := utils.BitField.HasBits(int64(emote.Visibility), int64(datastructure.EmoteVisibilityUnlisted))
+	if unlistAt > 0 && score >= unlistAt && !unlisted && !done[datastructure.AuditLogTypeReportAutoUnlist] && !done[datastructure.AuditLogTypeReportAutoRevert] {
+		oldVisibility := emote.Visibility
+		newVisibility := emote.Visibility | datastructure.EmoteVisibilityUnlisted
+		reason := fmt.Sprintf("Automatically unlisted after being reported by %d users", len(reporterIDs))
+		if err := Emotes.Edit(ctx, emote, bson.M{"visibility": newVisibility}, []*datastructure.AuditLogChange{
+			{Key: "visibility", OldValue: oldVisibility, NewValue: newVisibility},
+		}, datastructure.SystemUser, &reason); err != nil {
+			return err
+		}
+
+		unlisted = true
+		r.writeAutoModerationLog(ctx, emote.ID, datastructure.AuditLogTypeReportAutoUnlist, []*datastructure.AuditLogChange{
+			{Key: "visibility", OldValue: oldVisibility, NewValue: newVisibility},
+			{Key: "reporters", OldValue: nil, NewValue: len(reporterIDs)},
+			{Key: "score", OldValue: nil, NewValue: score},
+		}, reason)
+	}
+
+	if escalateAt > 0 && score >= escalateAt && !done[datastructure.AuditLogTypeReportAutoEscalate] {
+		go discord.SendReportEscalation(*emote, len(reporterIDs), score, unlisted)
+
+		r.writeAutoModerationLog(ctx, emote.ID, datastructure.AuditLogTypeReportAutoEscalate, []*datastructure.AuditLogChange{
+			{Key: "reporters", OldValue: nil, NewValue: len(reporterIDs)},
+			{Key: "score", OldValue: nil, NewValue: score},
+		}, fmt.Sprintf("Automatically escalated after being reported by %d users", len(reporterIDs)))
+	}
+
+	return nil
+}
+
+// GetReporterWeights: Weigh users by their track record of reports.
+// A user without any handled reports weighs 1. Resolved reports bring the weight closer to 2, rejected reports closer to 0
+func (*reports) GetReporterWeights(ctx context.Context, userIDs []primitive.ObjectID) (map[primitive.ObjectID]float64, error) {
+	cur, err := mongo.Collection(mongo.CollectionNameReports).Aggregate(ctx, mongo.Pipeline{
+		bson.D{
+			bson.E{
+				Key: "$match",
+				Value: bson.M{
+					"reporter_id": bson.M{"$in": userIDs},
+					"status": bson.M{"$in": []datastructure.ReportStatus{
+						datastructure.ReportStatusResolved,
+						datastructure.ReportStatusRejected,
+					}},
+				},
+			},
+		},
+		bson.D{
+			bson.E{
+				Key: "$group",
+				Value: bson.M{
+					"_id":   bson.M{"reporter_id": "$reporter_id", "status": "$status"},
+					"count": bson.M{"$sum": 1},
+				},
+			},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	counts := []*reporterStatusCount{}
+	if err := cur.All(ctx, &counts); err != nil {
+		return nil, err
+	}
+
+	resolved := map[primitive.ObjectID]int32{}
+	rejected := map[primitive.ObjectID]int32{}
+	for _, c := range counts {
+		switch c.ID.Status {
+		case datastructure.ReportStatusResolved:
+			resolved[c.ID.ReporterID] = c.Count
+		case datastructure.ReportStatusRejected:
+			rejected[c.ID.ReporterID] = c.Count
+		}
+	}
+
+	weights := make(map[primitive.ObjectID]float64, len(userIDs))
+	for _, id := range userIDs {
+		weights[id] = 2 * float64(resolved[id]+1) / float64(resolved[id]+rejected[id]+2)
+	}
+
+	return weights, nil
+}
+
+// RevertAutoUnlist: Undo the automatic unlisting of an emote.
+// The emote will not be automatically unlisted again until the rule's window has passed
+func (r *reports) RevertAutoUnlist(ctx context.Context, emoteID primitive.ObjectID, actor *datastructure.User, reason *string) error {
+	// Find the latest automatic action on the emote
+	log := &datastructure.AuditLog{}
+	res := mongo.Collection(mongo.CollectionNameAudit).FindOne(ctx, bson.M{
+		"target.id":   emoteID,
+		"target.type": "emotes",
+		"type": bson.M{"$in": []int32{
+			datastructure.AuditLogTypeReportAutoUnlist,
+			datastructure.AuditLogTypeReportAutoRevert,
+		}},
+	}, options.FindOne().SetSort(bson.M{"_id": -1}))
+	err := res.Err()
+	if err == nil {
+		err = res.Decode(log)
+	}
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return ErrNoAutoUnlist
+		}
+		return err
+	}
+	if log.Type != datastructure.AuditLogTypeReportAutoUnlist {
+		return ErrNoAutoUnlist
+	}
+
+	emote := &datastructure.Emote{}
+	res = mongo.Collection(mongo.CollectionNameEmotes).FindOne(ctx, bson.M{
+		"_id": emoteID,
+	})
+	err = res.Err()
+	if err == nil {
+		err = res.Decode(emote)
+	}
+	if err != nil {
+		return err
+	}
+
+	oldVisibility := emote.Visibility
+	newVisibility := emote.Visibility &^ datastructure.EmoteVisibilityUnlisted
+	changes := []*datastructure.AuditLogChange{
+		{Key: "visibility", OldValue: oldVisibility, NewValue: newVisibility},
+	}
+	if newVisibility != oldVisibility {
+		if err := Emotes.Edit(ctx, emote, bson.M{"visibility": newVisibility}, changes, actor, reason); err != nil {
+			return err
+		}
+	}
+
+	if _, err := mongo.Collection(mongo.CollectionNameAudit).InsertOne(ctx, &datastructure.AuditLog{
+		Type:      datastructure.AuditLogTypeReportAutoRevert,
+		CreatedBy: actor.ID,
+		Target:    &datastructure.Target{ID: &emoteID, Type: "emotes"},
+		Changes:   changes,
+		Reason:    reason,
+	}); err != nil {
+		logrus.WithError(err).Error("mongo")
+	}
+
+	return nil
+}
+
+func (*reports) writeAutoModerationLog(ctx context.Context, emoteID primitive.ObjectID, logType int32, changes []*datastructure.AuditLogChange, reason string) {
+	if _, err := mongo.Collection(mongo.CollectionNameAudit).InsertOne(ctx, &datastructure.AuditLog{
+		Type:      logType,
+		CreatedBy: datastructure.SystemUser.ID,
+		Target:    &datastructure.Target{ID: &emoteID, Type: "emotes"},
+		Changes:   changes,
+		Reason:    &reason,
+	}); err != nil {
+		logrus.WithError(err).Error("mongo")
+	}
+}
+
+type reporterStatusCount struct {
+	ID struct {
+		ReporterID primitive.ObjectID         `bson:"reporter_id"`
+		Status     datastructure.ReportStatus `bson:"status"`
+	} `bson:"_id"`
+	Count int32 `bson:"count"`
+}
@@ -0,0 +1,433 @@
+package actions
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/SevenTV/ServerGo/src/configure"
+	"github.com/SevenTV/ServerGo/src/mongo"
+	"github.com/SevenTV/ServerGo/src/mongo/datastructure"
+	"github.com/SevenTV/ServerGo/src/redis"
+	"github.com/SevenTV/ServerGo/src/utils"
+	"github.com/bsm/redislock"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var (
+	ErrInvalidSubscriptionEvent = fmt.Errorf("invalid subscription event")
+	ErrUnknownSubscriptionTier  = fmt.Errorf("unknown subscription tier")
+	ErrUnknownSubscriptionUser  = fmt.Errorf("unknown subscription user")
+	ErrSubscriptionConflict     = fmt.Errorf("subscription was changed by another event")
+)
+
+// How long retried deliveries of an event are recognized for
+const subscriptionEventDedupTTL = 7 * 24 * time.Hour
+
+type SubscriptionEventType string
+
+const (
+	SubscriptionEventCreated  SubscriptionEventType = "subscription.created"  // The user paid for a new subscription
+	SubscriptionEventRenewed  SubscriptionEventType = "subscription.renewed"  // The user paid for another period
+	SubscriptionEventUpdated  SubscriptionEventType = "subscription.updated"  // The subscription changed tier or period
+	SubscriptionEventCanceled SubscriptionEventType = "subscription.canceled" // The user canceled, the subscription ends with the current period
+	SubscriptionEventEnded    SubscriptionEventType = "subscription.ended"    // The subscription is over
+)
+
+// SubscriptionEvent is a change to a subscription, as delivered by the payment provider's webhook
+type SubscriptionEvent struct {
+	ID   string                `json:"id"`
+	Type SubscriptionEventType `json:"type"`
+	// When the provider created the event, which orders events delivered out of order
+	CreatedAt time.Time `json:"created_at"`
+	Data      struct {
+		// The ID the provider gave the subscription
+		SubscriptionID string `json:"subscription_id"`
+		// The ID of the 7TV user, passed to the provider when the user subscribed
+		UserID string `json:"user_id"`
+		// The price paid, which determines the tier
+		PriceID          string    `json:"price_id"`
+		CurrentPeriodEnd time.Time `json:"current_period_end"`
+	} `json:"data"`
+}
+
+// GetTiers: Get the subscription tiers defined in the config
+func (subscriptions) GetTiers() []datastructure.SubscriptionTier {
+	tiers := []datastructure.SubscriptionTier{}
+	if err := configure.Config.UnmarshalKey("subscriptions.tiers", &tiers); err != nil {
+		logrus.WithError(err).Error("config, subscriptions.tiers")
+	}
+	return tiers
+}
+
+// GetTier: Find a subscription tier by its ID
+func (x subscriptions) GetTier(id string) (datastructure.SubscriptionTier, bool) {
+	for _, t := range x.GetTiers() {
+		if t.ID == id {
+			return t, true
+		}
+	}
+	return datastructure.SubscriptionTier{}, false
+}
+
+// GetTierByPrice: Find the subscription tier which a price of the payment provider subscribes to
+func (x subscriptions) GetTierByPrice(priceID string) (datastructure.SubscriptionTier, bool) {
+	for _, t := range x.GetTiers() {
+		for _, p := range t.PriceIDs {
+			if p == priceID {
+				return t, true
+			}
+		}
+	}
+	return datastructure.SubscriptionTier{}, false
+}
+
+// ApplyEvent: Apply an event delivered by a payment provider to the subscription it is about,
+// then grant or revoke the entitlements of the subscription's tier accordingly.
+// Retried deliveries of an event, events older than the last one applied, and events about subscriptions which already ended, are ignored
+func (x subscriptions) ApplyEvent(ctx context.Context, provider string, event SubscriptionEvent) (*datastructure.Subscription, error) {
+	if event.ID == "" || event.Data.SubscriptionID == "" {
+		return nil, ErrInvalidSubscriptionEvent
+	}
+	switch event.Type {
+	case SubscriptionEventCreated, SubscriptionEventRenewed, SubscriptionEventUpdated, SubscriptionEventCanceled, SubscriptionEventEnded:
+	default:
+		return nil, ErrInvalidSubscriptionEvent
+	}
+
+	// Claim the event, so that a delivery retried while this one is processed is not applied twice
+	dedupKey := fmt.Sprintf("subscriptions:events:%s:%s", provider, event.ID)
+	if ok, err := redis.Client.SetNX(ctx, dedupKey, 1, subscriptionEventDedupTTL).Result(); err != nil {
+		logrus.WithError(err).Error("redis")
+		return nil, err
+	} else if !ok {
+		return nil, nil
+	}
+
+	// Apply one event at a time for a given subscription, so that concurrent deliveries don't overwrite each other
+	lock, err := redis.GetLocker().Obtain(ctx, fmt.Sprintf("lock:subscriptions:%s:%s", provider, event.Data.SubscriptionID), time.Second*30, &redislock.Options{
+		RetryStrategy: redislock.LimitRetry(redislock.LinearBackoff(time.Millisecond*250), 40),
+	})
+	if err != nil {
+		redis.Client.Del(ctx, dedupKey)
+		return nil, err
+	}
+	defer func() {
+		if err := lock.Release(ctx); err != nil {
+			logrus.WithError(err).Error("redis, failed to release lock")
+		}
+	}()
+
+	sub, err := x.applyEvent(ctx, provider, event)
+	if err != nil {
+		// Let the provider retry the event
+		redis.Client.Del(ctx, dedupKey)
+	}
+	return sub, err
+}
+
+func (x subscriptions) applyEvent(ctx context.Context, provider string, event SubscriptionEvent) (*datastructure.Subscription, error) {
+	now := time.Now()
+	created := false
+
+	sub := &datastructure.Subscription{}
+	err := mongo.Collection(mongo.CollectionNameSubscriptions).FindOne(ctx, bson.M{
+		"provider":    provider,
+		"provider_id": event.Data.SubscriptionID,
+	}).Decode(sub)
+	if err == mongo.ErrNoDocuments {
+		// Events may be delivered out of order, so any event can start tracking the subscription
+		if event.Type == SubscriptionEventEnded {
+			return nil, nil
+		}
+
+		userID, err := primitive.ObjectIDFromHex(event.Data.UserID)
+		if err != nil {
+			return nil, ErrInvalidSubscriptionEvent
+		}
+		if _, err := Users.GetByID(ctx, userID); err != nil {
+			if err == mongo.ErrNoDocuments {
+				return nil, ErrUnknownSubscriptionUser
+			}
+			logrus.WithError(err).Error("mongo")
+			return nil, err
+		}
+		if event.Data.CurrentPeriodEnd.IsZero() {
+			return nil, ErrInvalidSubscriptionEvent
+		}
+
+		created = true
+		sub = &datastructure.Subscription{
+			ID:         primitive.NewObjectID(),
+			UserID:     userID,
+			Provider:   provider,
+			ProviderID: event.Data.SubscriptionID,
+			Status:     datastructure.SubscriptionStatusActive,
+			StartedAt:  now,
+		}
+	} else if err != nil {
+		logrus.WithError(err).Error("mongo")
+		return nil, err
+	}
+
+	// An ended subscription is never resumed, the provider creates a new one instead
+	if sub.Status == datastructure.SubscriptionStatusEnded {
+		return sub, nil
+	}
+	// Ignore events delivered after a newer one, which would otherwise roll the subscription back
+	if !created && x.isStale(sub, event) {
+		return nil, nil
+	}
+
+	// The tier is only needed to grant entitlements, so a subscription can still end if its tier was removed
+	if event.Data.PriceID != "" && event.Type != SubscriptionEventEnded {
+		tier, ok := x.GetTierByPrice(event.Data.PriceID)
+		if !ok {
+			return nil, ErrUnknownSubscriptionTier
+		}
+		sub.TierID = tier.ID
+	}
+	tier, ok := x.GetTier(sub.TierID)
+	if !ok && event.Type != SubscriptionEventEnded {
+		return nil, ErrUnknownSubscriptionTier
+	}
+	if !event.Data.CurrentPeriodEnd.IsZero() {
+		sub.CurrentPeriodEnd = event.Data.CurrentPeriodEnd
+	}
+
+	previousStatus := sub.Status
+	switch event.Type {
+	case SubscriptionEventCreated, SubscriptionEventRenewed:
+		sub.Status = datastructure.SubscriptionStatusActive
+		sub.CanceledAt = nil
+	case SubscriptionEventCanceled:
+		sub.Status = datastructure.SubscriptionStatusCanceled
+		if sub.CanceledAt == nil {
+			sub.CanceledAt = &now
+		}
+	case SubscriptionEventEnded:
+		sub.Status = datastructure.SubscriptionStatusEnded
+		sub.EndedAt = &now
+	}
+	lastEventID := sub.LastEventID
+	sub.LastEventID = event.ID
+	if event.CreatedAt.After(sub.LastEventAt) {
+		sub.LastEventAt = event.CreatedAt
+	}
+
+	// Only replace the subscription if no other event was applied since it was read
+	filter := bson.M{"_id": sub.ID}
+	if !created {
+		filter["last_event_id"] = lastEventID
+	}
+	res, err := mongo.Collection(mongo.CollectionNameSubscriptions).ReplaceOne(ctx, filter, sub, &options.ReplaceOptions{
+		Upsert: utils.BoolPointer(created),
+	})
+	if err != nil {
+		logrus.WithError(err).Error("mongo")
+		return nil, err
+	}
+	if !created && res.MatchedCount == 0 {
+		return nil, ErrSubscriptionConflict
+	}
+
+	if sub.Status == datastructure.SubscriptionStatusEnded {
+		if err := x.RevokeEntitlements(ctx, sub); err != nil {
+			return nil, err
+		}
+	} else if err := x.SyncEntitlements(ctx, sub, tier); err != nil {
+		return nil, err
+	}
+
+	if created || sub.Status != previousStatus {
+		x.notifyStatus(ctx, sub, tier)
+	}
+	return sub, nil
+}
+
+// isStale: Whether an event is older than the last one applied to the subscription.
+// Events are ordered by when the provider created them, or by their period if they don't say
+func (subscriptions) isStale(sub *datastructure.Subscription, event SubscriptionEvent) bool {
+	if !event.CreatedAt.IsZero() && !sub.LastEventAt.IsZero() {
+		return event.CreatedAt.Before(sub.LastEventAt)
+	}
+	return !event.Data.CurrentPeriodEnd.IsZero() && event.Data.CurrentPeriodEnd.Before(sub.CurrentPeriodEnd)
+}
+
+// subscriptionGrant is an entitlement bundled with a subscription tier
+type subscriptionGrant struct {
+	kind  datastructure.EntitlementKind
+	ref   primitive.ObjectID
+	slots int32
+}
+
+func (g subscriptionGrant) matches(e datastructure.Entitlement) bool {
+	if e.Kind != g.kind {
+		return false
+	}
+	if g.kind == datastructure.EntitlementKindEmoteSlots {
+		slots, _ := e.Data.Lookup("slots").Int32OK()
+		return slots == g.slots
+	}
+	ref, _ := e.Data.Lookup("ref").ObjectIDOK()
+	return ref == g.ref
+}
+
+// tierGrants: List the entitlements bundled with a subscription's tier
+func (subscriptions) tierGrants(sub *datastructure.Subscription, tier datastructure.SubscriptionTier) []subscriptionGrant {
+	grants := []subscriptionGrant{{kind: datastructure.EntitlementKindSubscription, ref: sub.ID}}
+	for _, r := range []struct {
+		kind datastructure.EntitlementKind
+		id   string
+	}{
+		{datastructure.EntitlementKindRole, tier.RoleID},
+		{datastructure.EntitlementKindBadge, tier.BadgeID},
+		{datastructure.EntitlementKindPaint, tier.PaintID},
+	} {
+		if r.id == "" {
+			continue
+		}
+		ref, err := primitive.ObjectIDFromHex(r.id)
+		if err != nil {
+			logrus.WithFields(logrus.Fields{
+				"tier": tier.ID,
+				"kind": r.kind,
+			}).Error("subscriptions, tier has an invalid object id")
+			continue
+		}
+		grants = append(grants, subscriptionGrant{kind: r.kind, ref: ref})
+	}
+	if tier.EmoteSlots > 0 {
+		grants = append(grants, subscriptionGrant{kind: datastructure.EntitlementKindEmoteSlots, slots: tier.EmoteSlots})
+	}
+	return grants
+}
+
+// SyncEntitlements: Make the entitlements granted by a subscription match its tier,
+// and last until the end of its current period (plus a grace period for the renewal to go through, if it will renew).
+// Entitlements which are kept retain their state, such as whether the badge or paint is selected
+func (x subscriptions) SyncEntitlements(ctx context.Context, sub *datastructure.Subscription, tier datastructure.SubscriptionTier) error {
+	endsAt := sub.CurrentPeriodEnd
+	if sub.Status == datastructure.SubscriptionStatusActive {
+		grace := configure.Config.GetDuration("subscriptions.grace_period")
+		if grace <= 0 {
+			grace = 72 * time.Hour
+		}
+		endsAt = endsAt.Add(grace)
+	}
+
+	cur, err := mongo.Collection(mongo.CollectionNameEntitlements).Find(ctx, bson.M{"subscription_id": sub.ID})
+	if err != nil {
+		logrus.WithError(err).Error("mongo")
+		return err
+	}
+	existing := []datastructure.Entitlement{}
+	if err := cur.All(ctx, &existing); err != nil {
+		logrus.WithError(err).Error("mongo")
+		return err
+	}
+
+	grants := x.tierGrants(sub, tier)
+	granted := make([]bool, len(grants))
+	stale := []primitive.ObjectID{}
+	kept := []primitive.ObjectID{}
+	for _, e := range existing {
+		found := false
+		for i, g := range grants {
+			if !granted[i] && g.matches(e) {
+				granted[i], found = true, true
+				break
+			}
+		}
+		if found {
+			kept = append(kept, e.ID)
+		} else {
+			stale = append(stale, e.ID)
+		}
+	}
+
+	// Remove what is no longer part of the tier, and extend what still is
+	if len(stale) > 0 {
+		if _, err := mongo.Collection(mongo.CollectionNameEntitlements).DeleteMany(ctx, bson.M{"_id": bson.M{"$in": stale}}); err != nil {
+			logrus.WithError(err).Error("mongo")
+			return err
+		}
+	}
+	if len(kept) > 0 {
+		if _, err := mongo.Collection(mongo.CollectionNameEntitlements).UpdateMany(ctx, bson.M{"_id": bson.M{"$in": kept}}, bson.M{
+			"$set": bson.M{"ends_at": endsAt},
+		}); err != nil {
+			logrus.WithError(err).Error("mongo")
+			return err
+		}
+	}
+
+	// Grant what is new
+	for i, g := range grants {
+		if granted[i] {
+			continue
+		}
+
+		b := Entitlements.Create(ctx).
+			SetKind(g.kind).
+			SetUserID(sub.UserID).
+			SetSubscriptionID(&sub.ID).
+			SetEndsAt(&endsAt)
+		switch g.kind {
+		case datastructure.EntitlementKindSubscription:
+			b = b.SetSubscriptionData(datastructure.EntitledSubscription{ObjectReference: g.ref})
+		case datastructure.EntitlementKindRole:
+			b = b.SetRoleData(datastructure.EntitledRole{ObjectReference: g.ref})
+		case datastructure.EntitlementKindBadge:
+			b = b.SetBadgeData(datastructure.EntitledBadge{ObjectReference: g.ref})
+		case datastructure.EntitlementKindPaint:
+			b = b.SetPaintData(datastructure.EntitledPaint{ObjectReference: g.ref})
+		case datastructure.EntitlementKindEmoteSlots:
+			b = b.SetEmoteSlotsData(datastructure.EntitledEmoteSlots{Slots: g.slots})
+		}
+		if _, err := b.Write(); err != nil {
+			return err
+		}
+	}
+
+	Cosmetics.InvalidateUsers(ctx, sub.UserID)
+	return nil
+}
+
+// RevokeEntitlements: Remove all entitlements granted by a subscription
+func (subscriptions) RevokeEntitlements(ctx context.Context, sub *datastructure.Subscription) error {
+	if _, err := mongo.Collection(mongo.CollectionNameEntitlements).DeleteMany(ctx, bson.M{"subscription_id": sub.ID}); err != nil {
+		logrus.WithError(err).Error("mongo")
+		return err
+	}
+
+	Cosmetics.InvalidateUsers(ctx, sub.UserID)
+	return nil
+}
+
+// notifyStatus: Let the user know that their subscription started, was canceled or ended
+func (subscriptions) notifyStatus(ctx context.Context, sub *datastructure.Subscription, tier datastructure.SubscriptionTier) {
+	notify := Notifications.Create().
+		SetCategory(datastructure.NotificationCategoryAccount).
+		AddTargetUsers(sub.UserID).
+		SetDedupKey(fmt.Sprintf("subscription:%s:%s", sub.ID.Hex(), sub.Status), 24*time.Hour)
+
+	switch sub.Status {
+	case datastructure.SubscriptionStatusActive:
+		notify = notify.SetTitle("Subscription Started").
+			AddTextMessagePart(fmt.Sprintf("Thank you for subscribing to %s! Your benefits have been added to your account", tier.Name))
+	case datastructure.SubscriptionStatusCanceled:
+		notify = notify.SetTitle("Subscription Canceled").
+			AddTextMessagePart(fmt.Sprintf("Your %s subscription was canceled. You'll keep your benefits until %s", tier.Name, sub.CurrentPeriodEnd.Format("January 2, 2006")))
+	case datastructure.SubscriptionStatusEnded:
+		notify = notify.SetTitle("Subscription Ended").
+			AddTextMessagePart(fmt.Sprintf("Your %s subscription has ended and its benefits were removed from your account", tier.Name))
+	}
+
+	if err := notify.Write(ctx); err != nil {
+		logrus.WithError(err).Error("notifications")
+	}
+}
@@ -95,11 +95,10 @@ func (b UserBuilder) GetRole() datastructure.Role {
 func (b UserBuilder) FetchEntitlements(kind *datastructure.EntitlementKind) ([]EntitlementBuilder, error) {
 	// Make a request to get the user's entitlements
 	var entitlements []*datastructure.Entitlement
-	cur, err := mongo.Collection(mongo.CollectionNameEntitlements).Find(b.ctx, bson.M{
-		"user_id":  b.User.ID,
-		"kind":     kind,
-		"disabled": bson.M{"$not": bson.M{"$eq": true}},
-	})
+	cur, err := mongo.Collection(mongo.CollectionNameEntitlements).Find(b.ctx, Entitlements.ActiveFilter(bson.M{
+		"user_id": b.User.ID,
+		"kind":    kind,
+	}))
 	if err == mongo.ErrNoDocuments {
 		return nil, nil
 	} else if err != nil {
@@ -126,6 +125,22 @@ func (b UserBuilder) FetchEntitlements(kind *datastructure.EntitlementKind) ([]E
 	return builders, nil
 }
 
+// GetEmoteSlots: Get the user's maximum channel emote slot count,
+// including the additional slots granted by their entitlements
+func (b UserBuilder) GetEmoteSlots() int32 {
+	slots := b.User.GetEmoteSlots()
+
+	kind := datastructure.EntitlementKindEmoteSlots
+	entitlements, err := b.FetchEntitlements(&kind)
+	if err != nil {
+		return slots
+	}
+	for _, e := range entitlements {
+		slots += e.ReadEmoteSlotsData().Slots
+	}
+	return slots
+}
+
 func (b UserBuilder) IsBanned() bool {
 	banned, _ := Bans.IsUserBanned(b.User.ID)
 
@@ -0,0 +1,89 @@
+package tasks
+
+import (
+	"context"
+	"time"
+
+	"github.com/SevenTV/ServerGo/src/configure"
+	"github.com/SevenTV/ServerGo/src/redis"
+	"github.com/SevenTV/ServerGo/src/server/api/actions"
+	"github.com/bsm/redislock"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Move audit log entries older than the retention age into object storage
+func ArchiveAuditLogs(ctx context.Context) error {
+	maxAge := configure.Config.GetDuration("audit.retention.max_age")
+	if maxAge <= 0 {
+		logrus.Info("Task=ArchiveAuditLogs, retention is disabled")
+		return nil
+	}
+	if configure.Config.GetString("aws_audit_bucket") == "" {
+		logrus.Warn("Task=ArchiveAuditLogs, retention is enabled but aws_audit_bucket is not set, skipping")
+		return nil
+	}
+	interval := configure.Config.GetDuration("audit.retention.interval")
+	if interval <= 0 {
+		interval = 24 * time.Hour
+	}
+	batchSize := configure.Config.GetInt64("audit.retention.batch_size")
+	if batchSize <= 0 {
+		batchSize = 10000
+	}
+
+	// Acquire lock. We won't allow any other pod to execute this concurrently
+	lockCtx := context.Background()
+	lock, err := redis.GetLocker().Obtain(lockCtx, "lock:task:archive-audit-logs", interval+time.Minute, &redislock.Options{
+		RetryStrategy: redislock.ExponentialBackoff(time.Second*5, time.Minute*10),
+	})
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := lock.Release(lockCtx); err != nil {
+			logrus.WithError(err).Error("ArchiveAuditLogs, failed to release lock")
+		}
+	}()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	logrus.Info("Task=ArchiveAuditLogs, starting now")
+
+	f := func() {
+		before := primitive.NewObjectIDFromTimestamp(time.Now().Add(-maxAge))
+		var archived int32
+		for ctx.Err() == nil {
+			archive, err := actions.Audit.Archive(ctx, before, batchSize)
+			if err != nil {
+				logrus.WithError(err).Error("ArchiveAuditLogs, could not archive entries")
+				break
+			}
+			if archive == nil {
+				break
+			}
+
+			archived += archive.Count
+			logrus.WithField("key", archive.Key).WithField("count", archive.Count).Info("Task=ArchiveAuditLogs, archived entries")
+			if int64(archive.Count) < batchSize {
+				break
+			}
+		}
+
+		logrus.WithField("count", archived).Info("Task=ArchiveAuditLogs, completed archival cycle")
+	}
+
+	f()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			if err := lock.Refresh(ctx, interval+time.Minute, &redislock.Options{}); err != nil {
+				logrus.WithError(err).Error("ArchiveAuditLogs, could not refresh lock")
+			}
+
+			f()
+		}
+	}
+}
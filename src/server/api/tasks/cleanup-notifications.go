@@ -0,0 +1,76 @@
+package tasks
+
+import (
+	"context"
+	"time"
+
+	"github.com/SevenTV/ServerGo/src/configure"
+	"github.com/SevenTV/ServerGo/src/redis"
+	"github.com/SevenTV/ServerGo/src/server/api/actions"
+	"github.com/bsm/redislock"
+	"github.com/sirupsen/logrus"
+)
+
+// Delete notifications which have expired, along with their read states
+func CleanupNotifications(ctx context.Context) error {
+	interval := configure.Config.GetDuration("notifications.cleanup.interval")
+	if interval <= 0 {
+		interval = time.Hour
+	}
+	batchSize := configure.Config.GetInt64("notifications.cleanup.batch_size")
+	if batchSize <= 0 {
+		batchSize = 1000
+	}
+
+	// Acquire lock. We won't allow any other pod to execute this concurrently
+	lockCtx := context.Background()
+	lock, err := redis.GetLocker().Obtain(lockCtx, "lock:task:cleanup-notifications", interval+time.Minute, &redislock.Options{
+		RetryStrategy: redislock.ExponentialBackoff(time.Second*5, time.Minute*10),
+	})
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := lock.Release(lockCtx); err != nil {
+			logrus.WithError(err).Error("CleanupNotifications, failed to release lock")
+		}
+	}()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	logrus.Info("Task=CleanupNotifications, starting now")
+
+	f := func() {
+		total := int64(0)
+		for ctx.Err() == nil {
+			deleted, err := actions.Notifications.DeleteExpired(ctx, batchSize)
+			if err != nil {
+				logrus.WithError(err).Error("CleanupNotifications, could not delete expired notifications")
+				break
+			}
+			total += deleted
+
+			// A partial batch means there is nothing left to delete
+			if deleted < batchSize {
+				break
+			}
+		}
+		if total > 0 {
+			logrus.WithField("count", total).Info("Task=CleanupNotifications, deleted expired notifications")
+		}
+	}
+
+	f()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			if err := lock.Refresh(ctx, interval+time.Minute, &redislock.Options{}); err != nil {
+				logrus.WithError(err).Error("CleanupNotifications, could not refresh lock")
+			}
+
+			f()
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package tasks
+
+import (
+	"context"
+	"time"
+
+	"github.com/SevenTV/ServerGo/src/configure"
+	"github.com/SevenTV/ServerGo/src/redis"
+	"github.com/SevenTV/ServerGo/src/server/api/actions"
+	"github.com/bsm/redislock"
+	"github.com/sirupsen/logrus"
+)
+
+// The time up to which entitlement schedules were processed, kept so that none are missed between runs
+const entitlementScheduleCheckedKey = "entitlements:schedule:checked_at"
+
+// The longest period processed after the task was not running, so that users aren't told about long past changes
+const entitlementScheduleMaxCatchUp = 24 * time.Hour
+
+// Let users know when their entitlements activate or expire, and clear what depends on them
+func ProcessEntitlementSchedules(ctx context.Context) error {
+	interval := configure.Config.GetDuration("entitlements.schedule_interval")
+	if interval <= 0 {
+		interval = time.Minute
+	}
+
+	// Acquire lock. We won't allow any other pod to execute this concurrently
+	lockCtx := context.Background()
+	lock, err := redis.GetLocker().Obtain(lockCtx, "lock:task:process-entitlement-schedules", interval+time.Minute, &redislock.Options{
+		RetryStrategy: redislock.ExponentialBackoff(time.Second*5, time.Minute*10),
+	})
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := lock.Release(lockCtx); err != nil {
+			logrus.WithError(err).Error("ProcessEntitlementSchedules, failed to release lock")
+		}
+	}()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	logrus.Info("Task=ProcessEntitlementSchedules, starting now")
+
+	f := func() {
+		to := time.Now()
+		from := to.Add(-interval)
+		if ms, err := redis.Client.Get(ctx, entitlementScheduleCheckedKey).Int64(); err == nil {
+			from = time.UnixMilli(ms)
+		} else if err != redis.ErrNil {
+			logrus.WithError(err).Error("redis")
+			return
+		}
+		if to.Sub(from) > entitlementScheduleMaxCatchUp {
+			from = to.Add(-entitlementScheduleMaxCatchUp)
+		}
+
+		count, err := actions.Entitlements.ProcessSchedule(ctx, from, to)
+		if err != nil {
+			logrus.WithError(err).Error("ProcessEntitlementSchedules, could not process entitlement schedules")
+			return
+		}
+		if count > 0 {
+			logrus.WithField("count", count).Info("Task=ProcessEntitlementSchedules, entitlements activated or expired")
+		}
+
+		if err := redis.Client.Set(ctx, entitlementScheduleCheckedKey, to.UnixMilli(), 0).Err(); err != nil {
+			logrus.WithError(err).Error("redis")
+		}
+	}
+
+	f()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			if err := lock.Refresh(ctx, interval+time.Minute, &redislock.Options{}); err != nil {
+				logrus.WithError(err).Error("ProcessEntitlementSchedules, could not refresh lock")
+			}
+
+			f()
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package tasks
+
+import (
+	"context"
+	"time"
+
+	"github.com/SevenTV/ServerGo/src/redis"
+	"github.com/SevenTV/ServerGo/src/server/api/actions"
+	"github.com/bsm/redislock"
+	"github.com/sirupsen/logrus"
+)
+
+// Send composed notifications once their scheduled time has passed
+func SendScheduledNotifications(ctx context.Context) error {
+	interval := time.Minute
+
+	// Acquire lock. We won't allow any other pod to execute this concurrently
+	lockCtx := context.Background()
+	lock, err := redis.GetLocker().Obtain(lockCtx, "lock:task:send-scheduled-notifications", interval*2, &redislock.Options{
+		RetryStrategy: redislock.ExponentialBackoff(time.Second*5, time.Minute*10),
+	})
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := lock.Release(lockCtx); err != nil {
+			logrus.WithError(err).Error("SendScheduledNotifications, failed to release lock")
+		}
+	}()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	logrus.Info("Task=SendScheduledNotifications, starting now")
+
+	f := func() {
+		sent, err := actions.Notifications.SendScheduled(ctx)
+		if err != nil {
+			logrus.WithError(err).Error("SendScheduledNotifications, could not send notifications")
+		}
+		if sent > 0 {
+			logrus.WithField("count", sent).Info("Task=SendScheduledNotifications, sent scheduled notifications")
+		}
+	}
+
+	f()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			if err := lock.Refresh(ctx, interval*2, &redislock.Options{}); err != nil {
+				logrus.WithError(err).Error("SendScheduledNotifications, could not refresh lock")
+			}
+
+			f()
+		}
+	}
+}
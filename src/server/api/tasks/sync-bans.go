@@ -0,0 +1,32 @@
+package tasks
+
+import (
+	"context"
+	"time"
+
+	"github.com/SevenTV/ServerGo/src/server/api/actions"
+	"github.com/sirupsen/logrus"
+)
+
+// Keep the in-memory ban list of this pod in sync with the rest of the cluster
+//
+// Ban events broadcasted by other pods are applied as they come in, and the list is periodically
+// rebuilt from the database to recover from missed events and to clear out expired bans
+func SyncBans(ctx context.Context) error {
+	go actions.Bans.Listen(ctx)
+
+	ticker := time.NewTicker(5 * time.Minute)
+	defer ticker.Stop()
+	logrus.Info("Task=SyncBans, starting now")
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			if err := actions.Bans.FetchBans(ctx); err != nil {
+				logrus.WithError(err).Error("SyncBans, could not fetch bans")
+			}
+		}
+	}
+}
@@ -15,6 +15,42 @@ func Start() {
 	taskCtx = ctx
 	taskCancelCtx = cancel
 
+	go func() {
+		if err := SyncBans(taskCtx); err != nil {
+			logrus.WithError(err).Error("failed to sync bans")
+		}
+	}()
+
+	go func() {
+		if err := ArchiveAuditLogs(taskCtx); err != nil {
+			logrus.WithError(err).Error("failed to archive audit logs")
+		}
+	}()
+
+	go func() {
+		if err := SendScheduledNotifications(taskCtx); err != nil {
+			logrus.WithError(err).Error("failed to send scheduled notifications")
+		}
+	}()
+
+	go func() {
+		if err := CleanupNotifications(taskCtx); err != nil {
+			logrus.WithError(err).Error("failed to clean up notifications")
+		}
+	}()
+
+	go func() {
+		if err := UpdateCosmeticsSnapshot(taskCtx); err != nil {
+			logrus.WithError(err).Error("failed to update cosmetics snapshot")
+		}
+	}()
+
+	go func() {
+		if err := ProcessEntitlementSchedules(taskCtx); err != nil {
+			logrus.WithError(err).Error("failed to process entitlement schedules")
+		}
+	}()
+
 	if err := CheckEmotesPopularity(taskCtx); err != nil {
 		logrus.WithError(err).Error("failed to check popularity")
 	}
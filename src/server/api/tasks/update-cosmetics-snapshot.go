@@ -0,0 +1,82 @@
+package tasks
+
+import (
+	"context"
+	"time"
+
+	"github.com/SevenTV/ServerGo/src/configure"
+	"github.com/SevenTV/ServerGo/src/redis"
+	"github.com/SevenTV/ServerGo/src/server/api/actions"
+	"github.com/bsm/redislock"
+	"github.com/sirupsen/logrus"
+)
+
+// Recompute the cosmetics snapshot whenever cosmetics or entitlements change, and periodically in case a change was missed
+func UpdateCosmeticsSnapshot(ctx context.Context) error {
+	interval := configure.Config.GetDuration("cosmetics.snapshot.interval")
+	if interval <= 0 {
+		interval = 10 * time.Minute
+	}
+	// Changes are batched, so that a burst of them only causes one recomputation
+	debounce := configure.Config.GetDuration("cosmetics.snapshot.debounce")
+	if debounce <= 0 {
+		debounce = 5 * time.Second
+	}
+
+	// Acquire lock. We won't allow any other pod to execute this concurrently
+	lockCtx := context.Background()
+	lock, err := redis.GetLocker().Obtain(lockCtx, "lock:task:update-cosmetics-snapshot", interval+time.Minute, &redislock.Options{
+		RetryStrategy: redislock.ExponentialBackoff(time.Second*5, time.Minute*10),
+	})
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := lock.Release(lockCtx); err != nil {
+			logrus.WithError(err).Error("UpdateCosmeticsSnapshot, failed to release lock")
+		}
+	}()
+
+	ch := make(chan []byte, 16)
+	actions.Cosmetics.SubscribeChanges(ctx, ch)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	timer := time.NewTimer(debounce)
+	defer timer.Stop()
+	pending := false
+	logrus.Info("Task=UpdateCosmeticsSnapshot, starting now")
+
+	f := func() {
+		pending = false
+		version, err := actions.Cosmetics.RefreshSnapshot(ctx)
+		if err != nil {
+			logrus.WithError(err).Error("UpdateCosmeticsSnapshot, could not refresh the snapshot")
+			return
+		}
+		logrus.WithField("version", version).Debug("Task=UpdateCosmeticsSnapshot, refreshed the snapshot")
+	}
+
+	f()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ch:
+			if !pending {
+				pending = true
+				timer.Reset(debounce)
+			}
+		case <-timer.C:
+			if pending {
+				f()
+			}
+		case <-ticker.C:
+			if err := lock.Refresh(ctx, interval+time.Minute, &redislock.Options{}); err != nil {
+				logrus.WithError(err).Error("UpdateCosmeticsSnapshot, could not refresh lock")
+			}
+
+			f()
+		}
+	}
+}
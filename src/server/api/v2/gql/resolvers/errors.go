@@ -2,6 +2,7 @@ package resolvers
 
 import (
 	"fmt"
+	"strings"
 )
 
 var (
@@ -15,11 +16,36 @@ var (
 	ErrUnknownChannel        = fmt.Errorf("Unknown Channel")
 	ErrUnknownUser           = fmt.Errorf("Unknown User")
 	ErrUnknownRole           = fmt.Errorf("Unknown Role")
+	ErrUnknownAppeal         = fmt.Errorf("Unknown Appeal")
 	ErrAccessDenied          = fmt.Errorf("Insufficient Privilege")
 	ErrUserBanned            = fmt.Errorf("User Is Banned")
 	ErrUserNotBanned         = fmt.Errorf("User Is Not Banned")
+	ErrUserRestricted        = fmt.Errorf("User Is Restricted From This Action")
 	ErrYourself              = fmt.Errorf("Don't Be Silly")
 	ErrNoReason              = fmt.Errorf("No Reason")
+	ErrInvalidExpireAt       = fmt.Errorf("Invalid Expiry Date")
+	ErrInvalidDate           = fmt.Errorf("Invalid Date")
+	ErrInvalidCursor         = fmt.Errorf("Invalid Cursor")
+	ErrInvalidTarget         = fmt.Errorf("Invalid Target")
+	ErrTooManyArchives       = fmt.Errorf("Too Many Archives In Range, Narrow The Filter")
+	ErrUnknownReport         = fmt.Errorf("Unknown Report")
+	ErrReportClosed          = fmt.Errorf("Report Is Already Closed")
+	ErrReportOpen            = fmt.Errorf("Report Is Already Open")
+	ErrInvalidNote           = fmt.Errorf("Invalid Note")
+	ErrInvalidEvidence       = fmt.Errorf("Invalid Evidence")
+	ErrNoAutoUnlist          = fmt.Errorf("Emote Was Not Automatically Unlisted")
+	ErrUnknownAuditEntry     = fmt.Errorf("Unknown Audit Entry")
+	ErrNotRevertable         = fmt.Errorf("Audit Entry Cannot Be Reverted")
+	ErrAlreadyReverted       = fmt.Errorf("Audit Entry Was Already Reverted")
+	ErrUnknownNotification   = fmt.Errorf("Unknown Notification")
+	ErrInvalidNotification   = fmt.Errorf("Invalid Notification")
+	ErrNotificationSent      = fmt.Errorf("Notification Was Already Sent")
+	ErrNotificationRetracted = fmt.Errorf("Notification Was Already Retracted")
+	ErrUnknownCosmetic       = fmt.Errorf("Unknown Cosmetic")
+	ErrUnknownEmoteSet       = fmt.Errorf("Unknown Emote Set")
+	ErrEmoteSetActive        = fmt.Errorf("The Active Emote Set Cannot Be Deleted")
+	ErrEmoteSetConflict      = fmt.Errorf("The Channel's Emotes Were Changed At The Same Time, Try Again")
+	ErrEmoteSetFull          = fmt.Errorf("Too Many Emotes For The Emote Set's Slots")
 	ErrInternalServer        = fmt.Errorf("Internal Server Error")
 	ErrDepth                 = fmt.Errorf("Max Depth Exceeded (%v)", MaxDepth)
 	ErrQueryLimit            = fmt.Errorf("Max Query Limit Exceeded (%v)", QueryLimit)
@@ -27,4 +53,16 @@ var (
 	ErrEmoteSlotLimitReached = func(count int32) error {
 		return fmt.Errorf("Channel Emote Slots Limit Reached (%d)", count)
 	}
+	ErrPersonalEmoteSlotLimitReached = func(count int32) error {
+		return fmt.Errorf("Personal Emote Slots Limit Reached (%d)", count)
+	}
+	ErrEmoteSetLimitReached = func(count int64) error {
+		return fmt.Errorf("Emote Set Limit Reached (%d)", count)
+	}
+	ErrInvalidPaint = func(reason string) error {
+		return fmt.Errorf("Invalid Paint (%s)", reason)
+	}
+	ErrRevertConflict = func(keys []string) error {
+		return fmt.Errorf("Changed Since The Audit Entry (%s)", strings.Join(keys, ", "))
+	}
 )
@@ -0,0 +1,462 @@
+package mutation_resolvers
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/SevenTV/ServerGo/src/mongo"
+	"github.com/SevenTV/ServerGo/src/mongo/datastructure"
+	"github.com/SevenTV/ServerGo/src/redis"
+	"github.com/SevenTV/ServerGo/src/server/api/actions"
+	"github.com/SevenTV/ServerGo/src/server/api/v2/gql/resolvers"
+	"github.com/SevenTV/ServerGo/src/utils"
+	"github.com/SevenTV/ServerGo/src/validation"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+//
+// REVERT AUDIT ENTRY
+//
+func (*MutationResolver) RevertAuditEntry(ctx context.Context, args struct {
+	ID     string
+	Reason *string
+}) (*response, error) {
+	usr, ok := ctx.Value(utils.UserKey).(*datastructure.User)
+	if !ok {
+		return nil, resolvers.ErrLoginRequired
+	}
+
+	id, err := primitive.ObjectIDFromHex(args.ID)
+	if err != nil {
+		return nil, resolvers.ErrUnknownAuditEntry
+	}
+
+	entry := &datastructure.AuditLog{}
+	res := mongo.Collection(mongo.CollectionNameAudit).FindOne(ctx, bson.M{
+		"_id": id,
+	})
+	err = res.Err()
+	if err == nil {
+		err = res.Decode(entry)
+	}
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, resolvers.ErrUnknownAuditEntry
+		}
+		logrus.WithError(err).Error("mongo")
+		return nil, resolvers.ErrInternalServer
+	}
+	if entry.Target == nil || entry.Target.ID == nil {
+		return nil, resolvers.ErrNotRevertable
+	}
+
+	count, err := mongo.Collection(mongo.CollectionNameAudit).CountDocuments(ctx, bson.M{
+		"revert_of": entry.ID,
+	})
+	if err != nil {
+		logrus.WithError(err).Error("mongo")
+		return nil, resolvers.ErrInternalServer
+	}
+	if count > 0 {
+		return nil, resolvers.ErrAlreadyReverted
+	}
+
+	var changes []*datastructure.AuditLogChange
+	switch entry.Type {
+	case datastructure.AuditLogTypeEmoteEdit:
+		changes, err = revertEmoteEdit(ctx, usr, entry, args.Reason)
+	case datastructure.AuditLogTypeUserEdit:
+		changes, err = revertUserEdit(ctx, usr, entry)
+	case datastructure.AuditLogTypeUserChannelEmoteEdit:
+		changes, err = revertChannelEmoteEdit(ctx, usr, entry)
+	default:
+		err = resolvers.ErrNotRevertable
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = mongo.Collection(mongo.CollectionNameAudit).InsertOne(ctx, &datastructure.AuditLog{
+		Type:      entry.Type,
+		CreatedBy: usr.ID,
+		Target:    entry.Target,
+		Changes:   changes,
+		Reason:    args.Reason,
+		RevertOf:  &entry.ID,
+	})
+	if err != nil {
+		logrus.WithError(err).Error("mongo")
+	}
+
+	return &response{
+		OK:      true,
+		Status:  200,
+		Message: "success",
+	}, nil
+}
+
+// revertEmoteEdit: Restore the name, tags, visibility and owner of an emote, notifying about it like any other edit
+func revertEmoteEdit(ctx context.Context, usr *datastructure.User, entry *datastructure.AuditLog, reason *string) ([]*datastructure.AuditLogChange, error) {
+	if !usr.HasPermission(datastructure.RolePermissionEmoteEditAll) {
+		return nil, resolvers.ErrAccessDenied
+	}
+
+	emote := &datastructure.Emote{}
+	res := mongo.Collection(mongo.CollectionNameEmotes).FindOne(ctx, bson.M{
+		"_id": entry.Target.ID,
+	})
+	err := res.Err()
+	if err == nil {
+		err = res.Decode(emote)
+	}
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, resolvers.ErrUnknownEmote
+		}
+		logrus.WithError(err).Error("mongo")
+		return nil, resolvers.ErrInternalServer
+	}
+
+	rv := newReverter()
+	for _, c := range entry.Changes {
+		switch c.Key {
+		case "name":
+			name, ok := c.OldValue.(string)
+			if !ok || !validation.ValidateEmoteName(utils.S2B(name)) {
+				return nil, resolvers.ErrNotRevertable
+			}
+			rv.set(c, emote.Name, name)
+		case "tags":
+			tags, ok := toStringSlice(c.OldValue)
+			if !ok {
+				return nil, resolvers.ErrNotRevertable
+			}
+			rv.set(c, emote.Tags, tags)
+		case "visibility":
+			visibility, ok := toInt32(c.OldValue)
+			if !ok {
+				return nil, resolvers.ErrNotRevertable
+			}
+			rv.set(c, emote.Visibility, visibility)
+		case "owner":
+			owner, ok := c.OldValue.(primitive.ObjectID)
+			if !ok {
+				return nil, resolvers.ErrNotRevertable
+			}
+			if err := mongo.Collection(mongo.CollectionNameUsers).FindOne(ctx, bson.M{"_id": owner}).Err(); err != nil {
+				if err == mongo.ErrNoDocuments {
+					return nil, resolvers.ErrInvalidOwner
+				}
+				logrus.WithError(err).Error("mongo")
+				return nil, resolvers.ErrInternalServer
+			}
+			rv.set(c, emote.OwnerID, owner)
+		}
+	}
+	if err := rv.check(); err != nil {
+		return nil, err
+	}
+
+	rv.update["$set"].(bson.M)["last_modified_date"] = time.Now()
+	if err := rv.apply(ctx, mongo.CollectionNameEmotes, emote.ID); err != nil {
+		return nil, err
+	}
+
+	oldVisibility := emote.Visibility
+	if err := mongo.Collection(mongo.CollectionNameEmotes).FindOne(ctx, bson.M{"_id": emote.ID}).Decode(emote); err != nil {
+		logrus.WithError(err).Error("mongo")
+		return rv.changes, nil
+	}
+	actions.Emotes.NotifyEdit(emote, oldVisibility, rv.changes, usr, reason)
+
+	return rv.changes, nil
+}
+
+// revertUserEdit: Restore the role and channel emote slots of a user
+func revertUserEdit(ctx context.Context, usr *datastructure.User, entry *datastructure.AuditLog) ([]*datastructure.AuditLogChange, error) {
+	if !usr.HasPermission(datastructure.RolePermissionManageUsers) {
+		return nil, resolvers.ErrAccessDenied
+	}
+
+	target := &datastructure.User{}
+	res := mongo.Collection(mongo.CollectionNameUsers).FindOne(ctx, bson.M{
+		"_id": entry.Target.ID,
+	})
+	err := res.Err()
+	if err == nil {
+		err = res.Decode(target)
+	}
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, resolvers.ErrUnknownUser
+		}
+		logrus.WithError(err).Error("mongo")
+		return nil, resolvers.ErrInternalServer
+	}
+	targetRole := datastructure.GetRole(target.RoleID)
+
+	rv := newReverter()
+	for _, c := range entry.Changes {
+		switch c.Key {
+		case "role":
+			// Same restrictions as editing the role directly
+			if !usr.HasPermission(datastructure.RolePermissionManageRoles) || usr.Role.Position <= targetRole.Position {
+				return nil, resolvers.ErrAccessDenied
+			}
+
+			var roleID *primitive.ObjectID
+			switch v := c.OldValue.(type) {
+			case nil:
+			case primitive.ObjectID:
+				role := datastructure.GetRole(&v)
+				if role.Default {
+					return nil, resolvers.ErrUnknownRole
+				}
+				if role.Position >= usr.Role.Position {
+					return nil, resolvers.ErrAccessDenied
+				}
+				roleID = &v
+			default:
+				return nil, resolvers.ErrNotRevertable
+			}
+			rv.set(c, target.RoleID, roleID)
+		case "emote_slots":
+			slots, ok := toInt32(c.OldValue)
+			if !ok {
+				return nil, resolvers.ErrNotRevertable
+			}
+			rv.set(c, target.EmoteSlots, slots)
+		}
+	}
+	if err := rv.check(); err != nil {
+		return nil, err
+	}
+
+	if err := rv.apply(ctx, mongo.CollectionNameUsers, target.ID); err != nil {
+		return nil, err
+	}
+
+	return rv.changes, nil
+}
+
+// revertChannelEmoteEdit: Restore the aliases of a channel's emotes
+func revertChannelEmoteEdit(ctx context.Context, usr *datastructure.User, entry *datastructure.AuditLog) ([]*datastructure.AuditLogChange, error) {
+	if !usr.HasPermission(datastructure.RolePermissionManageUsers) && usr.ID != *entry.Target.ID {
+		return nil, resolvers.ErrAccessDenied
+	}
+
+	channel := &datastructure.User{}
+	res := mongo.Collection(mongo.CollectionNameUsers).FindOne(ctx, bson.M{
+		"_id": entry.Target.ID,
+	})
+	err := res.Err()
+	if err == nil {
+		err = res.Decode(channel)
+	}
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, resolvers.ErrUnknownChannel
+		}
+		logrus.WithError(err).Error("mongo")
+		return nil, resolvers.ErrInternalServer
+	}
+
+	rv := newReverter()
+	emoteIDs := []string{}
+	for _, c := range entry.Changes {
+		// Entries made before the emote was recorded in the key can't be reverted
+		if !strings.HasPrefix(c.Key, "emote_alias.") {
+			continue
+		}
+		emoteID := strings.TrimPrefix(c.Key, "emote_alias.")
+		if !primitive.IsValidObjectID(emoteID) {
+			return nil, resolvers.ErrNotRevertable
+		}
+
+		// An empty alias means the emote had no alias
+		alias := ""
+		switch v := c.OldValue.(type) {
+		case nil:
+		case string:
+			alias = v
+		default:
+			return nil, resolvers.ErrNotRevertable
+		}
+		if alias != "" && !validation.ValidateEmoteName(utils.S2B(alias)) {
+			return nil, resolvers.ErrNotRevertable
+		}
+
+		current, exists := channel.EmoteAlias[emoteID]
+		newValue, _ := c.NewValue.(string)
+		if current != newValue {
+			rv.conflicts = append(rv.conflicts, c.Key)
+			continue
+		}
+
+		if exists {
+			rv.filter[c.Key] = current
+		} else {
+			rv.filter[c.Key] = bson.M{"$exists": false}
+		}
+		if alias == "" {
+			rv.update["$unset"].(bson.M)[c.Key] = ""
+		} else if len(channel.EmoteAlias) == 0 {
+			rv.update["$set"].(bson.M)["emote_alias"] = bson.M{emoteID: alias}
+		} else {
+			rv.update["$set"].(bson.M)[c.Key] = alias
+		}
+		rv.changes = append(rv.changes, &datastructure.AuditLogChange{
+			Key: c.Key, OldValue: current, NewValue: alias,
+		})
+		emoteIDs = append(emoteIDs, emoteID)
+	}
+	if err := rv.check(); err != nil {
+		return nil, err
+	}
+
+	if err := rv.apply(ctx, mongo.CollectionNameUsers, channel.ID); err != nil {
+		return nil, err
+	}
+
+	// Push events to redis
+	go func() {
+		for _, id := range emoteIDs {
+			_ = redis.Publish(context.Background(), fmt.Sprintf("users:%v:emotes", channel.Login), redis.PubSubPayloadUserEmotes{
+				Removed: false,
+				ID:      id,
+				Actor:   usr.DisplayName,
+			})
+		}
+	}()
+
+	return rv.changes, nil
+}
+
+// reverter collects the inverse of the changes of an audit log entry.
+// The update only applies if the reverted fields still hold the values set by the entry
+type reverter struct {
+	filter    bson.M
+	update    bson.M
+	changes   []*datastructure.AuditLogChange
+	conflicts []string
+}
+
+func newReverter() *reverter {
+	return &reverter{
+		filter: bson.M{},
+		update: bson.M{"$set": bson.M{}, "$unset": bson.M{}},
+	}
+}
+
+// set: Restore a field to its old value, or record a conflict if it changed since the entry
+func (rv *reverter) set(c *datastructure.AuditLogChange, current interface{}, old interface{}) {
+	if !auditValuesEqual(current, c.NewValue) {
+		rv.conflicts = append(rv.conflicts, c.Key)
+		return
+	}
+
+	rv.filter[c.Key] = current
+	rv.update["$set"].(bson.M)[c.Key] = old
+	rv.changes = append(rv.changes, &datastructure.AuditLogChange{
+		Key: c.Key, OldValue: current, NewValue: old,
+	})
+}
+
+func (rv *reverter) check() error {
+	if len(rv.conflicts) > 0 {
+		return resolvers.ErrRevertConflict(rv.conflicts)
+	}
+	if len(rv.changes) == 0 {
+		return resolvers.ErrNotRevertable
+	}
+	return nil
+}
+
+func (rv *reverter) apply(ctx context.Context, collection mongo.CollectionName, id primitive.ObjectID) error {
+	update := bson.M{}
+	for op, fields := range rv.update {
+		if len(fields.(bson.M)) > 0 {
+			update[op] = fields
+		}
+	}
+
+	rv.filter["_id"] = id
+	res, err := mongo.Collection(collection).UpdateOne(ctx, rv.filter, update)
+	if err != nil {
+		logrus.WithError(err).Error("mongo")
+		return resolvers.ErrInternalServer
+	}
+
+	// Changed concurrently since the values were checked
+	if res.MatchedCount == 0 {
+		keys := make([]string, len(rv.changes))
+		for i, c := range rv.changes {
+			keys[i] = c.Key
+		}
+		return resolvers.ErrRevertConflict(keys)
+	}
+
+	return nil
+}
+
+// auditValuesEqual: Compare a current value with a value decoded from an audit log entry
+func auditValuesEqual(a interface{}, b interface{}) bool {
+	return reflect.DeepEqual(normalizeAuditValue(a), normalizeAuditValue(b))
+}
+
+// normalizeAuditValue: Convert a value to the same type whether it was read from a document or an audit log entry
+func normalizeAuditValue(v interface{}) interface{} {
+	if i, ok := toInt32(v); ok {
+		return i
+	}
+	if s, ok := toStringSlice(v); ok && v != nil {
+		if len(s) == 0 {
+			return nil
+		}
+		return s
+	}
+	if id, ok := v.(*primitive.ObjectID); ok {
+		if id == nil {
+			return nil
+		}
+		return *id
+	}
+	return v
+}
+
+func toInt32(v interface{}) (int32, bool) {
+	switch i := v.(type) {
+	case int32:
+		return i, true
+	case int64:
+		return int32(i), true
+	case int:
+		return int32(i), true
+	}
+	return 0, false
+}
+
+func toStringSlice(v interface{}) ([]string, bool) {
+	switch a := v.(type) {
+	case nil:
+		return []string{}, true
+	case []string:
+		return a, true
+	case primitive.A:
+		s := make([]string, len(a))
+		for i, e := range a {
+			str, ok := e.(string)
+			if !ok {
+				return nil, false
+			}
+			s[i] = str
+		}
+		return s, true
+	}
+	return nil, false
+}
@@ -2,6 +2,8 @@ package mutation_resolvers
 
 import (
 	"context"
+	"fmt"
+	"strings"
 	"time"
 
 	"github.com/SevenTV/ServerGo/src/mongo"
@@ -21,6 +23,8 @@ func (*MutationResolver) BanUser(ctx context.Context, args struct {
 	VictimID string
 	ExpireAt *string
 	Reason   *string
+	Type     *datastructure.BanType
+	Cascade  *string
 }) (*response, error) {
 	usr, ok := ctx.Value(utils.UserKey).(*datastructure.User)
 	if !ok {
@@ -43,20 +47,25 @@ func (*MutationResolver) BanUser(ctx context.Context, args struct {
 		return nil, resolvers.ErrYourself
 	}
 
+	banType := datastructure.BanTypeFull
+	if args.Type != nil {
+		banType = *args.Type
+	}
+
 	// Check if ban already exists on victim
-	banned, _ := actions.Bans.IsUserBanned(id)
+	banned, _ := actions.Bans.IsUserRestricted(id, banType)
 	if banned {
 		return nil, resolvers.ErrUserBanned
 	}
 
 	// Find user
-	res := mongo.Collection(mongo.CollectionNameUsers).FindOne(ctx, bson.M{
+	userRes := mongo.Collection(mongo.CollectionNameUsers).FindOne(ctx, bson.M{
 		"_id": id,
 	})
 	user := &datastructure.User{}
-	err = res.Err()
+	err = userRes.Err()
 	if err == nil {
-		err = res.Decode(user)
+		err = userRes.Decode(user)
 		role := datastructure.GetRole(user.RoleID)
 		user.Role = &role
 	}
@@ -79,9 +88,14 @@ func (*MutationResolver) BanUser(ctx context.Context, args struct {
 		reasonN = *args.Reason
 	}
 
-	expireAt := time.Time{}
+	// A ban without an expiry date is permanent
+	var expireAt *time.Time
 	if args.ExpireAt != nil {
-		expireAt, _ = time.Parse("2006-01-02T15:04:05.999Z07:00", *args.ExpireAt)
+		t, err := time.Parse("2006-01-02T15:04:05.999Z07:00", *args.ExpireAt)
+		if err != nil {
+			return nil, resolvers.ErrInvalidExpireAt
+		}
+		expireAt = &t
 	}
 
 	ban := &datastructure.Ban{
@@ -89,33 +103,78 @@ func (*MutationResolver) BanUser(ctx context.Context, args struct {
 		Reason:     reasonN,
 		IssuedByID: &usr.ID,
 		ExpireAt:   expireAt,
+		Type:       banType,
 	}
 
-	_, err = mongo.Collection(mongo.CollectionNameBans).InsertOne(ctx, ban)
+	res, err := mongo.Collection(mongo.CollectionNameBans).InsertOne(ctx, ban)
 	if err != nil {
 		logrus.Errorf("mongo, err=%v", err)
 		return nil, resolvers.ErrInternalServer
 	}
+	ban.ID, _ = res.InsertedID.(primitive.ObjectID)
 
-	actions.Bans.Mtx.Lock()
-	actions.Bans.BannedUsers[id] = ban
-	actions.Bans.Mtx.Unlock()
+	actions.Bans.Add(ctx, ban)
 	_, err = mongo.Collection(mongo.CollectionNameAudit).InsertOne(ctx, &datastructure.AuditLog{
 		Type:      datastructure.AuditLogTypeUserBan,
 		CreatedBy: usr.ID,
 		Target:    &datastructure.Target{ID: &id, Type: "users"},
-		Changes:   nil,
-		Reason:    args.Reason,
+		Changes: []*datastructure.AuditLogChange{
+			{Key: "type", OldValue: nil, NewValue: banType},
+			{Key: "expire_at", OldValue: nil, NewValue: expireAt},
+		},
+		Reason: args.Reason,
 	})
 
 	if err != nil {
 		logrus.Errorf("mongo, err=%v", err)
 	}
 
+	// Let the user know they were banned
+	notification := actions.Notifications.Create().
+		SetTitle(utils.Ternary(banType == datastructure.BanTypeFull, "You Were Banned", "You Were Restricted").(string)).
+		SetCategory(datastructure.NotificationCategoryModeration).
+		SetDedupKey(fmt.Sprintf("ban:%s:%s", id.Hex(), banType), actions.NotificationDedupWindow).
+		AddTargetUsers(id)
+	if banType == datastructure.BanTypeFull {
+		notification = notification.AddTextMessagePart("You were banned by ")
+	} else {
+		notification = notification.AddTextMessagePart(fmt.Sprintf("You were restricted from %s actions by ", banType))
+	}
+	notification = notification.AddUserMentionPart(usr.ID)
+	if expireAt != nil {
+		notification = notification.AddTextMessagePart(fmt.Sprintf(" until %s", expireAt.Format(time.RFC3339)))
+	}
+	notification = notification.AddTextMessagePart(fmt.Sprintf(" for the reason \"%v\".", reasonN))
+
+	go func() {
+		if err := notification.Write(context.Background()); err != nil {
+			logrus.WithError(err).Error("failed to create notification")
+		}
+	}()
+
+	// Clean up the victim's emotes and editor privileges
+	var jobID *string
+	if args.Cascade != nil {
+		job, err := actions.Bans.Cascade(ctx, actions.BanCascadeOptions{
+			Victim: id,
+			Actor:  usr.ID,
+			Mode:   actions.BanCascadeMode(*args.Cascade),
+			Reason: args.Reason,
+		})
+		if err != nil {
+			logrus.WithError(err).Error("mongo")
+			return nil, resolvers.ErrInternalServer
+		}
+
+		hex := job.Job.ID.Hex()
+		jobID = &hex
+	}
+
 	return &response{
 		OK:      true,
 		Status:  200,
 		Message: "success",
+		JobID:   jobID,
 	}, nil
 }
 
@@ -126,6 +185,7 @@ func (*MutationResolver) BanUser(ctx context.Context, args struct {
 func (*MutationResolver) UnbanUser(ctx context.Context, args struct {
 	VictimID string
 	Reason   *string
+	Type     *datastructure.BanType
 }) (*response, error) {
 	usr, ok := ctx.Value(utils.UserKey).(*datastructure.User)
 	if !ok {
@@ -145,8 +205,14 @@ func (*MutationResolver) UnbanUser(ctx context.Context, args struct {
 		return nil, resolvers.ErrYourself
 	}
 
-	banned, _ := actions.Bans.IsUserBanned(id)
-	if !banned {
+	// Find the restrictions to lift
+	types := []datastructure.BanType{}
+	for _, ban := range actions.Bans.GetRestrictions(id) {
+		if args.Type == nil || ban.GetType() == *args.Type {
+			types = append(types, ban.GetType())
+		}
+	}
+	if len(types) == 0 {
 		return nil, resolvers.ErrUserNotBanned
 	}
 
@@ -170,33 +236,231 @@ func (*MutationResolver) UnbanUser(ctx context.Context, args struct {
 		return nil, resolvers.ErrInternalServer
 	}
 
-	_, err = mongo.Collection(mongo.CollectionNameBans).UpdateMany(ctx, bson.M{
-		"user_id":   user.ID,
-		"expire_at": bson.M{"$not": bson.M{"$eq": time.Time{}}},
-	}, bson.M{
+	// Expire the user's active bans
+	filter := bson.M{
+		"user_id": user.ID,
+		"$or": bson.A{
+			bson.M{"expire_at": nil},
+			bson.M{"expire_at": bson.M{"$gt": time.Now()}},
+		},
+	}
+	if args.Type != nil {
+		if *args.Type == datastructure.BanTypeFull {
+			filter["type"] = bson.M{"$in": bson.A{datastructure.BanTypeFull, nil}}
+		} else {
+			filter["type"] = *args.Type
+		}
+	}
+	_, err = mongo.Collection(mongo.CollectionNameBans).UpdateMany(ctx, filter, bson.M{
 		"$set": bson.M{
-			"expire_at": time.Time{},
+			"expire_at": time.Now(),
 		},
 	})
 	if err != nil {
 		logrus.Errorf("mongo, err=%v", err)
 		return nil, resolvers.ErrInternalServer
 	}
-	actions.Bans.Mtx.Lock()
-	delete(actions.Bans.BannedUsers, id)
-	actions.Bans.Mtx.Unlock()
+
+	if args.Type != nil {
+		actions.Bans.Remove(ctx, id, *args.Type)
+	} else {
+		actions.Bans.Remove(ctx, id)
+	}
 	_, err = mongo.Collection(mongo.CollectionNameAudit).InsertOne(ctx, &datastructure.AuditLog{
 		Type:      datastructure.AuditLogTypeUserUnban,
 		CreatedBy: usr.ID,
 		Target:    &datastructure.Target{ID: &id, Type: "users"},
-		Changes:   nil,
-		Reason:    args.Reason,
+		Changes: []*datastructure.AuditLogChange{
+			{Key: "types", OldValue: types, NewValue: nil},
+		},
+		Reason: args.Reason,
 	})
 
 	if err != nil {
 		logrus.Errorf("mongo, err=%v", err)
 	}
 
+	// Let the user know their restrictions were lifted
+	lifted := make([]string, len(types))
+	for i, t := range types {
+		lifted[i] = string(t)
+	}
+	notification := actions.Notifications.Create().
+		SetTitle("Ban Lifted").
+		SetCategory(datastructure.NotificationCategoryModeration).
+		SetDedupKey(fmt.Sprintf("unban:%s:%s", id.Hex(), strings.Join(lifted, ",")), actions.NotificationDedupWindow).
+		AddTargetUsers(id).
+		AddTextMessagePart(fmt.Sprintf("Your restrictions (%s) were lifted by ", strings.Join(lifted, ", "))).
+		AddUserMentionPart(usr.ID).
+		AddTextMessagePart(".")
+
+	go func() {
+		if err := notification.Write(context.Background()); err != nil {
+			logrus.WithError(err).Error("failed to create notification")
+		}
+	}()
+
+	return &response{
+		OK:      true,
+		Status:  200,
+		Message: "success",
+	}, nil
+}
+
+//
+// REVIEW BAN APPEAL
+//
+
+func (*MutationResolver) ReviewBanAppeal(ctx context.Context, args struct {
+	AppealID string
+	Action   string
+	ExpireAt *string
+	Note     *string
+}) (*response, error) {
+	usr, ok := ctx.Value(utils.UserKey).(*datastructure.User)
+	if !ok {
+		return nil, resolvers.ErrLoginRequired
+	}
+
+	if !usr.HasPermission(datastructure.RolePermissionBanUsers) {
+		return nil, resolvers.ErrAccessDenied
+	}
+
+	id, err := primitive.ObjectIDFromHex(args.AppealID)
+	if err != nil {
+		return nil, resolvers.ErrUnknownAppeal
+	}
+
+	// Find the appeal
+	appeal := &datastructure.BanAppeal{}
+	res := mongo.Collection(mongo.CollectionNameBanAppeals).FindOne(ctx, bson.M{
+		"_id":    id,
+		"status": datastructure.BanAppealStatusPending,
+	})
+	err = res.Err()
+	if err == nil {
+		err = res.Decode(appeal)
+	}
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, resolvers.ErrUnknownAppeal
+		}
+		logrus.WithError(err).Error("mongo")
+		return nil, resolvers.ErrInternalServer
+	}
+
+	if appeal.UserID == usr.ID {
+		return nil, resolvers.ErrYourself
+	}
+
+	// Find the appealed ban
+	ban := &datastructure.Ban{}
+	res = mongo.Collection(mongo.CollectionNameBans).FindOne(ctx, bson.M{
+		"_id": appeal.BanID,
+	})
+	err = res.Err()
+	if err == nil {
+		err = res.Decode(ban)
+	}
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, resolvers.ErrUserNotBanned
+		}
+		logrus.WithError(err).Error("mongo")
+		return nil, resolvers.ErrInternalServer
+	}
+	if !ban.IsActive() {
+		return nil, resolvers.ErrUserNotBanned
+	}
+
+	oldExpireAt := ban.ExpireAt
+	var status datastructure.BanAppealStatus
+	var outcome string
+	switch args.Action {
+	case "ACCEPT":
+		now := time.Now()
+		ban.ExpireAt = &now
+		status = datastructure.BanAppealStatusAccepted
+		outcome = "accepted, and your ban has been lifted"
+	case "REDUCE":
+		if args.ExpireAt == nil {
+			return nil, resolvers.ErrInvalidExpireAt
+		}
+		t, err := time.Parse("2006-01-02T15:04:05.999Z07:00", *args.ExpireAt)
+		if err != nil || t.Before(time.Now()) || (oldExpireAt != nil && !t.Before(*oldExpireAt)) {
+			return nil, resolvers.ErrInvalidExpireAt
+		}
+		ban.ExpireAt = &t
+		status = datastructure.BanAppealStatusReduced
+		outcome = fmt.Sprintf("reviewed, and your ban has been reduced to end on %v", t.Format("Mon, 02 Jan 2006 15:04:05 MST"))
+	case "DENY":
+		status = datastructure.BanAppealStatusDenied
+		outcome = "denied"
+	default:
+		return nil, resolvers.ErrInvalidUpdate
+	}
+
+	// Update the ban
+	if ban.ExpireAt != oldExpireAt {
+		if _, err = mongo.Collection(mongo.CollectionNameBans).UpdateByID(ctx, ban.ID, bson.M{
+			"$set": bson.M{
+				"expire_at": ban.ExpireAt,
+			},
+		}); err != nil {
+			logrus.WithError(err).Error("mongo")
+			return nil, resolvers.ErrInternalServer
+		}
+
+		if ban.IsActive() {
+			actions.Bans.Add(ctx, ban)
+		} else {
+			actions.Bans.Remove(ctx, appeal.UserID, ban.GetType())
+		}
+	}
+
+	// Close the appeal
+	now := time.Now()
+	if _, err = mongo.Collection(mongo.CollectionNameBanAppeals).UpdateByID(ctx, appeal.ID, bson.M{
+		"$set": bson.M{
+			"status":      status,
+			"reviewer_id": usr.ID,
+			"review_note": args.Note,
+			"reviewed_at": now,
+		},
+	}); err != nil {
+		logrus.WithError(err).Error("mongo")
+		return nil, resolvers.ErrInternalServer
+	}
+
+	_, err = mongo.Collection(mongo.CollectionNameAudit).InsertOne(ctx, &datastructure.AuditLog{
+		Type:      datastructure.AuditLogTypeUserBanAppealReview,
+		CreatedBy: usr.ID,
+		Target:    &datastructure.Target{ID: &appeal.UserID, Type: "users"},
+		Changes: []*datastructure.AuditLogChange{
+			{Key: "status", OldValue: appeal.Status, NewValue: status},
+			{Key: "expire_at", OldValue: oldExpireAt, NewValue: ban.ExpireAt},
+		},
+	})
+	if err != nil {
+		logrus.WithError(err).Error("mongo")
+	}
+
+	// Let the user know about the outcome
+	notification := actions.Notifications.Create().
+		SetTitle("Ban Appeal Reviewed").
+		SetCategory(datastructure.NotificationCategoryModeration).
+		AddTargetUsers(appeal.UserID).
+		AddTextMessagePart(fmt.Sprintf("Your appeal was %v.", outcome))
+	if args.Note != nil && *args.Note != "" {
+		notification = notification.AddTextMessagePart(fmt.Sprintf(" Note from the moderator: \"%v\"", *args.Note))
+	}
+
+	go func() {
+		if err := notification.Write(context.Background()); err != nil {
+			logrus.WithError(err).Error("failed to create notification")
+		}
+	}()
+
 	return &response{
 		OK:      true,
 		Status:  200,
@@ -53,6 +53,11 @@ func (*MutationResolver) AddChannelEditor(ctx context.Context, args struct {
 		return nil, resolvers.ErrUserBanned
 	}
 
+	// Users restricted from editing can't be added as editors
+	if restricted, _ := actions.Bans.IsUserRestricted(editorID, datastructure.BanTypeEditor); restricted {
+		return nil, resolvers.ErrUserRestricted
+	}
+
 	res := mongo.Collection(mongo.CollectionNameUsers).FindOne(ctx, bson.M{
 		"_id": channelID,
 	})
@@ -115,6 +120,8 @@ func (*MutationResolver) AddChannelEditor(ctx context.Context, args struct {
 	if err != nil {
 		logrus.WithError(err).Error("mongo")
 	}
+
+	go sendChannelEditorNotifications(usr, channelID, editorID, true)
 	return query_resolvers.GenerateUserResolver(ctx, newChannel, &newChannel.ID, field.Children)
 }
 
@@ -209,5 +216,45 @@ func (*MutationResolver) RemoveChannelEditor(ctx context.Context, args struct {
 		logrus.WithError(err).Error("mongo")
 	}
 
+	go sendChannelEditorNotifications(usr, channelID, editorID, false)
 	return query_resolvers.GenerateUserResolver(ctx, newChannel, &newChannel.ID, field.Children)
 }
+
+// sendChannelEditorNotifications: Let the editor, and the channel owner if someone else made the change,
+// know that the editor was added to or removed from the channel
+func sendChannelEditorNotifications(actor *datastructure.User, channelID, editorID primitive.ObjectID, added bool) {
+	title := utils.Ternary(added, "Added As Channel Editor", "Removed As Channel Editor").(string)
+	action := utils.Ternary(added, "add", "remove").(string)
+	dedupKey := fmt.Sprintf("channel-editor-%s:%s:%s", action, channelID.Hex(), editorID.Hex())
+
+	notifications := []actions.NotificationBuilder{}
+	if actor.ID != editorID {
+		notifications = append(notifications, actions.Notifications.Create().
+			SetTitle(title).
+			SetCategory(datastructure.NotificationCategoryChannel).
+			SetDedupKey(dedupKey, actions.NotificationDedupWindow).
+			AddTargetUsers(editorID).
+			AddTextMessagePart(utils.Ternary(added, "You were added as an editor of ", "You were removed as an editor of ").(string)).
+			AddUserMentionPart(channelID).
+			AddTextMessagePart(" by ").
+			AddUserMentionPart(actor.ID),
+		)
+	}
+	if actor.ID != channelID {
+		notifications = append(notifications, actions.Notifications.Create().
+			SetTitle(utils.Ternary(added, "Channel Editor Added", "Channel Editor Removed").(string)).
+			SetCategory(datastructure.NotificationCategoryChannel).
+			SetDedupKey(dedupKey, actions.NotificationDedupWindow).
+			AddTargetUsers(channelID).
+			AddUserMentionPart(editorID).
+			AddTextMessagePart(utils.Ternary(added, " was added as an editor of your channel by ", " was removed as an editor of your channel by ").(string)).
+			AddUserMentionPart(actor.ID),
+		)
+	}
+
+	for _, n := range notifications {
+		if err := n.Write(context.Background()); err != nil {
+			logrus.WithError(err).Error("failed to create notification")
+		}
+	}
+}
@@ -68,8 +68,8 @@ func (*MutationResolver) AddChannelEmote(ctx context.Context, args struct {
 			}
 		}
 
-		if (len(channel.EmoteIDs) + 1) > int(channel.GetEmoteSlots()) {
-			return nil, resolvers.ErrEmoteSlotLimitReached(channel.GetEmoteSlots())
+		if slots := channelUB.GetChannelEmoteSlots(); (len(channel.EmoteIDs) + 1) > int(slots) {
+			return nil, resolvers.ErrEmoteSlotLimitReached(slots)
 		}
 	}
 
@@ -102,20 +102,9 @@ func (*MutationResolver) AddChannelEmote(ctx context.Context, args struct {
 		return nil, resolvers.ErrInternalServer
 	}
 
-	sharedWith := []string{}
-	for _, v := range emote.SharedWith {
-		sharedWith = append(sharedWith, v.Hex())
-	}
-
-	// Emote is global: can only be added if is owner or shared
-	if utils.BitField.HasBits(int64(emote.Visibility), int64(datastructure.EmoteVisibilityPrivate)) {
-		if emote.OwnerID.Hex() != channelID.Hex() && !utils.Contains(sharedWith, emoteID.Hex()) {
-			return nil, resolvers.ErrUnknownEmote
-		}
-	}
-	// User tries to add a zero-width emote but lacks permission
-	if utils.BitField.HasBits(int64(emote.Visibility), int64(datastructure.EmoteVisibilityZeroWidth)) && !channel.HasPermission(datastructure.RolePermissionUseZeroWidthEmote) {
-		return nil, resolvers.ErrAccessDenied
+	// The channel must be allowed to use the emote
+	if err := actions.Emotes.CanUse(emote, channel); err != nil {
+		return nil, emoteUsageError(err)
 	}
 
 	emoteIDs := append(channel.EmoteIDs, emoteID)
@@ -308,7 +297,7 @@ func (*MutationResolver) EditChannelEmote(ctx context.Context, args struct {
 		}
 
 		logChanges = append(logChanges, &datastructure.AuditLogChange{
-			Key: "emote_alias", OldValue: channel.EmoteAlias[emoteID.Hex()], NewValue: alias,
+			Key: fmt.Sprintf("emote_alias.%v", emoteID.Hex()), OldValue: channel.EmoteAlias[emoteID.Hex()], NewValue: alias,
 		})
 	}
 
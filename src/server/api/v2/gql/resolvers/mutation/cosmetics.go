@@ -0,0 +1,549 @@
+package mutation_resolvers
+
+import (
+	"bytes"
+	"context"
+	"sort"
+	"strings"
+
+	"github.com/SevenTV/ServerGo/src/mongo"
+	"github.com/SevenTV/ServerGo/src/mongo/datastructure"
+	"github.com/SevenTV/ServerGo/src/server/api/actions"
+	"github.com/SevenTV/ServerGo/src/server/api/v2/gql/resolvers"
+	query_resolvers "github.com/SevenTV/ServerGo/src/server/api/v2/gql/resolvers/query"
+	"github.com/SevenTV/ServerGo/src/utils"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	MAX_COSMETIC_NAME_LENGTH = 64
+	MAX_BADGE_TOOLTIP_LENGTH = 100
+)
+
+//
+// CREATE BADGE
+//
+func (*MutationResolver) CreateBadge(ctx context.Context, args struct {
+	Data badgeInput
+}) (*query_resolvers.CosmeticResolver, error) {
+	usr, err := getCosmeticsManager(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if args.Data.Name == nil {
+		return nil, resolvers.ErrInvalidName
+	}
+
+	cos := &datastructure.Cosmetic{
+		ID:      primitive.NewObjectID(),
+		Kind:    datastructure.CosmeticKindBadge,
+		UserIDs: []primitive.ObjectID{},
+	}
+	badge := &datastructure.CosmeticDataBadge{}
+	if args.Data.Tooltip == nil {
+		badge.Tooltip = strings.TrimSpace(*args.Data.Name)
+	}
+	if err := applyBadgeInput(cos, badge, args.Data); err != nil {
+		return nil, err
+	}
+
+	return createCosmetic(ctx, usr, cos, badge)
+}
+
+//
+// EDIT BADGE
+//
+func (*MutationResolver) EditBadge(ctx context.Context, args struct {
+	ID     string
+	Data   badgeInput
+	Reason *string
+}) (*query_resolvers.CosmeticResolver, error) {
+	usr, cos, err := getManagedCosmetic(ctx, args.ID, datastructure.CosmeticKindBadge)
+	if err != nil {
+		return nil, err
+	}
+
+	old := *cos
+	badge := cos.ReadBadge()
+	if err := applyBadgeInput(cos, badge, args.Data); err != nil {
+		return nil, err
+	}
+
+	return editCosmetic(ctx, usr, &old, cos, badge, args.Reason)
+}
+
+//
+// CREATE PAINT
+//
+func (*MutationResolver) CreatePaint(ctx context.Context, args struct {
+	Data paintInput
+}) (*query_resolvers.CosmeticResolver, error) {
+	usr, err := getCosmeticsManager(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if args.Data.Name == nil {
+		return nil, resolvers.ErrInvalidName
+	}
+	if args.Data.Function == nil {
+		return nil, resolvers.ErrInvalidPaint("missing function")
+	}
+
+	cos := &datastructure.Cosmetic{
+		ID:      primitive.NewObjectID(),
+		Kind:    datastructure.CosmeticKindNametagPaint,
+		UserIDs: []primitive.ObjectID{},
+	}
+	paint := &datastructure.CosmeticDataPaint{
+		Stops:       []datastructure.CosmeticPaintGradientStop{},
+		DropShadows: []datastructure.CosmeticPaintDropShadow{},
+	}
+	if err := applyPaintInput(cos, paint, args.Data); err != nil {
+		return nil, err
+	}
+
+	return createCosmetic(ctx, usr, cos, paint)
+}
+
+//
+// EDIT PAINT
+//
+func (*MutationResolver) EditPaint(ctx context.Context, args struct {
+	ID     string
+	Data   paintInput
+	Reason *string
+}) (*query_resolvers.CosmeticResolver, error) {
+	usr, cos, err := getManagedCosmetic(ctx, args.ID, datastructure.CosmeticKindNametagPaint)
+	if err != nil {
+		return nil, err
+	}
+
+	old := *cos
+	paint := cos.ReadPaint()
+	if err := applyPaintInput(cos, paint, args.Data); err != nil {
+		return nil, err
+	}
+
+	return editCosmetic(ctx, usr, &old, cos, paint, args.Reason)
+}
+
+//
+// REPRIORITIZE COSMETICS
+//
+func (*MutationResolver) ReprioritizeCosmetics(ctx context.Context, args struct {
+	IDs    []string
+	Reason *string
+}) (*response, error) {
+	usr, err := getCosmeticsManager(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := []primitive.ObjectID{}
+	for _, s := range args.IDs {
+		id, err := primitive.ObjectIDFromHex(s)
+		if err != nil {
+			return nil, resolvers.ErrUnknownCosmetic
+		}
+		if utils.ContainsObjectID(ids, id) {
+			return nil, resolvers.ErrInvalidUpdate
+		}
+		ids = append(ids, id)
+	}
+	if len(ids) < 2 {
+		return nil, resolvers.ErrInvalidUpdate
+	}
+
+	cur, err := mongo.Collection(mongo.CollectionNameCosmetics).Find(ctx, bson.M{
+		"_id": bson.M{"$in": ids},
+	})
+	if err != nil {
+		logrus.WithError(err).Error("mongo")
+		return nil, resolvers.ErrInternalServer
+	}
+	cosmetics := []*datastructure.Cosmetic{}
+	if err := cur.All(ctx, &cosmetics); err != nil {
+		logrus.WithError(err).Error("mongo")
+		return nil, resolvers.ErrInternalServer
+	}
+	if len(cosmetics) != len(ids) {
+		return nil, resolvers.ErrUnknownCosmetic
+	}
+
+	byID := make(map[primitive.ObjectID]*datastructure.Cosmetic, len(cosmetics))
+	priorities := make([]int, len(cosmetics))
+	for i, cos := range cosmetics {
+		// Badges and paints are displayed separately, so there is no order between them
+		if cos.Kind != cosmetics[0].Kind {
+			return nil, resolvers.ErrInvalidUpdate
+		}
+		byID[cos.ID] = cos
+		priorities[i] = cos.Priority
+	}
+
+	// The cosmetics take over their current priorities in the requested order.
+	// Equal priorities are raised, so that the order is strict
+	sort.Sort(sort.Reverse(sort.IntSlice(priorities)))
+	for i := len(priorities) - 2; i >= 0; i-- {
+		if priorities[i] <= priorities[i+1] {
+			priorities[i] = priorities[i+1] + 1
+		}
+	}
+
+	updates := []mongo.WriteModel{}
+	logs := []interface{}{}
+	for i, id := range ids {
+		cos := byID[id]
+		if cos.Priority == priorities[i] {
+			continue
+		}
+
+		id := id
+		updates = append(updates, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"_id": id}).
+			SetUpdate(bson.M{"$set": bson.M{"priority": priorities[i]}}),
+		)
+		logs = append(logs, &datastructure.AuditLog{
+			Type:      datastructure.AuditLogTypeCosmeticReprioritize,
+			CreatedBy: usr.ID,
+			Target:    &datastructure.Target{ID: &id, Type: "cosmetics"},
+			Changes: []*datastructure.AuditLogChange{
+				{Key: "priority", OldValue: cos.Priority, NewValue: priorities[i]},
+			},
+			Reason: args.Reason,
+		})
+	}
+	if len(updates) == 0 {
+		return &response{
+			OK:      true,
+			Status:  200,
+			Message: "no change",
+		}, nil
+	}
+
+	if _, err := mongo.Collection(mongo.CollectionNameCosmetics).BulkWrite(ctx, updates); err != nil {
+		logrus.WithError(err).Error("mongo")
+		return nil, resolvers.ErrInternalServer
+	}
+	if _, err := mongo.Collection(mongo.CollectionNameAudit).InsertMany(ctx, logs); err != nil {
+		logrus.WithError(err).Error("mongo")
+	}
+
+	// Users may now display another of their cosmetics
+	actions.Cosmetics.InvalidateAll(ctx)
+
+	return &response{
+		OK:      true,
+		Status:  200,
+		Message: "success",
+	}, nil
+}
+
+//
+// DELETE COSMETIC
+//
+func (*MutationResolver) DeleteCosmetic(ctx context.Context, args struct {
+	ID     string
+	Reason *string
+}) (*response, error) {
+	usr, cos, err := getManagedCosmetic(ctx, args.ID, "")
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := mongo.Collection(mongo.CollectionNameCosmetics).DeleteOne(ctx, bson.M{
+		"_id": cos.ID,
+	}); err != nil {
+		logrus.WithError(err).Error("mongo")
+		return nil, resolvers.ErrInternalServer
+	}
+
+	// Revoke the cosmetic from everyone it was granted to
+	res, err := mongo.Collection(mongo.CollectionNameEntitlements).DeleteMany(ctx, bson.M{
+		"kind":     string(cos.Kind),
+		"data.ref": cos.ID,
+	})
+	if err != nil {
+		logrus.WithError(err).Error("mongo")
+		return nil, resolvers.ErrInternalServer
+	}
+	actions.Cosmetics.InvalidateAll(ctx)
+
+	_, err = mongo.Collection(mongo.CollectionNameAudit).InsertOne(ctx, &datastructure.AuditLog{
+		Type:      datastructure.AuditLogTypeCosmeticDelete,
+		CreatedBy: usr.ID,
+		Target:    &datastructure.Target{ID: &cos.ID, Type: "cosmetics"},
+		Changes: []*datastructure.AuditLogChange{
+			{Key: "kind", OldValue: cos.Kind, NewValue: nil},
+			{Key: "name", OldValue: cos.Name, NewValue: nil},
+			{Key: "priority", OldValue: cos.Priority, NewValue: nil},
+			{Key: "data", OldValue: cos.Data, NewValue: nil},
+			{Key: "user_ids", OldValue: cos.UserIDs, NewValue: nil},
+			{Key: "entitlements", OldValue: res.DeletedCount, NewValue: 0},
+		},
+		Reason: args.Reason,
+	})
+	if err != nil {
+		logrus.WithError(err).Error("mongo")
+	}
+
+	return &response{
+		OK:      true,
+		Status:  200,
+		Message: "success",
+	}, nil
+}
+
+// getCosmeticsManager: Get the actor, verifying they are allowed to manage cosmetics
+func getCosmeticsManager(ctx context.Context) (*datastructure.User, error) {
+	usr, ok := ctx.Value(utils.UserKey).(*datastructure.User)
+	if !ok {
+		return nil, resolvers.ErrLoginRequired
+	}
+	if !usr.HasPermission(datastructure.RolePermissionManageCosmetics) {
+		return nil, resolvers.ErrAccessDenied
+	}
+
+	return usr, nil
+}
+
+// getManagedCosmetic: Get the actor and the cosmetic they are acting on,
+// verifying they are allowed to manage cosmetics. An empty kind matches any cosmetic
+func getManagedCosmetic(ctx context.Context, cosmeticID string, kind datastructure.CosmeticKind) (*datastructure.User, *datastructure.Cosmetic, error) {
+	usr, err := getCosmeticsManager(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	id, err := primitive.ObjectIDFromHex(cosmeticID)
+	if err != nil {
+		return nil, nil, resolvers.ErrUnknownCosmetic
+	}
+
+	filter := bson.M{"_id": id}
+	if kind != "" {
+		filter["kind"] = kind
+	}
+	cos := &datastructure.Cosmetic{}
+	res := mongo.Collection(mongo.CollectionNameCosmetics).FindOne(ctx, filter)
+	err = res.Err()
+	if err == nil {
+		err = res.Decode(cos)
+	}
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil, resolvers.ErrUnknownCosmetic
+		}
+		logrus.WithError(err).Error("mongo")
+		return nil, nil, resolvers.ErrInternalServer
+	}
+
+	return usr, cos, nil
+}
+
+// createCosmetic: Insert a new cosmetic with the given badge or paint data
+func createCosmetic(ctx context.Context, usr *datastructure.User, cos *datastructure.Cosmetic, data interface{}) (*query_resolvers.CosmeticResolver, error) {
+	raw, err := bson.Marshal(data)
+	if err != nil {
+		logrus.WithError(err).Error("bson")
+		return nil, resolvers.ErrInternalServer
+	}
+	cos.Data = raw
+
+	if _, err := mongo.Collection(mongo.CollectionNameCosmetics).InsertOne(ctx, cos); err != nil {
+		logrus.WithError(err).Error("mongo")
+		return nil, resolvers.ErrInternalServer
+	}
+
+	_, err = mongo.Collection(mongo.CollectionNameAudit).InsertOne(ctx, &datastructure.AuditLog{
+		Type:      datastructure.AuditLogTypeCosmeticCreate,
+		CreatedBy: usr.ID,
+		Target:    &datastructure.Target{ID: &cos.ID, Type: "cosmetics"},
+		Changes: []*datastructure.AuditLogChange{
+			{Key: "kind", OldValue: nil, NewValue: cos.Kind},
+			{Key: "name", OldValue: nil, NewValue: cos.Name},
+			{Key: "priority", OldValue: nil, NewValue: cos.Priority},
+			{Key: "data", OldValue: nil, NewValue: cos.Data},
+		},
+	})
+	if err != nil {
+		logrus.WithError(err).Error("mongo")
+	}
+
+	field, failed := query_resolvers.GenerateSelectedFieldMap(ctx, resolvers.MaxDepth)
+	if failed {
+		return nil, resolvers.ErrDepth
+	}
+
+	return query_resolvers.GenerateCosmeticResolver(ctx, cos, field.Children), nil
+}
+
+// editCosmetic: Write the edited name, priority and badge or paint data of a cosmetic
+func editCosmetic(
+	ctx context.Context, usr *datastructure.User,
+	old *datastructure.Cosmetic, cos *datastructure.Cosmetic, data interface{},
+	reason *string,
+) (*query_resolvers.CosmeticResolver, error) {
+	raw, err := bson.Marshal(data)
+	if err != nil {
+		logrus.WithError(err).Error("bson")
+		return nil, resolvers.ErrInternalServer
+	}
+	cos.Data = raw
+
+	changes := []*datastructure.AuditLogChange{}
+	if old.Name != cos.Name {
+		changes = append(changes, &datastructure.AuditLogChange{Key: "name", OldValue: old.Name, NewValue: cos.Name})
+	}
+	if old.Priority != cos.Priority {
+		changes = append(changes, &datastructure.AuditLogChange{Key: "priority", OldValue: old.Priority, NewValue: cos.Priority})
+	}
+	if !bytes.Equal(old.Data, cos.Data) {
+		changes = append(changes, &datastructure.AuditLogChange{Key: "data", OldValue: old.Data, NewValue: cos.Data})
+	}
+
+	if len(changes) > 0 {
+		if _, err := mongo.Collection(mongo.CollectionNameCosmetics).UpdateOne(ctx, bson.M{
+			"_id": cos.ID,
+		}, bson.M{
+			"$set": bson.M{
+				"name":     cos.Name,
+				"priority": cos.Priority,
+				"data":     cos.Data,
+			},
+		}); err != nil {
+			logrus.WithError(err).Error("mongo")
+			return nil, resolvers.ErrInternalServer
+		}
+		if old.Priority != cos.Priority {
+			actions.Cosmetics.InvalidateAll(ctx)
+		} else {
+			actions.Cosmetics.NotifyChanged(ctx)
+		}
+
+		_, err = mongo.Collection(mongo.CollectionNameAudit).InsertOne(ctx, &datastructure.AuditLog{
+			Type:      datastructure.AuditLogTypeCosmeticEdit,
+			CreatedBy: usr.ID,
+			Target:    &datastructure.Target{ID: &cos.ID, Type: "cosmetics"},
+			Changes:   changes,
+			Reason:    reason,
+		})
+		if err != nil {
+			logrus.WithError(err).Error("mongo")
+		}
+	}
+
+	field, failed := query_resolvers.GenerateSelectedFieldMap(ctx, resolvers.MaxDepth)
+	if failed {
+		return nil, resolvers.ErrDepth
+	}
+
+	return query_resolvers.GenerateCosmeticResolver(ctx, cos, field.Children), nil
+}
+
+// applyCosmeticInput: Validate and apply the fields shared by badges and paints
+func applyCosmeticInput(cos *datastructure.Cosmetic, name *string, priority *int32) error {
+	if name != nil {
+		n := strings.TrimSpace(*name)
+		if n == "" || len(n) > MAX_COSMETIC_NAME_LENGTH {
+			return resolvers.ErrInvalidName
+		}
+		cos.Name = n
+	}
+
+	if priority != nil {
+		cos.Priority = int(*priority)
+	}
+
+	return nil
+}
+
+// applyBadgeInput: Validate the input and apply it to the badge
+func applyBadgeInput(cos *datastructure.Cosmetic, badge *datastructure.CosmeticDataBadge, input badgeInput) error {
+	if err := applyCosmeticInput(cos, input.Name, input.Priority); err != nil {
+		return err
+	}
+
+	if input.Tooltip != nil {
+		tooltip := strings.TrimSpace(*input.Tooltip)
+		if tooltip == "" || len(tooltip) > MAX_BADGE_TOOLTIP_LENGTH {
+			return resolvers.ErrInvalidUpdate
+		}
+		badge.Tooltip = tooltip
+	}
+
+	if input.Misc != nil {
+		badge.Misc = *input.Misc
+	}
+
+	return nil
+}
+
+// applyPaintInput: Apply the input to the paint, then validate the result
+func applyPaintInput(cos *datastructure.Cosmetic, paint *datastructure.CosmeticDataPaint, input paintInput) error {
+	if err := applyCosmeticInput(cos, input.Name, input.Priority); err != nil {
+		return err
+	}
+
+	if input.Function != nil {
+		paint.Function = datastructure.CosmeticPaintFunction(*input.Function)
+	}
+	if input.Color != nil {
+		color := *input.Color
+		paint.Color = &color
+	}
+	if input.Stops != nil {
+		paint.Stops = make([]datastructure.CosmeticPaintGradientStop, len(*input.Stops))
+		for i, s := range *input.Stops {
+			paint.Stops[i] = datastructure.CosmeticPaintGradientStop{At: s.At, Color: s.Color}
+		}
+	}
+	if input.Repeat != nil {
+		paint.Repeat = *input.Repeat
+	}
+	if input.Angle != nil {
+		paint.Angle = *input.Angle
+	}
+	if input.Shape != nil {
+		paint.Shape = strings.TrimSpace(*input.Shape)
+	}
+	if input.ImageURL != nil {
+		paint.ImageURL = strings.TrimSpace(*input.ImageURL)
+	}
+	if input.DropShadows != nil {
+		paint.DropShadows = make([]datastructure.CosmeticPaintDropShadow, len(*input.DropShadows))
+		for i, s := range *input.DropShadows {
+			paint.DropShadows[i] = datastructure.CosmeticPaintDropShadow{
+				OffsetX: s.XOffset,
+				OffsetY: s.YOffset,
+				Radius:  s.Radius,
+				Color:   s.Color,
+			}
+		}
+	}
+	if input.Animation != nil {
+		paint.Animation = datastructure.CosmeticPaintAnimation{}
+		if len(input.Animation.Keyframes) > 0 {
+			paint.Animation.Speed = input.Animation.Speed
+			paint.Animation.Keyframes = make([]datastructure.CosmeticPaintAnimationKeyframe, len(input.Animation.Keyframes))
+			for i, k := range input.Animation.Keyframes {
+				paint.Animation.Keyframes[i] = datastructure.CosmeticPaintAnimationKeyframe{At: k.At, X: k.X, Y: k.Y}
+			}
+		}
+	}
+
+	// Drop the properties which don't apply to the paint's function
+	if paint.Function != datastructure.CosmeticPaintFunctionRadialGradient {
+		paint.Shape = ""
+	}
+	if paint.Function != datastructure.CosmeticPaintFunctionImageURL {
+		paint.ImageURL = ""
+	}
+
+	if err := datastructure.CosmeticUtil.ValidatePaint(paint); err != nil {
+		return resolvers.ErrInvalidPaint(err.Error())
+	}
+	return nil
+}
@@ -99,6 +99,8 @@ func (*MutationResolver) DeleteEmote(ctx context.Context, args struct {
 	if usr.ID.Hex() != emote.OwnerID.Hex() {
 		notification := actions.Notifications.Create().
 			SetTitle("Emote Deleted").
+			SetCategory(datastructure.NotificationCategoryEmote).
+			SetDedupKey(fmt.Sprintf("emote-delete:%s", emote.ID.Hex()), actions.NotificationDedupWindow).
 			AddTargetUsers(emote.OwnerID).
 			AddTextMessagePart("Your emote ").
 			AddEmoteMentionPart(emote.ID).
@@ -3,10 +3,8 @@ package mutation_resolvers
 import (
 	"context"
 	"fmt"
-	"time"
 
 	"github.com/SevenTV/ServerGo/src/configure"
-	"github.com/SevenTV/ServerGo/src/discord"
 	"github.com/SevenTV/ServerGo/src/mongo"
 	"github.com/SevenTV/ServerGo/src/mongo/datastructure"
 	"github.com/SevenTV/ServerGo/src/server/api/actions"
@@ -17,7 +15,6 @@ import (
 	"github.com/sirupsen/logrus"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
-	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 //
@@ -173,66 +170,9 @@ func (*MutationResolver) EditEmote(ctx context.Context, args struct {
 	}
 
 	if len(logChanges) > 0 {
-		update["last_modified_date"] = time.Now()
-
-		oldVisibility := emote.Visibility
-		after := options.After
-		doc := mongo.Collection(mongo.CollectionNameEmotes).FindOneAndUpdate(ctx, bson.M{
-			"_id": id,
-		}, bson.M{
-			"$set": update,
-		}, &options.FindOneAndUpdateOptions{
-			ReturnDocument: &after,
-		})
-		if err := doc.Decode(emote); err != nil {
-			return nil, err
-		}
-
-		err = doc.Err()
-		if err != nil {
-			logrus.WithError(err).WithField("id", id).Error("mongo")
+		if err := actions.Emotes.Edit(ctx, emote, update, logChanges, usr, args.Reason); err != nil {
 			return nil, resolvers.ErrInternalServer
 		}
-
-		_, err = mongo.Collection(mongo.CollectionNameAudit).InsertOne(ctx, &datastructure.AuditLog{
-			Type:      datastructure.AuditLogTypeEmoteEdit,
-			CreatedBy: usr.ID,
-			Target:    &datastructure.Target{ID: &id, Type: "emotes"},
-			Changes:   logChanges,
-			Reason:    args.Reason,
-		})
-
-		if err != nil {
-			logrus.WithError(err).Error("mongo")
-		}
-
-		// Send a notification to the emote owner if another user removed the UNLISTED flag
-		if usr.ID.Hex() != emote.OwnerID.Hex() {
-			wasUnlisted := utils.BitField.HasBits(int64(oldVisibility), int64(datastructure.EmoteVisibilityUnlisted)) &&
-				!utils.BitField.HasBits(int64(emote.Visibility), int64(datastructure.EmoteVisibilityUnlisted))
-
-			if req.Visibility != nil && wasUnlisted {
-				notification := actions.Notifications.Create().
-					SetTitle("Emote Approved").
-					AddTargetUsers(emote.OwnerID).
-					AddTextMessagePart("Your emote ").
-					AddEmoteMentionPart(emote.ID).
-					AddTextMessagePart("was approved by ").
-					AddUserMentionPart(usr.ID).
-					AddTextMessagePart("!")
-
-				go func() {
-					// Send the notification
-					if err := notification.Write(context.Background()); err != nil {
-						logrus.WithError(err).Error("failed to create notification")
-					}
-				}()
-			}
-
-		}
-
-		go discord.SendEmoteEdit(*emote, *usr, logChanges, args.Reason)
-		return query_resolvers.GenerateEmoteResolver(ctx, emote, &emote.ID, field.Children)
 	}
 
 	return query_resolvers.GenerateEmoteResolver(ctx, emote, &emote.ID, field.Children)
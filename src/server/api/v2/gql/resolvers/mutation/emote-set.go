@@ -0,0 +1,244 @@
+package mutation_resolvers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/SevenTV/ServerGo/src/mongo"
+	"github.com/SevenTV/ServerGo/src/mongo/datastructure"
+	"github.com/SevenTV/ServerGo/src/server/api/actions"
+	"github.com/SevenTV/ServerGo/src/server/api/v2/gql/resolvers"
+	query_resolvers "github.com/SevenTV/ServerGo/src/server/api/v2/gql/resolvers/query"
+	"github.com/SevenTV/ServerGo/src/utils"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// The longest name an emote set can have
+const maxEmoteSetNameLength = 32
+
+//
+// CREATE EMOTE SET
+//
+func (*MutationResolver) CreateEmoteSet(ctx context.Context, args struct {
+	ChannelID string
+	Name      string
+	Slots     *int32
+	Reason    *string
+}) (*query_resolvers.EmoteSetResolver, error) {
+	usr, channelUB, err := getEditableChannel(ctx, args.ChannelID)
+	if err != nil {
+		return nil, err
+	}
+
+	name, err := validateEmoteSetName(args.Name)
+	if err != nil {
+		return nil, err
+	}
+	opts := actions.CreateEmoteSetOptions{
+		Channel: &channelUB.User,
+		Name:    name,
+		Actor:   usr,
+		Reason:  args.Reason,
+	}
+	if args.Slots != nil {
+		if *args.Slots <= 0 {
+			return nil, fmt.Errorf("slots must be positive")
+		}
+		opts.Slots = *args.Slots
+	}
+
+	set, err := actions.EmoteSets.Create(ctx, opts)
+	if err != nil {
+		return nil, emoteSetError(err)
+	}
+
+	return generateEmoteSetResolver(ctx, set, channelUB)
+}
+
+//
+// COPY EMOTE SET
+//
+func (*MutationResolver) CopyEmoteSet(ctx context.Context, args struct {
+	ID        string
+	ChannelID string
+	Name      *string
+	Reason    *string
+}) (*query_resolvers.EmoteSetResolver, error) {
+	usr, channelUB, err := getEditableChannel(ctx, args.ChannelID)
+	if err != nil {
+		return nil, err
+	}
+
+	setID, err := primitive.ObjectIDFromHex(args.ID)
+	if err != nil {
+		return nil, resolvers.ErrUnknownEmoteSet
+	}
+	source, err := actions.EmoteSets.Get(ctx, setID)
+	if err != nil {
+		return nil, emoteSetError(err)
+	}
+	if banned, _ := actions.Bans.IsUserBanned(source.OwnerID); banned {
+		return nil, resolvers.ErrUnknownEmoteSet
+	}
+
+	name := source.Name
+	if args.Name != nil {
+		if name, err = validateEmoteSetName(*args.Name); err != nil {
+			return nil, err
+		}
+	}
+
+	set, err := actions.EmoteSets.Copy(ctx, source, actions.CreateEmoteSetOptions{
+		Channel: &channelUB.User,
+		Name:    name,
+		Actor:   usr,
+		Reason:  args.Reason,
+	})
+	if err != nil {
+		return nil, emoteSetError(err)
+	}
+
+	return generateEmoteSetResolver(ctx, set, channelUB)
+}
+
+//
+// ACTIVATE EMOTE SET
+//
+func (*MutationResolver) ActivateEmoteSet(ctx context.Context, args struct {
+	ID     string
+	Reason *string
+}) (*query_resolvers.UserResolver, error) {
+	usr, set, channelUB, err := getEditableEmoteSet(ctx, args.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	field, failed := query_resolvers.GenerateSelectedFieldMap(ctx, resolvers.MaxDepth)
+	if failed {
+		return nil, resolvers.ErrDepth
+	}
+
+	channel, err := actions.EmoteSets.Activate(ctx, &channelUB.User, set, usr, args.Reason)
+	if err != nil {
+		return nil, emoteSetError(err)
+	}
+
+	return query_resolvers.GenerateUserResolver(ctx, channel, &channel.ID, field.Children)
+}
+
+//
+// DELETE EMOTE SET
+//
+func (*MutationResolver) DeleteEmoteSet(ctx context.Context, args struct {
+	ID     string
+	Reason *string
+}) (*response, error) {
+	usr, set, channelUB, err := getEditableEmoteSet(ctx, args.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := actions.EmoteSets.Delete(ctx, set, &channelUB.User, usr, args.Reason); err != nil {
+		return nil, emoteSetError(err)
+	}
+
+	return &response{
+		OK:      true,
+		Status:  200,
+		Message: "success",
+	}, nil
+}
+
+// getEditableChannel: Get the actor and a channel whose emotes they may change,
+// which are their own channel, the channels they are an editor of, or any channel with permission
+func getEditableChannel(ctx context.Context, channelID string) (*datastructure.User, *actions.UserBuilder, error) {
+	usr, ok := ctx.Value(utils.UserKey).(*datastructure.User)
+	if !ok {
+		return nil, nil, resolvers.ErrLoginRequired
+	}
+
+	id, err := primitive.ObjectIDFromHex(channelID)
+	if err != nil {
+		return nil, nil, resolvers.ErrUnknownChannel
+	}
+	if banned, _ := actions.Bans.IsUserBanned(id); banned {
+		return nil, nil, resolvers.ErrUserBanned
+	}
+
+	channelUB, err := actions.Users.GetByID(ctx, id)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil, resolvers.ErrUnknownChannel
+		}
+		logrus.WithError(err).Error("mongo")
+		return nil, nil, resolvers.ErrInternalServer
+	}
+
+	if channelUB.User.ID != usr.ID && !utils.ContainsObjectID(channelUB.User.EditorIDs, usr.ID) && !usr.HasPermission(datastructure.RolePermissionManageUsers) {
+		return nil, nil, resolvers.ErrAccessDenied
+	}
+
+	return usr, channelUB, nil
+}
+
+// getEditableEmoteSet: Get the actor, an emote set and the channel it belongs to, verifying the actor may change the channel's emotes
+func getEditableEmoteSet(ctx context.Context, id string) (*datastructure.User, *datastructure.EmoteSet, *actions.UserBuilder, error) {
+	if _, ok := ctx.Value(utils.UserKey).(*datastructure.User); !ok {
+		return nil, nil, nil, resolvers.ErrLoginRequired
+	}
+
+	setID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, nil, nil, resolvers.ErrUnknownEmoteSet
+	}
+	set, err := actions.EmoteSets.Get(ctx, setID)
+	if err != nil {
+		return nil, nil, nil, emoteSetError(err)
+	}
+
+	usr, channelUB, err := getEditableChannel(ctx, set.OwnerID.Hex())
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	return usr, set, channelUB, nil
+}
+
+// generateEmoteSetResolver: Resolve an emote set of a channel
+func generateEmoteSetResolver(ctx context.Context, set *datastructure.EmoteSet, channelUB *actions.UserBuilder) (*query_resolvers.EmoteSetResolver, error) {
+	field, failed := query_resolvers.GenerateSelectedFieldMap(ctx, resolvers.MaxDepth)
+	if failed {
+		return nil, resolvers.ErrDepth
+	}
+
+	slots := actions.EmoteSets.GetSlots(set, channelUB.GetEmoteSlots())
+	return query_resolvers.GenerateEmoteSetResolver(ctx, set, actions.EmoteSets.IsActive(set, &channelUB.User), slots, field.Children), nil
+}
+
+func validateEmoteSetName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || len(name) > maxEmoteSetNameLength {
+		return "", resolvers.ErrInvalidName
+	}
+	return name, nil
+}
+
+// emoteSetError: Get the error returned to the client for an error of an emote set action
+func emoteSetError(err error) error {
+	switch err {
+	case actions.ErrUnknownEmoteSet:
+		return resolvers.ErrUnknownEmoteSet
+	case actions.ErrEmoteSetLimitReached:
+		return resolvers.ErrEmoteSetLimitReached(actions.EmoteSets.GetMaxSets())
+	case actions.ErrEmoteSetActive:
+		return resolvers.ErrEmoteSetActive
+	case actions.ErrEmoteSetConflict:
+		return resolvers.ErrEmoteSetConflict
+	case actions.ErrEmoteSetFull:
+		return resolvers.ErrEmoteSetFull
+	}
+	logrus.WithError(err).Error("emote sets")
+	return resolvers.ErrInternalServer
+}
@@ -0,0 +1,177 @@
+package mutation_resolvers
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/SevenTV/ServerGo/src/mongo"
+	"github.com/SevenTV/ServerGo/src/mongo/datastructure"
+	"github.com/SevenTV/ServerGo/src/server/api/actions"
+	"github.com/SevenTV/ServerGo/src/server/api/v2/gql/resolvers"
+	"github.com/SevenTV/ServerGo/src/utils"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// The most users which can be listed explicitly in a bulk entitlement job
+const maxBulkEntitlementUserIDs = 10000
+
+type bulkEntitlementResult struct {
+	Matched  *int32  `json:"matched"`
+	Affected *int32  `json:"affected"`
+	JobID    *string `json:"job_id"`
+}
+
+//
+// BULK ENTITLEMENTS
+//
+func (*MutationResolver) BulkEntitlements(ctx context.Context, args struct {
+	Action   string
+	Kind     datastructure.EntitlementKind
+	Data     entitlementCreateInput
+	Users    bulkEntitlementSelectorInput
+	DryRun   *bool
+	StartsAt *string
+	EndsAt   *string
+	Reason   *string
+}) (*bulkEntitlementResult, error) {
+	// Get actor reference
+	actor, ok := ctx.Value(utils.UserKey).(*datastructure.User)
+	if !ok {
+		return nil, resolvers.ErrLoginRequired
+	}
+
+	// Verify actor's permission
+	if !actor.HasPermission(datastructure.RolePermissionManageEntitlements) {
+		return nil, resolvers.ErrAccessDenied
+	}
+
+	opts := actions.BulkEntitlementOptions{
+		Action: actions.BulkEntitlementAction(args.Action),
+		Actor:  actor.ID,
+		Reason: args.Reason,
+	}
+
+	// Parse the selection of users
+	if args.Users.UserIDs != nil {
+		if len(*args.Users.UserIDs) > maxBulkEntitlementUserIDs {
+			return nil, fmt.Errorf("too many user ids (max %d)", maxBulkEntitlementUserIDs)
+		}
+		opts.UserIDs = make([]primitive.ObjectID, len(*args.Users.UserIDs))
+		for i, s := range *args.Users.UserIDs {
+			id, err := primitive.ObjectIDFromHex(s)
+			if err != nil {
+				return nil, resolvers.ErrUnknownUser
+			}
+			opts.UserIDs[i] = id
+		}
+	}
+	if args.Users.RoleID != nil {
+		id, err := primitive.ObjectIDFromHex(*args.Users.RoleID)
+		if err != nil {
+			return nil, resolvers.ErrUnknownRole
+		}
+		opts.RoleID = &id
+	}
+	if args.Users.EmoteID != nil {
+		id, err := primitive.ObjectIDFromHex(*args.Users.EmoteID)
+		if err != nil {
+			return nil, resolvers.ErrUnknownEmote
+		}
+		opts.EmoteID = &id
+	}
+	if opts.UserIDs == nil && opts.RoleID == nil && opts.EmoteID == nil {
+		return nil, fmt.Errorf("no users selected")
+	}
+
+	// Build the entitlement each user is granted, or which is revoked from them
+	builder := actions.Entitlements.Create(ctx).SetKind(args.Kind)
+	var err error
+	if builder, err = setEntitlementPeriod(builder, args.StartsAt, args.EndsAt); err != nil {
+		return nil, err
+	}
+	switch args.Kind {
+	case datastructure.EntitlementKindBadge, datastructure.EntitlementKindPaint:
+		id, selected, roleBindingID := "", false, (*string)(nil)
+		if args.Kind == datastructure.EntitlementKindBadge && args.Data.Badge != nil {
+			id, selected, roleBindingID = args.Data.Badge.ID, args.Data.Badge.Selected, args.Data.Badge.RoleBindingID
+		} else if args.Kind == datastructure.EntitlementKindPaint && args.Data.Paint != nil {
+			id, selected, roleBindingID = args.Data.Paint.ID, args.Data.Paint.Selected, args.Data.Paint.RoleBindingID
+		} else {
+			return nil, fmt.Errorf("missing %s data", args.Kind)
+		}
+
+		itemID, err := primitive.ObjectIDFromHex(id)
+		if err != nil {
+			return nil, resolvers.ErrUnknownCosmetic
+		}
+		count, err := mongo.Collection(mongo.CollectionNameCosmetics).CountDocuments(ctx, bson.M{"_id": itemID, "kind": args.Kind})
+		if err != nil {
+			logrus.WithError(err).Error("mongo")
+			return nil, resolvers.ErrInternalServer
+		}
+		if count == 0 {
+			return nil, resolvers.ErrUnknownCosmetic
+		}
+
+		var roleBinding primitive.ObjectID
+		if roleBindingID != nil && primitive.IsValidObjectID(*roleBindingID) {
+			roleBinding, _ = primitive.ObjectIDFromHex(*roleBindingID)
+		}
+		if args.Kind == datastructure.EntitlementKindBadge {
+			builder = builder.SetBadgeData(datastructure.EntitledBadge{ObjectReference: itemID, Selected: selected, RoleBinding: &roleBinding})
+		} else {
+			builder = builder.SetPaintData(datastructure.EntitledPaint{ObjectReference: itemID, Selected: selected, RoleBinding: &roleBinding})
+		}
+	case datastructure.EntitlementKindRole:
+		if args.Data.Role == nil {
+			return nil, fmt.Errorf("missing role data")
+		}
+		itemID, err := primitive.ObjectIDFromHex(args.Data.Role.ID)
+		if err != nil {
+			return nil, resolvers.ErrUnknownRole
+		}
+		count, err := mongo.Collection(mongo.CollectionNameRoles).CountDocuments(ctx, bson.M{"_id": itemID})
+		if err != nil {
+			logrus.WithError(err).Error("mongo")
+			return nil, resolvers.ErrInternalServer
+		}
+		if count == 0 {
+			return nil, resolvers.ErrUnknownRole
+		}
+
+		builder = builder.SetRoleData(datastructure.EntitledRole{ObjectReference: itemID})
+	default:
+		return nil, fmt.Errorf("bulk jobs only support BADGE, PAINT and ROLE entitlements")
+	}
+	opts.Entitlement = builder.Entitlement
+
+	// A dry run only counts the users the job would affect
+	if args.DryRun != nil && *args.DryRun {
+		count, err := actions.Entitlements.CountBulk(ctx, opts)
+		if err != nil {
+			logrus.WithError(err).Error("mongo")
+			return nil, resolvers.ErrInternalServer
+		}
+
+		return &bulkEntitlementResult{
+			Matched:  &count.Matched,
+			Affected: &count.Affected,
+		}, nil
+	}
+
+	job, err := actions.Entitlements.ApplyBulk(ctx, opts)
+	if err != nil {
+		if err == actions.ErrBulkEntitlementRunning {
+			return nil, err
+		}
+		logrus.WithError(err).Error("bulk entitlements")
+		return nil, resolvers.ErrInternalServer
+	}
+
+	hex := job.Job.ID.Hex()
+	return &bulkEntitlementResult{
+		JobID: &hex,
+	}, nil
+}
@@ -3,6 +3,7 @@ package mutation_resolvers
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/SevenTV/ServerGo/src/configure"
 	"github.com/SevenTV/ServerGo/src/mongo"
@@ -40,12 +41,16 @@ func (*MutationResolver) DeleteEntitlement(ctx context.Context, args struct {
 	}
 
 	// Delete the entitlement
-	if _, err = mongo.Collection(mongo.CollectionNameEntitlements).DeleteOne(ctx, bson.M{
+	entitlement := &datastructure.Entitlement{}
+	if err = mongo.Collection(mongo.CollectionNameEntitlements).FindOneAndDelete(ctx, bson.M{
 		"_id": eID,
-	}); err != nil {
+	}).Decode(entitlement); err != nil && err != mongo.ErrNoDocuments {
 		logrus.WithError(err).Error("mongo")
 		return nil, resolvers.ErrInternalServer
 	}
+	if err == nil {
+		actions.Cosmetics.InvalidateUsers(ctx, entitlement.UserID)
+	}
 
 	return &response{
 		OK:      true,
@@ -59,6 +64,8 @@ func (*MutationResolver) CreateEntitlement(ctx context.Context, args struct {
 	Data     entitlementCreateInput
 	UserID   string
 	Disabled *bool
+	StartsAt *string
+	EndsAt   *string
 }) (*response, error) {
 	// Get actor reference
 	actor, ok := ctx.Value(utils.UserKey).(*datastructure.User)
@@ -82,8 +89,14 @@ func (*MutationResolver) CreateEntitlement(ctx context.Context, args struct {
 		SetKind(args.Kind).
 		SetUserID(userID)
 
+	// Parse the time the entitlement is active for, if limited
+	if builder, err = setEntitlementPeriod(builder, args.StartsAt, args.EndsAt); err != nil {
+		return nil, err
+	}
+
 	// Initiate a new notification to be sent to the entitled user
 	notify := actions.Notifications.Create().
+		SetCategory(datastructure.NotificationCategoryAccount).
 		AddTargetUsers(userID)
 
 	// Assign typed data based on kind
@@ -195,6 +208,66 @@ func (*MutationResolver) CreateEntitlement(ctx context.Context, args struct {
 		notify = notify.SetTitle("Global Role Granted").
 			AddTextMessagePart("You've been granted the role").
 			AddRoleMentionPart(role.ID)
+	case datastructure.EntitlementKindEmoteSet:
+		if args.Data.EmoteSet == nil {
+			return nil, fmt.Errorf("missing emote set data")
+		}
+
+		set := datastructure.EntitledEmoteSet{
+			UnicodeTag: args.Data.EmoteSet.UnicodeTag,
+			EmoteIDs:   []primitive.ObjectID{},
+		}
+		if args.Data.EmoteSet.Slots != nil {
+			if *args.Data.EmoteSet.Slots <= 0 {
+				return nil, fmt.Errorf("emote set slots must be positive")
+			}
+			set.Slots = *args.Data.EmoteSet.Slots
+		}
+
+		// The set may be granted with emotes already in it
+		slots := actions.Entitlements.GetPersonalEmoteSlots(set)
+		for _, id := range args.Data.EmoteSet.EmoteIDs {
+			emoteID, err := primitive.ObjectIDFromHex(id)
+			if err != nil {
+				return nil, resolvers.ErrUnknownEmote
+			}
+			if !utils.ContainsObjectID(set.EmoteIDs, emoteID) {
+				set.EmoteIDs = append(set.EmoteIDs, emoteID)
+			}
+		}
+		if len(set.EmoteIDs) > int(slots) {
+			return nil, resolvers.ErrPersonalEmoteSlotLimitReached(slots)
+		}
+		if len(set.EmoteIDs) > 0 {
+			count, err := mongo.Collection(mongo.CollectionNameEmotes).CountDocuments(ctx, bson.M{
+				"_id":    bson.M{"$in": set.EmoteIDs},
+				"status": datastructure.EmoteStatusLive,
+			})
+			if err != nil {
+				logrus.WithError(err).Error("mongo")
+				return nil, resolvers.ErrInternalServer
+			}
+			if int(count) != len(set.EmoteIDs) {
+				return nil, resolvers.ErrUnknownEmote
+			}
+		}
+
+		builder = builder.SetEmoteSetData(set)
+
+		notify = notify.SetTitle("Personal Emotes Granted").
+			AddTextMessagePart(fmt.Sprintf("You've been granted a personal emote set with %d slots, usable in every channel", slots))
+	case datastructure.EntitlementKindEmoteSlots:
+		if args.Data.EmoteSlots == nil {
+			return nil, fmt.Errorf("missing emote slots data")
+		}
+		if args.Data.EmoteSlots.Slots <= 0 {
+			return nil, fmt.Errorf("emote slots must be positive")
+		}
+
+		builder = builder.SetEmoteSlotsData(*args.Data.EmoteSlots)
+
+		notify = notify.SetTitle("Emote Slots Granted").
+			AddTextMessagePart(fmt.Sprintf("You've been granted %d additional channel emote slots", args.Data.EmoteSlots.Slots))
 	}
 
 	if builder.Entitlement.Data != nil {
@@ -210,8 +283,8 @@ func (*MutationResolver) CreateEntitlement(ctx context.Context, args struct {
 			f.Set("X-Created-ID", builder.Entitlement.ID.Hex())
 		}
 
-		// Send the notification
-		if len(notify.Notification.MessageParts) > 0 {
+		// Send the notification, unless the entitlement starts later, in which case it is sent once it does
+		if len(notify.Notification.MessageParts) > 0 && builder.Entitlement.IsActive(time.Now()) {
 			go func() {
 				if err := notify.Write(ctx); err != nil {
 					logrus.WithError(err).Error("notifications")
@@ -226,3 +299,27 @@ func (*MutationResolver) CreateEntitlement(ctx context.Context, args struct {
 		Message: "Entitlement Created",
 	}, nil
 }
+
+// setEntitlementPeriod: Parse and set the time an entitlement is active for, if limited
+func setEntitlementPeriod(builder actions.EntitlementBuilder, startsAt *string, endsAt *string) (actions.EntitlementBuilder, error) {
+	if startsAt != nil && *startsAt != "" {
+		t, err := time.Parse("2006-01-02T15:04:05.999Z07:00", *startsAt)
+		if err != nil {
+			return builder, resolvers.ErrInvalidDate
+		}
+		builder = builder.SetStartsAt(&t)
+	}
+	if endsAt != nil && *endsAt != "" {
+		t, err := time.Parse("2006-01-02T15:04:05.999Z07:00", *endsAt)
+		if err != nil {
+			return builder, resolvers.ErrInvalidDate
+		}
+		// An entitlement can't end before it starts
+		start := builder.Entitlement.StartsAt
+		if !t.After(time.Now()) || (start != nil && !t.After(*start)) {
+			return builder, resolvers.ErrInvalidDate
+		}
+		builder = builder.SetEndsAt(&t)
+	}
+	return builder, nil
+}
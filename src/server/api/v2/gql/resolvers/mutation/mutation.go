@@ -5,9 +5,10 @@ import "github.com/SevenTV/ServerGo/src/mongo/datastructure"
 type MutationResolver struct{}
 
 type response struct {
-	OK      bool   `json:"ok"`
-	Message string `json:"message"`
-	Status  int32  `json:"status"`
+	OK      bool    `json:"ok"`
+	Message string  `json:"message"`
+	Status  int32   `json:"status"`
+	JobID   *string `json:"job_id"`
 }
 
 type emoteInput struct {
@@ -26,10 +27,87 @@ type userInput struct {
 	CosmeticBadge *string `json:"cosmetic_badge"`
 }
 
+type reportEvidenceInput struct {
+	Links   *[]string `json:"links"`
+	ChatLog *string   `json:"chat_log"`
+}
+
+type notificationInput struct {
+	Title         *string                         `json:"title"`
+	MessageParts  *[]notificationMessagePartInput `json:"message_parts"`
+	Announcement  *bool                           `json:"announcement"`
+	TargetUserIDs *[]string                       `json:"target_user_ids"`
+	ScheduledAt   *string                         `json:"scheduled_at"`
+	ExpireAt      *string                         `json:"expire_at"`
+}
+
+type notificationMessagePartInput struct {
+	Type int32  `json:"type"`
+	Data string `json:"data"`
+}
+
+type badgeInput struct {
+	Name     *string `json:"name"`
+	Priority *int32  `json:"priority"`
+	Tooltip  *string `json:"tooltip"`
+	Misc     *bool   `json:"misc"`
+}
+
+type paintInput struct {
+	Name        *string                 `json:"name"`
+	Priority    *int32                  `json:"priority"`
+	Function    *string                 `json:"function"`
+	Color       *int32                  `json:"color"`
+	Stops       *[]paintStopInput       `json:"stops"`
+	Repeat      *bool                   `json:"repeat"`
+	Angle       *int32                  `json:"angle"`
+	Shape       *string                 `json:"shape"`
+	ImageURL    *string                 `json:"image_url"`
+	DropShadows *[]paintDropShadowInput `json:"drop_shadows"`
+	Animation   *paintAnimationInput    `json:"animation"`
+}
+
+type paintStopInput struct {
+	At    float64 `json:"at"`
+	Color int32   `json:"color"`
+}
+
+type paintDropShadowInput struct {
+	XOffset float64 `json:"x_offset"`
+	YOffset float64 `json:"y_offset"`
+	Radius  float64 `json:"radius"`
+	Color   int32   `json:"color"`
+}
+
+type paintAnimationInput struct {
+	Speed     int32                `json:"speed"`
+	Keyframes []paintKeyframeInput `json:"keyframes"`
+}
+
+type paintKeyframeInput struct {
+	At float64 `json:"at"`
+	X  float64 `json:"x"`
+	Y  float64 `json:"y"`
+}
+
 type entitlementCreateInput struct {
 	Subscription *datastructure.EntitledSubscription `json:"subscription"`
 	Badge        *datastructure.EntitledBadge        `json:"badge"`
 	Paint        *datastructure.EntitledPaint        `json:"paint"`
 	Role         *datastructure.EntitledRole         `json:"role"`
-	EmoteSet     *datastructure.EntitledEmoteSet     `json:"emote_set"`
+	EmoteSet     *entitlementEmoteSetInput           `json:"emote_set"`
+	EmoteSlots   *datastructure.EntitledEmoteSlots   `json:"emote_slots"`
+}
+
+type bulkEntitlementSelectorInput struct {
+	UserIDs *[]string `json:"user_ids"`
+	RoleID  *string   `json:"role_id"`
+	EmoteID *string   `json:"emote_id"`
+}
+
+type entitlementEmoteSetInput struct {
+	ID         *string  `json:"id"`
+	UnicodeTag string   `json:"unicode_tag"`
+	EmoteIDs   []string `json:"emote_ids"`
+	Slots      *int32   `json:"slots"`
 }
@@ -0,0 +1,378 @@
+package mutation_resolvers
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/SevenTV/ServerGo/src/mongo"
+	"github.com/SevenTV/ServerGo/src/mongo/datastructure"
+	"github.com/SevenTV/ServerGo/src/server/api/actions"
+	"github.com/SevenTV/ServerGo/src/server/api/v2/gql/resolvers"
+	query_resolvers "github.com/SevenTV/ServerGo/src/server/api/v2/gql/resolvers/query"
+	"github.com/SevenTV/ServerGo/src/utils"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	MAX_NOTIFICATION_TITLE_LENGTH = 100
+	MAX_NOTIFICATION_PARTS        = 50
+	MAX_NOTIFICATION_TEXT_LENGTH  = 2000
+)
+
+//
+// CREATE NOTIFICATION
+//
+func (*MutationResolver) CreateNotification(ctx context.Context, args struct {
+	Data notificationInput
+}) (*query_resolvers.NotificationResolver, error) {
+	usr, ok := ctx.Value(utils.UserKey).(*datastructure.User)
+	if !ok {
+		return nil, resolvers.ErrLoginRequired
+	}
+	if !usr.HasPermission(datastructure.RolePermissionSendNotifications) {
+		return nil, resolvers.ErrAccessDenied
+	}
+	if args.Data.Title == nil || args.Data.MessageParts == nil {
+		return nil, resolvers.ErrInvalidNotification
+	}
+
+	notification := &datastructure.Notification{
+		ID:       primitive.NewObjectID(),
+		AuthorID: &usr.ID,
+	}
+	if err := applyNotificationInput(notification, args.Data); err != nil {
+		return nil, err
+	}
+
+	if notification.Pending {
+		if _, err := mongo.Collection(mongo.CollectionNameNotifications).InsertOne(ctx, notification); err != nil {
+			logrus.WithError(err).Error("mongo")
+			return nil, resolvers.ErrInternalServer
+		}
+	} else if err := actions.Notifications.Send(ctx, *notification); err != nil {
+		return nil, resolvers.ErrInternalServer
+	}
+
+	_, err := mongo.Collection(mongo.CollectionNameAudit).InsertOne(ctx, &datastructure.AuditLog{
+		Type:      datastructure.AuditLogTypeNotificationCreate,
+		CreatedBy: usr.ID,
+		Target:    &datastructure.Target{ID: &notification.ID, Type: "notifications"},
+		Changes: []*datastructure.AuditLogChange{
+			{Key: "title", OldValue: nil, NewValue: notification.Title},
+			{Key: "announcement", OldValue: nil, NewValue: notification.Announcement},
+			{Key: "scheduled_at", OldValue: nil, NewValue: notification.ScheduledAt},
+			{Key: "expire_at", OldValue: nil, NewValue: notification.ExpireAt},
+		},
+	})
+	if err != nil {
+		logrus.WithError(err).Error("mongo")
+	}
+
+	field, failed := query_resolvers.GenerateSelectedFieldMap(ctx, resolvers.MaxDepth)
+	if failed {
+		return nil, resolvers.ErrDepth
+	}
+
+	return query_resolvers.GenerateNotificationResolver(ctx, notification, field.Children)
+}
+
+//
+// EDIT NOTIFICATION
+//
+func (*MutationResolver) EditNotification(ctx context.Context, args struct {
+	ID     string
+	Data   notificationInput
+	Reason *string
+}) (*query_resolvers.NotificationResolver, error) {
+	usr, notification, err := getComposedNotification(ctx, args.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Once sent, only the content of a notification may change
+	if !notification.Pending && (args.Data.Announcement != nil || args.Data.TargetUserIDs != nil || args.Data.ScheduledAt != nil) {
+		return nil, resolvers.ErrNotificationSent
+	}
+
+	old := *notification
+	if err := applyNotificationInput(notification, args.Data); err != nil {
+		return nil, err
+	}
+
+	if old.Pending && !notification.Pending {
+		// The schedule was removed or moved into the past: claim and send the notification now
+		res, err := mongo.Collection(mongo.CollectionNameNotifications).UpdateOne(ctx, bson.M{
+			"_id":     notification.ID,
+			"pending": true,
+		}, bson.M{
+			"$set":   bson.M{"pending": false},
+			"$unset": bson.M{"scheduled_at": 1},
+		})
+		if err != nil {
+			logrus.WithError(err).Error("mongo")
+			return nil, resolvers.ErrInternalServer
+		}
+		if res.ModifiedCount == 0 {
+			return nil, resolvers.ErrNotificationSent
+		}
+
+		if err := actions.Notifications.Send(ctx, *notification); err != nil {
+			return nil, resolvers.ErrInternalServer
+		}
+	} else {
+		filter := bson.M{"_id": notification.ID}
+		set := bson.M{
+			"title":         notification.Title,
+			"message_parts": notification.MessageParts,
+			"expire_at":     notification.ExpireAt,
+		}
+		if notification.Pending {
+			// Don't edit the targets of a notification the scheduler already claimed
+			filter["pending"] = true
+			set["announcement"] = notification.Announcement
+			set["targets"] = notification.Targets
+			set["scheduled_at"] = notification.ScheduledAt
+		}
+
+		res, err := mongo.Collection(mongo.CollectionNameNotifications).UpdateOne(ctx, filter, bson.M{
+			"$set": set,
+		})
+		if err != nil {
+			logrus.WithError(err).Error("mongo")
+			return nil, resolvers.ErrInternalServer
+		}
+		if res.MatchedCount == 0 {
+			return nil, resolvers.ErrNotificationSent
+		}
+	}
+
+	changes := []*datastructure.AuditLogChange{}
+	if old.Title != notification.Title {
+		changes = append(changes, &datastructure.AuditLogChange{Key: "title", OldValue: old.Title, NewValue: notification.Title})
+	}
+	if args.Data.MessageParts != nil {
+		changes = append(changes, &datastructure.AuditLogChange{Key: "message_parts", OldValue: old.MessageParts, NewValue: notification.MessageParts})
+	}
+	if old.Announcement != notification.Announcement {
+		changes = append(changes, &datastructure.AuditLogChange{Key: "announcement", OldValue: old.Announcement, NewValue: notification.Announcement})
+	}
+	if args.Data.TargetUserIDs != nil {
+		changes = append(changes, &datastructure.AuditLogChange{Key: "targets", OldValue: old.Targets, NewValue: notification.Targets})
+	}
+	if args.Data.ScheduledAt != nil {
+		changes = append(changes, &datastructure.AuditLogChange{Key: "scheduled_at", OldValue: old.ScheduledAt, NewValue: notification.ScheduledAt})
+	}
+	if args.Data.ExpireAt != nil {
+		changes = append(changes, &datastructure.AuditLogChange{Key: "expire_at", OldValue: old.ExpireAt, NewValue: notification.ExpireAt})
+	}
+
+	_, err = mongo.Collection(mongo.CollectionNameAudit).InsertOne(ctx, &datastructure.AuditLog{
+		Type:      datastructure.AuditLogTypeNotificationEdit,
+		CreatedBy: usr.ID,
+		Target:    &datastructure.Target{ID: &notification.ID, Type: "notifications"},
+		Changes:   changes,
+		Reason:    args.Reason,
+	})
+	if err != nil {
+		logrus.WithError(err).Error("mongo")
+	}
+
+	field, failed := query_resolvers.GenerateSelectedFieldMap(ctx, resolvers.MaxDepth)
+	if failed {
+		return nil, resolvers.ErrDepth
+	}
+
+	return query_resolvers.GenerateNotificationResolver(ctx, notification, field.Children)
+}
+
+//
+// RETRACT NOTIFICATION
+//
+func (*MutationResolver) RetractNotification(ctx context.Context, args struct {
+	ID     string
+	Reason *string
+}) (*response, error) {
+	usr, notification, err := getComposedNotification(ctx, args.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Remove the notification from the users it was sent to
+	if err := actions.Notifications.Retract(ctx, *notification); err != nil {
+		return nil, resolvers.ErrInternalServer
+	}
+
+	_, err = mongo.Collection(mongo.CollectionNameAudit).InsertOne(ctx, &datastructure.AuditLog{
+		Type:      datastructure.AuditLogTypeNotificationRetract,
+		CreatedBy: usr.ID,
+		Target:    &datastructure.Target{ID: &notification.ID, Type: "notifications"},
+		Changes: []*datastructure.AuditLogChange{
+			{Key: "retracted", OldValue: false, NewValue: true},
+		},
+		Reason: args.Reason,
+	})
+	if err != nil {
+		logrus.WithError(err).Error("mongo")
+	}
+
+	return &response{
+		OK:      true,
+		Status:  200,
+		Message: "success",
+	}, nil
+}
+
+// getComposedNotification: Get the actor and the staff-composed notification they are acting on,
+// verifying they are allowed to manage notifications
+func getComposedNotification(ctx context.Context, notificationID string) (*datastructure.User, *datastructure.Notification, error) {
+	usr, ok := ctx.Value(utils.UserKey).(*datastructure.User)
+	if !ok {
+		return nil, nil, resolvers.ErrLoginRequired
+	}
+	if !usr.HasPermission(datastructure.RolePermissionSendNotifications) {
+		return nil, nil, resolvers.ErrAccessDenied
+	}
+
+	id, err := primitive.ObjectIDFromHex(notificationID)
+	if err != nil {
+		return nil, nil, resolvers.ErrUnknownNotification
+	}
+
+	// System notifications can't be edited
+	notification := &datastructure.Notification{}
+	res := mongo.Collection(mongo.CollectionNameNotifications).FindOne(ctx, bson.M{
+		"_id":       id,
+		"author_id": bson.M{"$exists": true},
+	})
+	err = res.Err()
+	if err == nil {
+		err = res.Decode(notification)
+	}
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil, resolvers.ErrUnknownNotification
+		}
+		logrus.WithError(err).Error("mongo")
+		return nil, nil, resolvers.ErrInternalServer
+	}
+	if notification.Retracted {
+		return nil, nil, resolvers.ErrNotificationRetracted
+	}
+
+	return usr, notification, nil
+}
+
+// applyNotificationInput: Validate the input and apply it to the notification
+func applyNotificationInput(notification *datastructure.Notification, input notificationInput) error {
+	if input.Title != nil {
+		title := strings.TrimSpace(*input.Title)
+		if title == "" || len(title) > MAX_NOTIFICATION_TITLE_LENGTH {
+			return resolvers.ErrInvalidNotification
+		}
+		notification.Title = title
+	}
+
+	if input.MessageParts != nil {
+		if len(*input.MessageParts) == 0 || len(*input.MessageParts) > MAX_NOTIFICATION_PARTS {
+			return resolvers.ErrInvalidNotification
+		}
+
+		parts := make([]datastructure.NotificationMessagePart, len(*input.MessageParts))
+		for i, p := range *input.MessageParts {
+			part := datastructure.NotificationMessagePart{
+				Type: datastructure.NotificationContentMessagePartType(p.Type),
+			}
+
+			switch part.Type {
+			case datastructure.NotificationMessagePartTypeText:
+				if p.Data == "" || len(p.Data) > MAX_NOTIFICATION_TEXT_LENGTH {
+					return resolvers.ErrInvalidNotification
+				}
+				text := p.Data
+				part.Text = &text
+			case datastructure.NotificationMessagePartTypeUserMention, datastructure.NotificationMessagePartTypeEmoteMention:
+				id, err := primitive.ObjectIDFromHex(p.Data)
+				if err != nil {
+					return resolvers.ErrInvalidNotification
+				}
+				part.Mention = &id
+			case datastructure.NotificationMessagePartTypeRoleMention:
+				id, err := primitive.ObjectIDFromHex(p.Data)
+				if err != nil {
+					return resolvers.ErrUnknownRole
+				}
+				// Everyone holds the default role, an announcement should be used instead
+				if role := datastructure.GetRole(&id); role.ID != id || role.Default {
+					return resolvers.ErrUnknownRole
+				}
+				part.Mention = &id
+			default:
+				return resolvers.ErrInvalidNotification
+			}
+
+			parts[i] = part
+		}
+		notification.MessageParts = parts
+	}
+
+	if input.Announcement != nil {
+		notification.Announcement = *input.Announcement
+	}
+
+	if input.TargetUserIDs != nil {
+		targets := []primitive.ObjectID{}
+		for _, s := range *input.TargetUserIDs {
+			id, err := primitive.ObjectIDFromHex(s)
+			if err != nil {
+				return resolvers.ErrUnknownUser
+			}
+			if !utils.ContainsObjectID(targets, id) {
+				targets = append(targets, id)
+			}
+		}
+		notification.Targets = targets
+	}
+
+	if input.ScheduledAt != nil {
+		notification.ScheduledAt = nil
+		if *input.ScheduledAt != "" {
+			t, err := time.Parse("2006-01-02T15:04:05.999Z07:00", *input.ScheduledAt)
+			if err != nil {
+				return resolvers.ErrInvalidDate
+			}
+			notification.ScheduledAt = &t
+		}
+	}
+	notification.Pending = notification.ScheduledAt != nil && notification.ScheduledAt.After(time.Now())
+	if !notification.Pending {
+		notification.ScheduledAt = nil
+	}
+
+	if input.ExpireAt != nil {
+		notification.ExpireAt = nil
+		if *input.ExpireAt != "" {
+			t, err := time.Parse("2006-01-02T15:04:05.999Z07:00", *input.ExpireAt)
+			if err != nil {
+				return resolvers.ErrInvalidDate
+			}
+			// A notification can't expire before it is sent
+			if !t.After(time.Now()) || (notification.ScheduledAt != nil && !t.After(*notification.ScheduledAt)) {
+				return resolvers.ErrInvalidDate
+			}
+			notification.ExpireAt = &t
+		}
+	}
+
+	// The notification must reach someone: everyone, specific users or the holders of a mentioned role
+	if !notification.Announcement && len(notification.Targets) == 0 {
+		b, _ := actions.Notifications.CreateFrom(*notification).GetMentionedRoles(context.Background())
+		if len(b.MentionedRoles) == 0 {
+			return resolvers.ErrInvalidNotification
+		}
+	}
+
+	return nil
+}
@@ -7,8 +7,10 @@ import (
 
 	"github.com/SevenTV/ServerGo/src/mongo"
 	"github.com/SevenTV/ServerGo/src/mongo/datastructure"
+	"github.com/SevenTV/ServerGo/src/server/api/actions"
 	"github.com/SevenTV/ServerGo/src/server/api/v2/gql/resolvers"
 	"github.com/SevenTV/ServerGo/src/utils"
+	"github.com/sirupsen/logrus"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
@@ -38,6 +40,7 @@ func (*MutationResolver) MarkNotificationsRead(ctx context.Context, args struct
 			"$in": ids,
 		},
 		"target": usr.ID,
+		"read":   false,
 	}, bson.M{
 		"$set": bson.M{
 			"read":    true,
@@ -48,9 +51,68 @@ func (*MutationResolver) MarkNotificationsRead(ctx context.Context, args struct
 		return nil, err
 	}
 
+	// Announcements get a read state when they are first read
+	upserted, err := actions.Notifications.MarkAnnouncementsRead(ctx, usr.ID, ids)
+	if err != nil {
+		return nil, resolvers.ErrInternalServer
+	}
+
+	// Keep the unread count and the user's other clients in sync
+	actions.Notifications.OnRead(ctx, usr.ID, ids, res.ModifiedCount+upserted)
+
+	return &response{
+		OK:      true,
+		Status:  200,
+		Message: fmt.Sprintf("Marked %d notifications as read", res.ModifiedCount+upserted),
+	}, nil
+}
+
+//
+// MARK ALL NOTIFICATIONS READ
+//
+func (*MutationResolver) MarkAllNotificationsRead(ctx context.Context) (*response, error) {
+	usr, ok := ctx.Value(utils.UserKey).(*datastructure.User)
+	if !ok {
+		return nil, resolvers.ErrLoginRequired
+	}
+
+	read, err := actions.Notifications.MarkAllRead(ctx, usr.ID)
+	if err != nil {
+		return nil, resolvers.ErrInternalServer
+	}
+
+	return &response{
+		OK:      true,
+		Status:  200,
+		Message: fmt.Sprintf("Marked %d notifications as read", read),
+	}, nil
+}
+
+//
+// SET NOTIFICATION CATEGORY MUTED
+//
+func (*MutationResolver) SetNotificationCategoryMuted(ctx context.Context, args struct {
+	Category datastructure.NotificationCategory
+	Muted    bool
+}) (*response, error) {
+	usr, ok := ctx.Value(utils.UserKey).(*datastructure.User)
+	if !ok {
+		return nil, resolvers.ErrLoginRequired
+	}
+
+	op := utils.Ternary(args.Muted, "$addToSet", "$pull").(string)
+	if _, err := mongo.Collection(mongo.CollectionNameUsers).UpdateOne(ctx, bson.M{
+		"_id": usr.ID,
+	}, bson.M{
+		op: bson.M{"muted_notifications": args.Category},
+	}); err != nil {
+		logrus.WithError(err).Error("mongo")
+		return nil, resolvers.ErrInternalServer
+	}
+
 	return &response{
 		OK:      true,
 		Status:  200,
-		Message: fmt.Sprintf("Marked %d notifications as read", res.ModifiedCount),
+		Message: "success",
 	}, nil
 }
@@ -0,0 +1,149 @@
+package mutation_resolvers
+
+import (
+	"context"
+
+	"github.com/SevenTV/ServerGo/src/mongo"
+	"github.com/SevenTV/ServerGo/src/mongo/datastructure"
+	"github.com/SevenTV/ServerGo/src/server/api/actions"
+	"github.com/SevenTV/ServerGo/src/server/api/v2/gql/resolvers"
+	query_resolvers "github.com/SevenTV/ServerGo/src/server/api/v2/gql/resolvers/query"
+	"github.com/SevenTV/ServerGo/src/utils"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+//
+// ADD PERSONAL EMOTE
+//
+func (*MutationResolver) AddPersonalEmote(ctx context.Context, args struct {
+	SetID   string
+	EmoteID string
+}) (*query_resolvers.PersonalEmoteSetResolver, error) {
+	usr, b, owner, err := getEditablePersonalEmoteSet(ctx, args.SetID)
+	if err != nil {
+		return nil, err
+	}
+
+	emoteID, err := primitive.ObjectIDFromHex(args.EmoteID)
+	if err != nil {
+		return nil, resolvers.ErrUnknownEmote
+	}
+	emote := &datastructure.Emote{}
+	if err := mongo.Collection(mongo.CollectionNameEmotes).FindOne(ctx, bson.M{
+		"_id":    emoteID,
+		"status": datastructure.EmoteStatusLive,
+	}).Decode(emote); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, resolvers.ErrUnknownEmote
+		}
+		logrus.WithError(err).Error("mongo")
+		return nil, resolvers.ErrInternalServer
+	}
+
+	// The owner of the set must be allowed to use the emote
+	if err := actions.Emotes.CanUse(emote, owner); err != nil {
+		return nil, emoteUsageError(err)
+	}
+
+	if _, err := b.AddPersonalEmote(emote, usr); err != nil {
+		if err == actions.ErrPersonalEmoteSetFull {
+			return nil, resolvers.ErrPersonalEmoteSlotLimitReached(actions.Entitlements.GetPersonalEmoteSlots(b.ReadEmoteSetData()))
+		}
+		return nil, resolvers.ErrInternalServer
+	}
+
+	return generatePersonalEmoteSetResolver(ctx, b)
+}
+
+//
+// REMOVE PERSONAL EMOTE
+//
+func (*MutationResolver) RemovePersonalEmote(ctx context.Context, args struct {
+	SetID   string
+	EmoteID string
+}) (*query_resolvers.PersonalEmoteSetResolver, error) {
+	usr, b, _, err := getEditablePersonalEmoteSet(ctx, args.SetID)
+	if err != nil {
+		return nil, err
+	}
+
+	emoteID, err := primitive.ObjectIDFromHex(args.EmoteID)
+	if err != nil {
+		return nil, resolvers.ErrUnknownEmote
+	}
+
+	if _, err := b.RemovePersonalEmote(emoteID, usr); err != nil {
+		return nil, resolvers.ErrInternalServer
+	}
+
+	return generatePersonalEmoteSetResolver(ctx, b)
+}
+
+// getEditablePersonalEmoteSet: Get a personal emote set which the actor may add emotes to or remove emotes from,
+// which are the actor's own sets, or anyone's with permission
+func getEditablePersonalEmoteSet(ctx context.Context, id string) (*datastructure.User, actions.EntitlementBuilder, *datastructure.User, error) {
+	usr, ok := ctx.Value(utils.UserKey).(*datastructure.User)
+	if !ok {
+		return nil, actions.EntitlementBuilder{}, nil, resolvers.ErrLoginRequired
+	}
+
+	setID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, actions.EntitlementBuilder{}, nil, resolvers.ErrUnknownEmoteSet
+	}
+	b, err := actions.Entitlements.GetPersonalEmoteSet(ctx, setID)
+	if err != nil {
+		if err == actions.ErrUnknownPersonalEmoteSet {
+			return nil, b, nil, resolvers.ErrUnknownEmoteSet
+		}
+		return nil, b, nil, resolvers.ErrInternalServer
+	}
+
+	if b.Entitlement.UserID != usr.ID && !usr.HasPermission(datastructure.RolePermissionManageUsers) {
+		return nil, b, nil, resolvers.ErrAccessDenied
+	}
+	if banned, _ := actions.Bans.IsUserBanned(b.Entitlement.UserID); banned {
+		return nil, b, nil, resolvers.ErrUserBanned
+	}
+
+	ub, err := b.GetUser()
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, b, nil, resolvers.ErrUnknownUser
+		}
+		logrus.WithError(err).Error("mongo")
+		return nil, b, nil, resolvers.ErrInternalServer
+	}
+
+	return usr, b, &ub.User, nil
+}
+
+// generatePersonalEmoteSetResolver: Resolve a personal emote set as it is after a change
+func generatePersonalEmoteSetResolver(ctx context.Context, b actions.EntitlementBuilder) (*query_resolvers.PersonalEmoteSetResolver, error) {
+	field, failed := query_resolvers.GenerateSelectedFieldMap(ctx, resolvers.MaxDepth)
+	if failed {
+		return nil, resolvers.ErrDepth
+	}
+
+	sets, err := actions.Entitlements.GetPersonalEmoteSets(ctx, b.Entitlement.UserID)
+	if err != nil {
+		return nil, resolvers.ErrInternalServer
+	}
+	for _, set := range sets {
+		if set.ID == b.Entitlement.ID.Hex() {
+			return query_resolvers.GeneratePersonalEmoteSetResolver(ctx, set, field.Children), nil
+		}
+	}
+	return nil, resolvers.ErrUnknownEmoteSet
+}
+
+// emoteUsageError: Get the error returned to the client when a user may not use an emote.
+// A private emote which isn't shared with the user is treated as if it didn't exist
+func emoteUsageError(err error) error {
+	if err == actions.ErrZeroWidthNotAllowed {
+		return resolvers.ErrAccessDenied
+	}
+	return resolvers.ErrUnknownEmote
+}
@@ -0,0 +1,305 @@
+package mutation_resolvers
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/SevenTV/ServerGo/src/mongo"
+	"github.com/SevenTV/ServerGo/src/mongo/datastructure"
+	"github.com/SevenTV/ServerGo/src/server/api/actions"
+	"github.com/SevenTV/ServerGo/src/server/api/v2/gql/resolvers"
+	query_resolvers "github.com/SevenTV/ServerGo/src/server/api/v2/gql/resolvers/query"
+	"github.com/SevenTV/ServerGo/src/utils"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const MAX_REPORT_NOTE_LENGTH = 1000
+
+//
+// ASSIGN REPORT
+//
+func (*MutationResolver) AssignReport(ctx context.Context, args struct {
+	ReportID   string
+	AssigneeID *string
+}) (*query_resolvers.ReportResolver, error) {
+	usr, report, err := getModeratedReport(ctx, args.ReportID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Assign to self by default
+	assigneeID := usr.ID
+	if args.AssigneeID != nil {
+		if assigneeID, err = primitive.ObjectIDFromHex(*args.AssigneeID); err != nil {
+			return nil, resolvers.ErrUnknownUser
+		}
+	}
+
+	if assigneeID != usr.ID {
+		assignee := &datastructure.User{}
+		res := mongo.Collection(mongo.CollectionNameUsers).FindOne(ctx, bson.M{
+			"_id": assigneeID,
+		})
+		err = res.Err()
+		if err == nil {
+			err = res.Decode(assignee)
+		}
+		if err != nil {
+			if err == mongo.ErrNoDocuments {
+				return nil, resolvers.ErrUnknownUser
+			}
+			logrus.WithError(err).Error("mongo")
+			return nil, resolvers.ErrInternalServer
+		}
+
+		// Only moderators can handle reports
+		role := datastructure.GetRole(assignee.RoleID)
+		assignee.Role = &role
+		if !assignee.HasPermission(datastructure.RolePermissionManageReports) {
+			return nil, resolvers.ErrAccessDenied
+		}
+	}
+
+	return updateReport(ctx, usr, report, bson.M{
+		"$set": bson.M{"assignee_id": assigneeID},
+	}, datastructure.AuditLogTypeReportAssign, []*datastructure.AuditLogChange{
+		{Key: "assignee_id", OldValue: report.AssigneeID, NewValue: assigneeID},
+	}, nil)
+}
+
+//
+// RESOLVE REPORT
+//
+func (*MutationResolver) ResolveReport(ctx context.Context, args struct {
+	ReportID    string
+	ActionTaken string
+	Reason      *string
+}) (*query_resolvers.ReportResolver, error) {
+	usr, report, err := getModeratedReport(ctx, args.ReportID)
+	if err != nil {
+		return nil, err
+	}
+	if report.GetStatus() != datastructure.ReportStatusOpen {
+		return nil, resolvers.ErrReportClosed
+	}
+
+	action := strings.TrimSpace(args.ActionTaken)
+	if action == "" {
+		return nil, resolvers.ErrNoReason
+	}
+
+	return updateReport(ctx, usr, report, bson.M{
+		"$set": bson.M{
+			"status":       datastructure.ReportStatusResolved,
+			"cleared":      true,
+			"action_taken": action,
+			"closed_at":    time.Now(),
+		},
+	}, datastructure.AuditLogTypeReportClear, []*datastructure.AuditLogChange{
+		{Key: "status", OldValue: report.GetStatus(), NewValue: datastructure.ReportStatusResolved},
+		{Key: "action_taken", OldValue: report.ActionTaken, NewValue: action},
+	}, args.Reason)
+}
+
+//
+// REJECT REPORT
+//
+func (*MutationResolver) RejectReport(ctx context.Context, args struct {
+	ReportID string
+	Reason   *string
+}) (*query_resolvers.ReportResolver, error) {
+	usr, report, err := getModeratedReport(ctx, args.ReportID)
+	if err != nil {
+		return nil, err
+	}
+	if report.GetStatus() != datastructure.ReportStatusOpen {
+		return nil, resolvers.ErrReportClosed
+	}
+
+	return updateReport(ctx, usr, report, bson.M{
+		"$set": bson.M{
+			"status":    datastructure.ReportStatusRejected,
+			"cleared":   true,
+			"closed_at": time.Now(),
+		},
+	}, datastructure.AuditLogTypeReportClear, []*datastructure.AuditLogChange{
+		{Key: "status", OldValue: report.GetStatus(), NewValue: datastructure.ReportStatusRejected},
+	}, args.Reason)
+}
+
+//
+// REOPEN REPORT
+//
+func (*MutationResolver) ReopenReport(ctx context.Context, args struct {
+	ReportID string
+	Reason   *string
+}) (*query_resolvers.ReportResolver, error) {
+	usr, report, err := getModeratedReport(ctx, args.ReportID)
+	if err != nil {
+		return nil, err
+	}
+	if report.GetStatus() == datastructure.ReportStatusOpen {
+		return nil, resolvers.ErrReportOpen
+	}
+
+	return updateReport(ctx, usr, report, bson.M{
+		"$set": bson.M{
+			"status":  datastructure.ReportStatusOpen,
+			"cleared": false,
+		},
+		"$unset": bson.M{
+			"action_taken": 1,
+			"closed_at":    1,
+		},
+	}, datastructure.AuditLogTypeReportReopen, []*datastructure.AuditLogChange{
+		{Key: "status", OldValue: report.GetStatus(), NewValue: datastructure.ReportStatusOpen},
+	}, args.Reason)
+}
+
+//
+// ADD REPORT NOTE
+//
+func (*MutationResolver) AddReportNote(ctx context.Context, args struct {
+	ReportID string
+	Content  string
+}) (*query_resolvers.ReportResolver, error) {
+	usr, report, err := getModeratedReport(ctx, args.ReportID)
+	if err != nil {
+		return nil, err
+	}
+
+	content := strings.TrimSpace(args.Content)
+	if content == "" || len(content) > MAX_REPORT_NOTE_LENGTH {
+		return nil, resolvers.ErrInvalidNote
+	}
+
+	note := &datastructure.ReportNote{
+		AuthorID:  usr.ID,
+		Content:   content,
+		CreatedAt: time.Now(),
+	}
+
+	return updateReport(ctx, usr, report, bson.M{
+		"$push": bson.M{"notes": note},
+	}, datastructure.AuditLogTypeReportNote, []*datastructure.AuditLogChange{
+		// The note itself is internal, only its count is recorded
+		{Key: "notes", OldValue: len(report.Notes), NewValue: len(report.Notes) + 1},
+	}, nil)
+}
+
+//
+// REVERT AUTO UNLIST
+//
+func (*MutationResolver) RevertAutoUnlist(ctx context.Context, args struct {
+	EmoteID string
+	Reason  *string
+}) (*response, error) {
+	usr, ok := ctx.Value(utils.UserKey).(*datastructure.User)
+	if !ok {
+		return nil, resolvers.ErrLoginRequired
+	}
+	if !usr.HasPermission(datastructure.RolePermissionManageReports) {
+		return nil, resolvers.ErrAccessDenied
+	}
+
+	id, err := primitive.ObjectIDFromHex(args.EmoteID)
+	if err != nil {
+		return nil, resolvers.ErrUnknownEmote
+	}
+
+	if err := actions.Reports.RevertAutoUnlist(ctx, id, usr, args.Reason); err != nil {
+		if err == actions.ErrNoAutoUnlist {
+			return nil, resolvers.ErrNoAutoUnlist
+		}
+		if err == mongo.ErrNoDocuments {
+			return nil, resolvers.ErrUnknownEmote
+		}
+		logrus.WithError(err).Error("mongo")
+		return nil, resolvers.ErrInternalServer
+	}
+
+	return &response{
+		OK:      true,
+		Status:  200,
+		Message: "success",
+	}, nil
+}
+
+// getModeratedReport: Get the actor and the report they are acting on, verifying they are allowed to manage reports
+func getModeratedReport(ctx context.Context, reportID string) (*datastructure.User, *datastructure.Report, error) {
+	usr, ok := ctx.Value(utils.UserKey).(*datastructure.User)
+	if !ok {
+		return nil, nil, resolvers.ErrLoginRequired
+	}
+	if !usr.HasPermission(datastructure.RolePermissionManageReports) {
+		return nil, nil, resolvers.ErrAccessDenied
+	}
+
+	id, err := primitive.ObjectIDFromHex(reportID)
+	if err != nil {
+		return nil, nil, resolvers.ErrUnknownReport
+	}
+
+	report := &datastructure.Report{}
+	res := mongo.Collection(mongo.CollectionNameReports).FindOne(ctx, bson.M{
+		"_id": id,
+	})
+	err = res.Err()
+	if err == nil {
+		err = res.Decode(report)
+	}
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil, resolvers.ErrUnknownReport
+		}
+		logrus.WithError(err).Error("mongo")
+		return nil, nil, resolvers.ErrInternalServer
+	}
+
+	return usr, report, nil
+}
+
+// updateReport: Apply an update to a report, write the audit log and return the updated report
+func updateReport(
+	ctx context.Context, usr *datastructure.User, report *datastructure.Report,
+	update bson.M, logType int32, changes []*datastructure.AuditLogChange, reason *string,
+) (*query_resolvers.ReportResolver, error) {
+	after := options.After
+	res := mongo.Collection(mongo.CollectionNameReports).FindOneAndUpdate(ctx, bson.M{
+		"_id": report.ID,
+	}, update, &options.FindOneAndUpdateOptions{
+		ReturnDocument: &after,
+	})
+
+	updated := &datastructure.Report{}
+	err := res.Err()
+	if err == nil {
+		err = res.Decode(updated)
+	}
+	if err != nil {
+		logrus.WithError(err).Error("mongo")
+		return nil, resolvers.ErrInternalServer
+	}
+
+	_, err = mongo.Collection(mongo.CollectionNameAudit).InsertOne(ctx, &datastructure.AuditLog{
+		Type:      logType,
+		CreatedBy: usr.ID,
+		Target:    &datastructure.Target{ID: &report.ID, Type: "reports"},
+		Changes:   changes,
+		Reason:    reason,
+	})
+	if err != nil {
+		logrus.WithError(err).Error("mongo")
+	}
+
+	field, failed := query_resolvers.GenerateSelectedFieldMap(ctx, resolvers.MaxDepth)
+	if failed {
+		return nil, resolvers.ErrDepth
+	}
+
+	return query_resolvers.GenerateReportResolver(ctx, updated, field.Children)
+}
@@ -3,6 +3,9 @@ package mutation_resolvers
 import (
 	"context"
 	"fmt"
+	"net/url"
+	"strings"
+	"time"
 
 	"github.com/SevenTV/ServerGo/src/configure"
 	"github.com/SevenTV/ServerGo/src/mongo"
@@ -16,12 +19,20 @@ import (
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
+const (
+	MAX_REPORT_EVIDENCE_LINKS       = 5
+	MAX_REPORT_EVIDENCE_LINK_LENGTH = 500
+	MAX_REPORT_CHAT_LOG_LENGTH      = 2000
+)
+
 //
 // REPORT EMOTE
 //
 func (*MutationResolver) ReportEmote(ctx context.Context, args struct {
-	EmoteID string
-	Reason  *string
+	EmoteID  string
+	Reason   *string
+	Category string
+	Evidence *reportEvidenceInput
 }) (*response, error) {
 	if configure.Config.GetBool("maintenance_mode") {
 		return nil, fmt.Errorf("Maintenance Mode")
@@ -30,6 +41,14 @@ func (*MutationResolver) ReportEmote(ctx context.Context, args struct {
 	if !ok {
 		return nil, resolvers.ErrLoginRequired
 	}
+	if restricted, _ := actions.Bans.IsUserRestricted(usr.ID, datastructure.BanTypeReport); restricted {
+		return nil, resolvers.ErrUserRestricted
+	}
+
+	category, evidence, err := parseReportDetails(args.Category, args.Reason, args.Evidence)
+	if err != nil {
+		return nil, err
+	}
 
 	id, err := primitive.ObjectIDFromHex(args.EmoteID)
 	if err != nil {
@@ -70,6 +89,11 @@ func (*MutationResolver) ReportEmote(ctx context.Context, args struct {
 			"cleared":     false,
 			"reporter_id": usr.ID,
 			"reason":      args.Reason,
+			"category":    category,
+			"evidence":    evidence,
+			"status":      datastructure.ReportStatusOpen,
+
+			"last_reported_at": time.Now(),
 		},
 	}, opts)
 
@@ -88,6 +112,13 @@ func (*MutationResolver) ReportEmote(ctx context.Context, args struct {
 		logrus.WithError(err).Error("mongo")
 	}
 
+	// Apply automatic moderation if enough users reported the emote
+	go func() {
+		if err := actions.Reports.EvaluateEmote(context.Background(), emote.ID); err != nil {
+			logrus.WithError(err).Error("reports, auto moderation")
+		}
+	}()
+
 	return &response{
 		OK:      true,
 		Status:  200,
@@ -99,13 +130,23 @@ func (*MutationResolver) ReportEmote(ctx context.Context, args struct {
 // REPORT USER
 //
 func (*MutationResolver) ReportUser(ctx context.Context, args struct {
-	UserID string
-	Reason *string
+	UserID   string
+	Reason   *string
+	Category string
+	Evidence *reportEvidenceInput
 }) (*response, error) {
 	usr, ok := ctx.Value(utils.UserKey).(*datastructure.User)
 	if !ok {
 		return nil, resolvers.ErrLoginRequired
 	}
+	if restricted, _ := actions.Bans.IsUserRestricted(usr.ID, datastructure.BanTypeReport); restricted {
+		return nil, resolvers.ErrUserRestricted
+	}
+
+	category, evidence, err := parseReportDetails(args.Category, args.Reason, args.Evidence)
+	if err != nil {
+		return nil, err
+	}
 
 	id, err := primitive.ObjectIDFromHex(args.UserID)
 	if err != nil {
@@ -121,7 +162,7 @@ func (*MutationResolver) ReportUser(ctx context.Context, args struct {
 		return nil, resolvers.ErrUserBanned
 	}
 
-	res := mongo.Database.Collection("user").FindOne(ctx, bson.M{
+	res := mongo.Collection(mongo.CollectionNameUsers).FindOne(ctx, bson.M{
 		"_id": id,
 	})
 
@@ -155,6 +196,11 @@ func (*MutationResolver) ReportUser(ctx context.Context, args struct {
 			"cleared":     false,
 			"reporter_id": usr.ID,
 			"reason":      args.Reason,
+			"category":    category,
+			"evidence":    evidence,
+			"status":      datastructure.ReportStatusOpen,
+
+			"last_reported_at": time.Now(),
 		},
 	}, opts)
 
@@ -165,7 +211,7 @@ func (*MutationResolver) ReportUser(ctx context.Context, args struct {
 	_, err = mongo.Collection(mongo.CollectionNameAudit).InsertOne(ctx, &datastructure.AuditLog{
 		Type:      datastructure.AuditLogTypeReport,
 		CreatedBy: usr.ID,
-		Target:    &datastructure.Target{ID: &id, Type: "emotes"},
+		Target:    &datastructure.Target{ID: &id, Type: "users"},
 		Changes:   nil,
 		Reason:    args.Reason,
 	})
@@ -179,3 +225,48 @@ func (*MutationResolver) ReportUser(ctx context.Context, args struct {
 		Message: "success",
 	}, nil
 }
+
+// parseReportDetails: Validate the category, reason and evidence attached to a report.
+// A report under OTHER must explain itself with a reason
+func parseReportDetails(category string, reason *string, input *reportEvidenceInput) (datastructure.ReportCategory, *datastructure.ReportEvidence, error) {
+	result := datastructure.ReportCategory(category)
+	if result == datastructure.ReportCategoryOther && (reason == nil || strings.TrimSpace(*reason) == "") {
+		return "", nil, resolvers.ErrNoReason
+	}
+
+	if input == nil {
+		return result, nil, nil
+	}
+
+	evidence := &datastructure.ReportEvidence{}
+	if input.Links != nil {
+		if len(*input.Links) > MAX_REPORT_EVIDENCE_LINKS {
+			return "", nil, resolvers.ErrInvalidEvidence
+		}
+		for _, link := range *input.Links {
+			link = strings.TrimSpace(link)
+			if len(link) > MAX_REPORT_EVIDENCE_LINK_LENGTH {
+				return "", nil, resolvers.ErrInvalidEvidence
+			}
+			u, err := url.Parse(link)
+			if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				return "", nil, resolvers.ErrInvalidEvidence
+			}
+			evidence.Links = append(evidence.Links, u.String())
+		}
+	}
+	if input.ChatLog != nil {
+		chatLog := strings.TrimSpace(*input.ChatLog)
+		if len(chatLog) > MAX_REPORT_CHAT_LOG_LENGTH {
+			return "", nil, resolvers.ErrInvalidEvidence
+		}
+		if chatLog != "" {
+			evidence.ChatLog = &chatLog
+		}
+	}
+
+	if len(evidence.Links) == 0 && evidence.ChatLog == nil {
+		return result, nil, nil
+	}
+	return result, evidence, nil
+}
@@ -98,6 +98,7 @@ func (*MutationResolver) EditUser(ctx context.Context, args struct {
 			})
 			notifications = append(notifications, actions.Notifications.Create().
 				SetTitle("Role Changed").
+				SetCategory(datastructure.NotificationCategoryAccount).
 				AddTargetUsers(targetID).
 				AddTextMessagePart("Your global role was changed from ").
 				AddTextMessagePart(datastructure.GetRole(target.RoleID).Name).
@@ -129,6 +130,7 @@ func (*MutationResolver) EditUser(ctx context.Context, args struct {
 		})
 		notifications = append(notifications, actions.Notifications.Create().
 			SetTitle("Maximum Channel Emote Slots Changed").
+			SetCategory(datastructure.NotificationCategoryAccount).
 			AddTargetUsers(targetID).
 			AddTextMessagePart("Your channel emote slots ").
 			AddTextMessagePart(utils.Ternary(target.EmoteSlots > slots, "were reduced", "rose to").(string)).
@@ -200,6 +202,10 @@ func (*MutationResolver) EditUser(ctx context.Context, args struct {
 		}
 	}
 
+	if req.CosmeticPaint != nil || req.CosmeticBadge != nil {
+		actions.Cosmetics.InvalidateUsers(ctx, targetID)
+	}
+
 	field, failed := query_resolvers.GenerateSelectedFieldMap(ctx, resolvers.MaxDepth)
 	if failed {
 		return nil, resolvers.ErrDepth
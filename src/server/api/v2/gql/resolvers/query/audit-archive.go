@@ -0,0 +1,55 @@
+package query_resolvers
+
+import (
+	"context"
+	"time"
+
+	"github.com/SevenTV/ServerGo/src/mongo/datastructure"
+)
+
+type auditArchiveResolver struct {
+	ctx context.Context
+	v   *datastructure.AuditArchive
+
+	fields map[string]*SelectedField
+}
+
+func GenerateAuditArchiveResolver(ctx context.Context, archive *datastructure.AuditArchive, fields map[string]*SelectedField) (*auditArchiveResolver, error) {
+	return &auditArchiveResolver{
+		ctx:    ctx,
+		v:      archive,
+		fields: fields,
+	}, nil
+}
+
+func (r *auditArchiveResolver) ID() string {
+	return r.v.ID.Hex()
+}
+
+func (r *auditArchiveResolver) From() string {
+	return r.v.From.Format(time.RFC3339)
+}
+
+func (r *auditArchiveResolver) To() string {
+	return r.v.To.Format(time.RFC3339)
+}
+
+func (r *auditArchiveResolver) Count() int32 {
+	return r.v.Count
+}
+
+func (r *auditArchiveResolver) Size() float64 {
+	return float64(r.v.Size)
+}
+
+func (r *auditArchiveResolver) Types() []int32 {
+	return r.v.Types
+}
+
+func (r *auditArchiveResolver) TargetTypes() []string {
+	return r.v.TargetTypes
+}
+
+func (r *auditArchiveResolver) CreatedAt() string {
+	return r.v.ID.Timestamp().Format(time.RFC3339)
+}
@@ -6,6 +6,7 @@ import (
 
 	"github.com/SevenTV/ServerGo/src/mongo"
 	"github.com/SevenTV/ServerGo/src/mongo/datastructure"
+	"github.com/SevenTV/ServerGo/src/server/api/actions"
 	"github.com/SevenTV/ServerGo/src/server/api/v2/gql/resolvers"
 	"github.com/SevenTV/ServerGo/src/utils"
 	"github.com/hashicorp/go-multierror"
@@ -100,6 +101,14 @@ func (r *auditResolver) Reason() *string {
 	return r.v.Reason
 }
 
+func (r *auditResolver) RevertOf() *string {
+	if r.v.RevertOf == nil {
+		return nil
+	}
+	hex := r.v.RevertOf.Hex()
+	return &hex
+}
+
 func (r *auditResolver) CreatedBy() string {
 	return r.v.CreatedBy.Hex()
 }
@@ -180,3 +189,141 @@ type auditTargetEmote struct {
 	ID   primitive.ObjectID `bson:"_id" id:"id"`
 	Name string             `bson:"name" json:"name"`
 }
+
+// The amount of audit entries listed on a single emote
+const auditEntriesLimit = 20
+
+// fetchEmoteAuditEntries: Load the latest audit entries of many emotes at once
+func fetchEmoteAuditEntries(ctx context.Context, emotes map[primitive.ObjectID]*datastructure.Emote) error {
+	ids := make([]primitive.ObjectID, 0, len(emotes))
+	for id, e := range emotes {
+		ids = append(ids, id)
+		e.AuditEntries = &[]*datastructure.AuditLog{}
+	}
+
+	cur, err := mongo.Collection(mongo.CollectionNameEmotes).Aggregate(ctx, mongo.Pipeline{
+		bson.D{bson.E{
+			Key:   "$match",
+			Value: bson.M{"_id": bson.M{"$in": ids}},
+		}},
+		// Lookup the latest entries of each emote separately, so a single emote can't fill the result
+		bson.D{bson.E{
+			Key: "$lookup",
+			Value: bson.M{
+				"from": mongo.CollectionNameAudit,
+				"let":  bson.M{"emote_id": "$_id"},
+				"pipeline": mongo.Pipeline{
+					bson.D{bson.E{
+						Key: "$match",
+						Value: bson.M{
+							"target.type": "emotes",
+							"$expr":       bson.M{"$eq": bson.A{"$target.id", "$$emote_id"}},
+						},
+					}},
+					bson.D{bson.E{Key: "$sort", Value: bson.M{"_id": -1}}},
+					bson.D{bson.E{Key: "$limit", Value: auditEntriesLimit}},
+				},
+				"as": "audit_entries",
+			},
+		}},
+		bson.D{bson.E{
+			Key:   "$project",
+			Value: bson.M{"audit_entries": 1},
+		}},
+	})
+	if err != nil {
+		return err
+	}
+
+	result := []struct {
+		ID           primitive.ObjectID        `bson:"_id"`
+		AuditEntries []*datastructure.AuditLog `bson:"audit_entries"`
+	}{}
+	if err := cur.All(ctx, &result); err != nil {
+		return err
+	}
+
+	for i := range result {
+		if e, ok := emotes[result[i].ID]; ok {
+			e.AuditEntries = &result[i].AuditEntries
+		}
+	}
+	return nil
+}
+
+// parseAuditLogFilter: Create an audit log filter from query arguments
+func parseAuditLogFilter(types *[]int32, actorID, targetID, targetType, after, before, cursor *string) (*actions.AuditLogFilter, error) {
+	filter := &actions.AuditLogFilter{
+		TargetType: targetType,
+	}
+	if types != nil {
+		filter.Types = *types
+	}
+	if actorID != nil {
+		id, err := primitive.ObjectIDFromHex(*actorID)
+		if err != nil {
+			return nil, resolvers.ErrUnknownUser
+		}
+		filter.ActorID = &id
+	}
+	if targetID != nil {
+		id, err := primitive.ObjectIDFromHex(*targetID)
+		if err != nil {
+			return nil, resolvers.ErrInvalidTarget
+		}
+		filter.TargetID = &id
+	}
+	if cursor != nil {
+		id, err := primitive.ObjectIDFromHex(*cursor)
+		if err != nil {
+			return nil, resolvers.ErrInvalidCursor
+		}
+		filter.Cursor = &id
+	}
+	if after != nil {
+		t, err := time.Parse("2006-01-02T15:04:05.999Z07:00", *after)
+		if err != nil {
+			return nil, resolvers.ErrInvalidDate
+		}
+		filter.After = &t
+	}
+	if before != nil {
+		t, err := time.Parse("2006-01-02T15:04:05.999Z07:00", *before)
+		if err != nil {
+			return nil, resolvers.ErrInvalidDate
+		}
+		filter.Before = &t
+	}
+
+	return filter, nil
+}
+
+// The maximum amount of archives that can be downloaded by a single query
+const MAX_REHYDRATED_ARCHIVES = 10
+
+// auditArchiveQuery: Create the query matching the archives which may contain entries matching a filter
+func auditArchiveQuery(filter *actions.AuditLogFilter) bson.M {
+	query := bson.M{}
+	if len(filter.Types) > 0 {
+		query["types"] = bson.M{"$in": filter.Types}
+	}
+	if filter.ActorID != nil {
+		query["actor_ids"] = *filter.ActorID
+	}
+	if filter.TargetID != nil {
+		query["target_ids"] = *filter.TargetID
+	}
+	if filter.TargetType != nil {
+		query["target_types"] = *filter.TargetType
+	}
+
+	// Find archives overlapping the time range
+	if filter.After != nil {
+		query["to"] = bson.M{"$gte": filter.After.Truncate(time.Second)}
+	}
+	if filter.Before != nil {
+		query["from"] = bson.M{"$lt": *filter.Before}
+	}
+
+	return query
+}
@@ -0,0 +1,95 @@
+package query_resolvers
+
+import (
+	"context"
+	"time"
+
+	"github.com/SevenTV/ServerGo/src/mongo"
+	"github.com/SevenTV/ServerGo/src/mongo/datastructure"
+	"github.com/SevenTV/ServerGo/src/server/api/v2/gql/resolvers"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+type banAppealResolver struct {
+	ctx context.Context
+	v   *datastructure.BanAppeal
+
+	fields map[string]*SelectedField
+}
+
+func GenerateBanAppealResolver(ctx context.Context, appeal *datastructure.BanAppeal, fields map[string]*SelectedField) (*banAppealResolver, error) {
+	if _, ok := fields["ban"]; ok && appeal.Ban == nil {
+		ban := &datastructure.Ban{}
+		res := mongo.Collection(mongo.CollectionNameBans).FindOne(ctx, bson.M{
+			"_id": appeal.BanID,
+		})
+		err := res.Err()
+		if err == nil {
+			err = res.Decode(ban)
+		}
+		if err != nil && err != mongo.ErrNoDocuments {
+			logrus.WithError(err).Error("mongo")
+			return nil, resolvers.ErrInternalServer
+		}
+		if err == nil {
+			appeal.Ban = ban
+		}
+	}
+
+	return &banAppealResolver{
+		ctx:    ctx,
+		v:      appeal,
+		fields: fields,
+	}, nil
+}
+
+func (r *banAppealResolver) ID() string {
+	return r.v.ID.Hex()
+}
+
+func (r *banAppealResolver) BanID() string {
+	return r.v.BanID.Hex()
+}
+
+func (r *banAppealResolver) Ban() (*banResolver, error) {
+	if r.v.Ban == nil {
+		return nil, nil
+	}
+	return GenerateBanResolver(r.ctx, r.v.Ban, r.fields["ban"].Children)
+}
+
+func (r *banAppealResolver) User() (*UserResolver, error) {
+	return GenerateUserResolver(r.ctx, nil, &r.v.UserID, r.fields["user"].Children)
+}
+
+func (r *banAppealResolver) Message() string {
+	return r.v.Message
+}
+
+func (r *banAppealResolver) Status() string {
+	return string(r.v.Status)
+}
+
+func (r *banAppealResolver) Reviewer() (*UserResolver, error) {
+	if r.v.ReviewerID == nil {
+		return nil, nil
+	}
+	return GenerateUserResolver(r.ctx, nil, r.v.ReviewerID, r.fields["reviewer"].Children)
+}
+
+func (r *banAppealResolver) ReviewNote() *string {
+	return r.v.ReviewNote
+}
+
+func (r *banAppealResolver) ReviewedAt() *string {
+	if r.v.ReviewedAt == nil {
+		return nil
+	}
+	date := r.v.ReviewedAt.Format(time.RFC3339)
+	return &date
+}
+
+func (r *banAppealResolver) CreatedAt() string {
+	return r.v.ID.Timestamp().Format(time.RFC3339)
+}
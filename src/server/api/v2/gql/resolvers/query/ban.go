@@ -39,7 +39,19 @@ func (r *banResolver) Reason() string {
 }
 
 func (r *banResolver) Active() bool {
-	return r.v.ExpireAt.After(time.Now())
+	return r.v.IsActive()
+}
+
+func (r *banResolver) Type() string {
+	return string(r.v.GetType())
+}
+
+func (r *banResolver) ExpireAt() *string {
+	if r.v.ExpireAt == nil {
+		return nil
+	}
+	s := r.v.ExpireAt.Format(time.RFC3339)
+	return &s
 }
 
 func (r *banResolver) IssuedByID() *string {
@@ -8,38 +8,42 @@ import (
 	"go.mongodb.org/mongo-driver/bson"
 )
 
-type cosmeticResolver struct {
+type CosmeticResolver struct {
 	ctx context.Context
 	v   *datastructure.Cosmetic
 
 	fields map[string]*SelectedField
 }
 
-func GenerateCosmeticResolver(ctx context.Context, cos *datastructure.Cosmetic, fields map[string]*SelectedField) *cosmeticResolver {
-	return &cosmeticResolver{
+func GenerateCosmeticResolver(ctx context.Context, cos *datastructure.Cosmetic, fields map[string]*SelectedField) *CosmeticResolver {
+	return &CosmeticResolver{
 		ctx:    ctx,
 		v:      cos,
 		fields: fields,
 	}
 }
 
-func (r *cosmeticResolver) ID() string {
+func (r *CosmeticResolver) ID() string {
 	return r.v.ID.Hex()
 }
 
-func (r *cosmeticResolver) Kind() string {
+func (r *CosmeticResolver) Kind() string {
 	return string(r.v.Kind)
 }
 
-func (r *cosmeticResolver) Name() string {
+func (r *CosmeticResolver) Name() string {
 	return r.v.Name
 }
 
-func (r *cosmeticResolver) Selected() bool {
+func (r *CosmeticResolver) Priority() int32 {
+	return int32(r.v.Priority)
+}
+
+func (r *CosmeticResolver) Selected() bool {
 	return r.v.Selected
 }
 
-func (r *cosmeticResolver) Data() (string, error) {
+func (r *CosmeticResolver) Data() (string, error) {
 	if r.v.Data == nil {
 		return "{}", nil
 	}
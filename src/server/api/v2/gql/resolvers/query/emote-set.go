@@ -0,0 +1,109 @@
+package query_resolvers
+
+import (
+	"context"
+
+	"github.com/SevenTV/ServerGo/src/mongo"
+	"github.com/SevenTV/ServerGo/src/mongo/datastructure"
+	"github.com/SevenTV/ServerGo/src/server/api/v2/gql/resolvers"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+type EmoteSetResolver struct {
+	ctx    context.Context
+	v      *datastructure.EmoteSet
+	active bool
+	// The amount of emotes the set can hold, given its owner's channel emote slots
+	slots int32
+
+	fields map[string]*SelectedField
+}
+
+func GenerateEmoteSetResolver(ctx context.Context, set *datastructure.EmoteSet, active bool, slots int32, fields map[string]*SelectedField) *EmoteSetResolver {
+	return &EmoteSetResolver{
+		ctx:    ctx,
+		v:      set,
+		active: active,
+		slots:  slots,
+		fields: fields,
+	}
+}
+
+func (r *EmoteSetResolver) ID() string {
+	return r.v.ID.Hex()
+}
+
+func (r *EmoteSetResolver) OwnerID() string {
+	return r.v.OwnerID.Hex()
+}
+
+func (r *EmoteSetResolver) Name() string {
+	return r.v.Name
+}
+
+func (r *EmoteSetResolver) Active() bool {
+	return r.active
+}
+
+func (r *EmoteSetResolver) Slots() int32 {
+	return r.slots
+}
+
+func (r *EmoteSetResolver) EmoteIDs() []string {
+	ids := make([]string, len(r.v.EmoteIDs))
+	for i, id := range r.v.EmoteIDs {
+		ids[i] = id.Hex()
+	}
+	return ids
+}
+
+func (r *EmoteSetResolver) EmoteAliases() [][]string {
+	result := make([][]string, len(r.v.EmoteAlias))
+
+	i := 0
+	for id, name := range r.v.EmoteAlias {
+		result[i] = []string{id, name}
+		i++
+	}
+
+	return result
+}
+
+func (r *EmoteSetResolver) Emotes() ([]*EmoteResolver, error) {
+	var fields map[string]*SelectedField
+	if f, ok := r.fields["emotes"]; ok {
+		fields = f.Children
+	}
+
+	emotes := []*datastructure.Emote{}
+	if len(r.v.EmoteIDs) > 0 {
+		cur, err := mongo.Collection(mongo.CollectionNameEmotes).Find(r.ctx, bson.M{
+			"_id":    bson.M{"$in": r.v.EmoteIDs},
+			"status": datastructure.EmoteStatusLive,
+		})
+		if err == nil {
+			err = cur.All(r.ctx, &emotes)
+		}
+		if err != nil {
+			logrus.WithError(err).Error("mongo")
+			return nil, resolvers.ErrInternalServer
+		}
+	}
+
+	// Find aliases and replace
+	emotes = datastructure.UserUtil.GetAliasedEmotes(&datastructure.User{Emotes: &emotes, EmoteAlias: r.v.EmoteAlias})
+
+	result := []*EmoteResolver{}
+	for _, e := range emotes {
+		er, err := GenerateEmoteResolver(r.ctx, e, nil, fields)
+		if err != nil {
+			logrus.WithError(err).Error("generation")
+			return nil, resolvers.ErrInternalServer
+		}
+		if er != nil {
+			result = append(result, er)
+		}
+	}
+	return result, nil
+}
@@ -53,7 +53,7 @@ func GenerateEmoteResolver(ctx context.Context, emote *datastructure.Emote, emot
 			cur, err := mongo.Collection(mongo.CollectionNameAudit).Find(ctx, bson.M{
 				"target.id":   emote.ID,
 				"target.type": "emotes",
-			})
+			}, options.Find().SetSort(bson.M{"_id": -1}).SetLimit(auditEntriesLimit))
 			if err == nil {
 				err = cur.All(ctx, emote.AuditEntries)
 			}
@@ -85,7 +85,7 @@ func GenerateEmoteResolver(ctx context.Context, emote *datastructure.Emote, emot
 	}
 
 	usr, usrValid := ctx.Value(utils.UserKey).(*datastructure.User)
-	if v, ok := fields["reports"]; ok && usrValid && (usr.Rank != datastructure.UserRankAdmin && usr.Rank != datastructure.UserRankModerator) && emote.Reports == nil {
+	if v, ok := fields["reports"]; ok && usrValid && usr.HasPermission(datastructure.RolePermissionManageReports) && emote.Reports == nil {
 		emote.Reports = &[]*datastructure.Report{}
 		cur, err := mongo.Collection(mongo.CollectionNameReports).Find(ctx, bson.M{
 			"target.id":   emote.ID,
@@ -198,14 +198,16 @@ func (r *EmoteResolver) Owner() (*UserResolver, error) {
 
 func (r *EmoteResolver) AuditEntries() (*[]*auditResolver, error) {
 	var logs []*datastructure.AuditLog
-	if cur, err := mongo.Collection(mongo.CollectionNameAudit).Find(r.ctx, bson.M{
+	if r.v.AuditEntries != nil {
+		logs = *r.v.AuditEntries
+	} else if cur, err := mongo.Collection(mongo.CollectionNameAudit).Find(r.ctx, bson.M{
 		"target.type": "emotes",
 		"target.id":   r.v.ID,
 	}, &options.FindOptions{
 		Sort: bson.M{
 			"_id": -1,
 		},
-		Limit: utils.Int64Pointer(20),
+		Limit: utils.Int64Pointer(auditEntriesLimit),
 	}); err != nil {
 		logrus.WithError(err).Error("mongo")
 		return nil, resolvers.ErrInternalServer
@@ -313,9 +315,9 @@ func (r *EmoteResolver) ChannelCount() int32 {
 	return *r.v.ChannelCount
 }
 
-func (r *EmoteResolver) Reports() (*[]*reportResolver, error) {
+func (r *EmoteResolver) Reports() (*[]*ReportResolver, error) {
 	u, ok := r.ctx.Value(utils.UserKey).(*datastructure.User)
-	if !ok || (u.Rank != datastructure.UserRankAdmin && u.Rank != datastructure.UserRankModerator) {
+	if !ok || !u.HasPermission(datastructure.RolePermissionManageReports) {
 		return nil, resolvers.ErrAccessDenied
 	}
 
@@ -324,7 +326,7 @@ func (r *EmoteResolver) Reports() (*[]*reportResolver, error) {
 	}
 
 	e := *r.v.Reports
-	reports := make([]*reportResolver, len(e))
+	reports := make([]*ReportResolver, len(e))
 	var err error
 	for i, l := range e {
 		reports[i], err = GenerateReportResolver(r.ctx, l, r.fields["reports"].Children)
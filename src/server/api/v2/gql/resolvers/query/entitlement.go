@@ -0,0 +1,290 @@
+package query_resolvers
+
+import (
+	"context"
+	"time"
+
+	"github.com/SevenTV/ServerGo/src/mongo"
+	"github.com/SevenTV/ServerGo/src/mongo/datastructure"
+	"github.com/SevenTV/ServerGo/src/server/api/actions"
+	"github.com/SevenTV/ServerGo/src/server/api/v2/gql/resolvers"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type entitlementResolver struct {
+	ctx context.Context
+	v   *datastructure.Entitlement
+	// The roles of the user's active role entitlements, to evaluate the role binding with
+	roles []primitive.ObjectID
+
+	fields map[string]*SelectedField
+}
+
+func GenerateEntitlementResolver(ctx context.Context, e *datastructure.Entitlement, roles []primitive.ObjectID, fields map[string]*SelectedField) (*entitlementResolver, error) {
+	return &entitlementResolver{
+		ctx:    ctx,
+		v:      e,
+		roles:  roles,
+		fields: fields,
+	}, nil
+}
+
+func (r *entitlementResolver) ID() string {
+	return r.v.ID.Hex()
+}
+
+func (r *entitlementResolver) Kind() string {
+	return string(r.v.Kind)
+}
+
+func (r *entitlementResolver) UserID() string {
+	return r.v.UserID.Hex()
+}
+
+func (r *entitlementResolver) User() (*UserResolver, error) {
+	var fields map[string]*SelectedField
+	if f, ok := r.fields["user"]; ok {
+		fields = f.Children
+	}
+	return GenerateUserResolver(r.ctx, nil, &r.v.UserID, fields)
+}
+
+func (r *entitlementResolver) Disabled() bool {
+	return r.v.Disabled
+}
+
+func (r *entitlementResolver) Active() bool {
+	return r.v.IsActive(time.Now())
+}
+
+func (r *entitlementResolver) StartsAt() *string {
+	if r.v.StartsAt == nil {
+		return nil
+	}
+	date := r.v.StartsAt.Format(time.RFC3339)
+	return &date
+}
+
+func (r *entitlementResolver) EndsAt() *string {
+	if r.v.EndsAt == nil {
+		return nil
+	}
+	date := r.v.EndsAt.Format(time.RFC3339)
+	return &date
+}
+
+func (r *entitlementResolver) SubscriptionID() *string {
+	if r.v.SubscriptionID == nil {
+		return nil
+	}
+	id := r.v.SubscriptionID.Hex()
+	return &id
+}
+
+func (r *entitlementResolver) Data() *entitlementDataResolver {
+	var fields map[string]*SelectedField
+	if f, ok := r.fields["data"]; ok {
+		fields = f.Children
+	}
+	return &entitlementDataResolver{
+		ctx:    r.ctx,
+		e:      r.v,
+		roles:  r.roles,
+		fields: fields,
+	}
+}
+
+// entitlementDataResolver: The decoded data of an entitlement, fields which don't apply to its kind are null
+type entitlementDataResolver struct {
+	ctx   context.Context
+	e     *datastructure.Entitlement
+	roles []primitive.ObjectID
+
+	fields map[string]*SelectedField
+}
+
+func (r *entitlementDataResolver) Ref() *string {
+	ref, ok := r.e.Data.Lookup("ref").ObjectIDOK()
+	if !ok {
+		return nil
+	}
+	id := ref.Hex()
+	return &id
+}
+
+func (r *entitlementDataResolver) Cosmetic() (*CosmeticResolver, error) {
+	if r.e.Kind != datastructure.EntitlementKindBadge && r.e.Kind != datastructure.EntitlementKindPaint {
+		return nil, nil
+	}
+	ref, ok := r.e.Data.Lookup("ref").ObjectIDOK()
+	if !ok {
+		return nil, nil
+	}
+
+	cos := &datastructure.Cosmetic{}
+	if err := mongo.Collection(mongo.CollectionNameCosmetics).FindOne(r.ctx, bson.M{"_id": ref}).Decode(cos); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		logrus.WithError(err).Error("mongo")
+		return nil, resolvers.ErrInternalServer
+	}
+
+	var fields map[string]*SelectedField
+	if f, ok := r.fields["cosmetic"]; ok {
+		fields = f.Children
+	}
+	return GenerateCosmeticResolver(r.ctx, cos, fields), nil
+}
+
+func (r *entitlementDataResolver) Role() (*RoleResolver, error) {
+	if r.e.Kind != datastructure.EntitlementKindRole {
+		return nil, nil
+	}
+	ref, ok := r.e.Data.Lookup("ref").ObjectIDOK()
+	if !ok {
+		return nil, nil
+	}
+
+	var fields map[string]*SelectedField
+	if f, ok := r.fields["role"]; ok {
+		fields = f.Children
+	}
+	return GenerateRoleResolver(r.ctx, nil, &ref, fields)
+}
+
+func (r *entitlementDataResolver) Subscription() (*entitlementSubscriptionResolver, error) {
+	if r.e.Kind != datastructure.EntitlementKindSubscription {
+		return nil, nil
+	}
+	ref, ok := r.e.Data.Lookup("ref").ObjectIDOK()
+	if !ok {
+		return nil, nil
+	}
+
+	sub := &datastructure.Subscription{}
+	if err := mongo.Collection(mongo.CollectionNameSubscriptions).FindOne(r.ctx, bson.M{"_id": ref}).Decode(sub); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		logrus.WithError(err).Error("mongo")
+		return nil, resolvers.ErrInternalServer
+	}
+
+	return &entitlementSubscriptionResolver{v: sub}, nil
+}
+
+func (r *entitlementDataResolver) Selected() *bool {
+	selected, ok := r.e.Data.Lookup("selected").BooleanOK()
+	if !ok {
+		return nil
+	}
+	return &selected
+}
+
+func (r *entitlementDataResolver) RoleBindingID() *string {
+	id, ok := r.e.Data.Lookup("role_binding").ObjectIDOK()
+	if !ok {
+		return nil
+	}
+	hex := id.Hex()
+	return &hex
+}
+
+func (r *entitlementDataResolver) RoleBinding() (*RoleResolver, error) {
+	id, ok := r.e.Data.Lookup("role_binding").ObjectIDOK()
+	if !ok {
+		return nil, nil
+	}
+
+	var fields map[string]*SelectedField
+	if f, ok := r.fields["role_binding"]; ok {
+		fields = f.Children
+	}
+	return GenerateRoleResolver(r.ctx, nil, &id, fields)
+}
+
+// RoleBindingSatisfied: Whether the user is entitled to the role the entitlement is bound to,
+// which is required for a badge or paint to show up. Null if the entitlement isn't bound to a role
+func (r *entitlementDataResolver) RoleBindingSatisfied() *bool {
+	bound, satisfied := actions.Entitlements.EvaluateRoleBinding(r.e, r.roles)
+	if !bound {
+		return nil
+	}
+	return &satisfied
+}
+
+func (r *entitlementDataResolver) UnicodeTag() *string {
+	tag, ok := r.e.Data.Lookup("unicode_tag").StringValueOK()
+	if !ok {
+		return nil
+	}
+	return &tag
+}
+
+func (r *entitlementDataResolver) EmoteIDs() *[]string {
+	if r.e.Kind != datastructure.EntitlementKindEmoteSet {
+		return nil
+	}
+	data := datastructure.EntitledEmoteSet{}
+	if err := bson.Unmarshal(r.e.Data, &data); err != nil {
+		return nil
+	}
+
+	ids := make([]string, len(data.EmoteIDs))
+	for i, id := range data.EmoteIDs {
+		ids[i] = id.Hex()
+	}
+	return &ids
+}
+
+func (r *entitlementDataResolver) Slots() *int32 {
+	v, err := r.e.Data.LookupErr("slots")
+	if err != nil {
+		return nil
+	}
+	slots, ok := v.AsInt64OK()
+	if !ok {
+		return nil
+	}
+	i := int32(slots)
+	return &i
+}
+
+type entitlementSubscriptionResolver struct {
+	v *datastructure.Subscription
+}
+
+func (r *entitlementSubscriptionResolver) ID() string {
+	return r.v.ID.Hex()
+}
+
+func (r *entitlementSubscriptionResolver) TierID() string {
+	return r.v.TierID
+}
+
+func (r *entitlementSubscriptionResolver) Provider() string {
+	return r.v.Provider
+}
+
+func (r *entitlementSubscriptionResolver) Status() string {
+	return string(r.v.Status)
+}
+
+func (r *entitlementSubscriptionResolver) StartedAt() string {
+	return r.v.StartedAt.Format(time.RFC3339)
+}
+
+func (r *entitlementSubscriptionResolver) CurrentPeriodEnd() string {
+	return r.v.CurrentPeriodEnd.Format(time.RFC3339)
+}
+
+func (r *entitlementSubscriptionResolver) EndedAt() *string {
+	if r.v.EndedAt == nil {
+		return nil
+	}
+	date := r.v.EndedAt.Format(time.RFC3339)
+	return &date
+}
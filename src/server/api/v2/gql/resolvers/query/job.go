@@ -0,0 +1,67 @@
+package query_resolvers
+
+import (
+	"context"
+	"time"
+
+	"github.com/SevenTV/ServerGo/src/mongo/datastructure"
+)
+
+type jobResolver struct {
+	ctx context.Context
+	v   *datastructure.Job
+
+	fields map[string]*SelectedField
+}
+
+func GenerateJobResolver(ctx context.Context, job *datastructure.Job, fields map[string]*SelectedField) (*jobResolver, error) {
+	return &jobResolver{
+		ctx:    ctx,
+		v:      job,
+		fields: fields,
+	}, nil
+}
+
+func (r *jobResolver) ID() string {
+	return r.v.ID.Hex()
+}
+
+func (r *jobResolver) Kind() string {
+	return string(r.v.Kind)
+}
+
+func (r *jobResolver) Status() string {
+	return string(r.v.Status)
+}
+
+func (r *jobResolver) CreatedBy() (*UserResolver, error) {
+	return GenerateUserResolver(r.ctx, nil, &r.v.CreatedBy, r.fields["created_by"].Children)
+}
+
+func (r *jobResolver) Total() int32 {
+	return r.v.Total
+}
+
+func (r *jobResolver) Processed() int32 {
+	return r.v.Processed
+}
+
+func (r *jobResolver) Failed() int32 {
+	return r.v.Failed
+}
+
+func (r *jobResolver) Error() *string {
+	return r.v.Error
+}
+
+func (r *jobResolver) CreatedAt() string {
+	return r.v.ID.Timestamp().Format(time.RFC3339)
+}
+
+func (r *jobResolver) FinishedAt() *string {
+	if r.v.FinishedAt == nil {
+		return nil
+	}
+	date := r.v.FinishedAt.Format(time.RFC3339)
+	return &date
+}
@@ -7,6 +7,7 @@ import (
 
 	"github.com/SevenTV/ServerGo/src/mongo/datastructure"
 	"github.com/SevenTV/ServerGo/src/server/api/actions"
+	"github.com/SevenTV/ServerGo/src/utils"
 	"github.com/sirupsen/logrus"
 )
 
@@ -37,6 +38,63 @@ func (r *NotificationResolver) Title() string {
 	return r.v.Title
 }
 
+func (r *NotificationResolver) Category() *string {
+	if r.v.Category == "" {
+		return nil
+	}
+
+	category := string(r.v.Category)
+	return &category
+}
+
+func (r *NotificationResolver) AuthorID() *string {
+	if r.v.AuthorID == nil {
+		return nil
+	}
+
+	id := r.v.AuthorID.Hex()
+	return &id
+}
+
+func (r *NotificationResolver) TargetIDs() *[]string {
+	// Only staff may see who a notification was sent to
+	if u, ok := r.ctx.Value(utils.UserKey).(*datastructure.User); !ok || !u.HasPermission(datastructure.RolePermissionSendNotifications) {
+		return nil
+	}
+
+	ids := make([]string, len(r.v.Targets))
+	for i, id := range r.v.Targets {
+		ids[i] = id.Hex()
+	}
+	return &ids
+}
+
+func (r *NotificationResolver) ScheduledAt() *string {
+	if r.v.ScheduledAt == nil {
+		return nil
+	}
+
+	date := r.v.ScheduledAt.Format(time.RFC3339)
+	return &date
+}
+
+func (r *NotificationResolver) ExpireAt() *string {
+	if r.v.ExpireAt == nil {
+		return nil
+	}
+
+	date := r.v.ExpireAt.Format(time.RFC3339)
+	return &date
+}
+
+func (r *NotificationResolver) Pending() bool {
+	return r.v.Pending
+}
+
+func (r *NotificationResolver) Retracted() bool {
+	return r.v.Retracted
+}
+
 func (r *NotificationResolver) Timestamp() string {
 	return r.v.ID.Timestamp().Format(time.RFC3339)
 }
@@ -0,0 +1,56 @@
+package query_resolvers
+
+import (
+	"context"
+
+	"github.com/SevenTV/ServerGo/src/mongo/datastructure"
+	"github.com/SevenTV/ServerGo/src/server/api/v2/gql/resolvers"
+	"github.com/sirupsen/logrus"
+)
+
+type PersonalEmoteSetResolver struct {
+	ctx context.Context
+	v   *datastructure.EntitledEmoteSet
+
+	fields map[string]*SelectedField
+}
+
+func GeneratePersonalEmoteSetResolver(ctx context.Context, set *datastructure.EntitledEmoteSet, fields map[string]*SelectedField) *PersonalEmoteSetResolver {
+	return &PersonalEmoteSetResolver{
+		ctx:    ctx,
+		v:      set,
+		fields: fields,
+	}
+}
+
+func (r *PersonalEmoteSetResolver) ID() string {
+	return r.v.ID
+}
+
+func (r *PersonalEmoteSetResolver) UnicodeTag() string {
+	return r.v.UnicodeTag
+}
+
+func (r *PersonalEmoteSetResolver) Slots() int32 {
+	return r.v.Slots
+}
+
+func (r *PersonalEmoteSetResolver) Emotes() ([]*EmoteResolver, error) {
+	var fields map[string]*SelectedField
+	if f, ok := r.fields["emotes"]; ok {
+		fields = f.Children
+	}
+
+	result := []*EmoteResolver{}
+	for _, e := range r.v.Emotes {
+		er, err := GenerateEmoteResolver(r.ctx, e, nil, fields)
+		if err != nil {
+			logrus.WithError(err).Error("generation")
+			return nil, resolvers.ErrInternalServer
+		}
+		if er != nil {
+			result = append(result, er)
+		}
+	}
+	return result, nil
+}
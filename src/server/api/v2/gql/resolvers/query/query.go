@@ -6,12 +6,14 @@ import (
 	"math"
 	"regexp"
 	"strings"
+	"time"
 
 	"github.com/SevenTV/ServerGo/src/cache"
 	"github.com/SevenTV/ServerGo/src/mongo"
 	mongocache "github.com/SevenTV/ServerGo/src/mongo/cache"
 	"github.com/SevenTV/ServerGo/src/mongo/datastructure"
 	"github.com/SevenTV/ServerGo/src/redis"
+	"github.com/SevenTV/ServerGo/src/server/api/actions"
 	"github.com/SevenTV/ServerGo/src/server/api/v2/gql/resolvers"
 	api_proxy "github.com/SevenTV/ServerGo/src/server/api/v2/proxy"
 	"github.com/SevenTV/ServerGo/src/utils"
@@ -68,10 +70,24 @@ func GenerateSelectedFieldMap(ctx context.Context, max int) (*SelectedField, boo
 }
 
 func (*QueryResolver) AuditLogs(ctx context.Context, args struct {
-	Page  int32
-	Limit *int32
-	Types *[]int32
+	Page       *int32
+	Limit      *int32
+	Types      *[]int32
+	ActorID    *string
+	TargetID   *string
+	TargetType *string
+	After      *string
+	Before     *string
+	Cursor     *string
 }) ([]*auditResolver, error) {
+	// Filtering by actor or target would list everything a given moderator did, which is only for staff
+	if args.ActorID != nil || args.TargetID != nil || args.TargetType != nil {
+		usr, _ := ctx.Value(utils.UserKey).(*datastructure.User)
+		if usr == nil || !usr.HasPermission(datastructure.RolePermissionManageReports) {
+			return nil, resolvers.ErrAccessDenied
+		}
+	}
+
 	var logs []*datastructure.AuditLog
 
 	// Find audit logs
@@ -80,18 +96,20 @@ func (*QueryResolver) AuditLogs(ctx context.Context, args struct {
 		limit = *args.Limit
 	}
 
-	query := bson.M{}
-	if args.Types != nil && len(*args.Types) > 0 {
-		query["type"] = bson.M{
-			"$in": *args.Types,
-		}
+	filter, err := parseAuditLogFilter(args.Types, args.ActorID, args.TargetID, args.TargetType, args.After, args.Before, args.Cursor)
+	if err != nil {
+		return nil, err
 	}
-	cur, err := mongo.Collection(mongo.CollectionNameAudit).Find(ctx, query, &options.FindOptions{
-		Limit: utils.Int64Pointer(int64(math.Min(250, float64(limit)))),
-		Sort: bson.M{
-			"_id": -1,
-		},
-	})
+
+	// Paginate by cursor when given, as pages shift when new entries are created
+	opts := options.Find().SetSort(bson.M{
+		"_id": -1,
+	}).SetLimit(int64(math.Min(250, float64(limit))))
+	if filter.Cursor == nil && args.Page != nil && *args.Page > 1 {
+		opts.SetSkip(int64(*args.Page-1) * *opts.Limit)
+	}
+
+	cur, err := mongo.Collection(mongo.CollectionNameAudit).Find(ctx, actions.Audit.Query(filter), opts)
 	if err == nil {
 		err = cur.All(ctx, &logs)
 	}
@@ -122,6 +140,125 @@ func (*QueryResolver) AuditLogs(ctx context.Context, args struct {
 	return resolvers, nil
 }
 
+func (*QueryResolver) AuditArchives(ctx context.Context, args struct {
+	Types    *[]int32
+	ActorID  *string
+	TargetID *string
+	After    *string
+	Before   *string
+	Page     *int32
+	Limit    *int32
+}) ([]*auditArchiveResolver, error) {
+	usr, _ := ctx.Value(utils.UserKey).(*datastructure.User)
+	if usr == nil || !usr.HasPermission(datastructure.RolePermissionAdministrator) {
+		return nil, resolvers.ErrAccessDenied
+	}
+
+	filter, err := parseAuditLogFilter(args.Types, args.ActorID, args.TargetID, nil, args.After, args.Before, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	limit := int64(20)
+	if args.Limit != nil {
+		limit = int64(*args.Limit)
+	}
+	if limit > resolvers.QueryLimit {
+		return nil, resolvers.ErrQueryLimit
+	}
+	page := int64(1)
+	if args.Page != nil && *args.Page > 1 {
+		page = int64(*args.Page)
+	}
+
+	opts := options.Find().SetSort(bson.M{
+		"_id": -1,
+	}).SetLimit(limit).SetSkip((page - 1) * limit)
+
+	archives := []*datastructure.AuditArchive{}
+	cur, err := mongo.Collection(mongo.CollectionNameAuditArchives).Find(ctx, auditArchiveQuery(filter), opts)
+	if err == nil {
+		err = cur.All(ctx, &archives)
+	}
+	if err != nil {
+		logrus.WithError(err).Error("mongo")
+		return nil, resolvers.ErrInternalServer
+	}
+
+	field, failed := GenerateSelectedFieldMap(ctx, resolvers.MaxDepth)
+	if failed {
+		return nil, resolvers.ErrDepth
+	}
+
+	resolvers := make([]*auditArchiveResolver, len(archives))
+	for i, a := range archives {
+		resolvers[i], err = GenerateAuditArchiveResolver(ctx, a, field.Children)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return resolvers, nil
+}
+
+func (*QueryResolver) ArchivedAuditLogs(ctx context.Context, args struct {
+	After      string
+	Before     string
+	Types      *[]int32
+	ActorID    *string
+	TargetID   *string
+	TargetType *string
+}) ([]*auditResolver, error) {
+	usr, _ := ctx.Value(utils.UserKey).(*datastructure.User)
+	if usr == nil || !usr.HasPermission(datastructure.RolePermissionAdministrator) {
+		return nil, resolvers.ErrAccessDenied
+	}
+
+	filter, err := parseAuditLogFilter(args.Types, args.ActorID, args.TargetID, args.TargetType, &args.After, &args.Before, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// Archives are downloaded in full, so limit how many can be read at once
+	archives := []*datastructure.AuditArchive{}
+	cur, err := mongo.Collection(mongo.CollectionNameAuditArchives).Find(ctx, auditArchiveQuery(filter), options.Find().SetSort(bson.M{
+		"_id": 1,
+	}).SetLimit(MAX_REHYDRATED_ARCHIVES+1))
+	if err == nil {
+		err = cur.All(ctx, &archives)
+	}
+	if err != nil {
+		logrus.WithError(err).Error("mongo")
+		return nil, resolvers.ErrInternalServer
+	}
+	if len(archives) > MAX_REHYDRATED_ARCHIVES {
+		return nil, resolvers.ErrTooManyArchives
+	}
+
+	logs := []*datastructure.AuditLog{}
+	for _, a := range archives {
+		l, err := actions.Audit.Rehydrate(ctx, a, filter)
+		if err != nil {
+			logrus.WithError(err).WithField("key", a.Key).Error("audit, could not rehydrate archive")
+			return nil, resolvers.ErrInternalServer
+		}
+		logs = append(logs, l...)
+	}
+
+	field, failed := GenerateSelectedFieldMap(ctx, resolvers.MaxDepth)
+	if failed {
+		return nil, resolvers.ErrDepth
+	}
+
+	resolvers := make([]*auditResolver, len(logs))
+	for i, l := range logs {
+		resolvers[i], err = GenerateAuditResolver(ctx, l, field.Children)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return resolvers, nil
+}
+
 func (*QueryResolver) User(ctx context.Context, args struct{ ID string }) (*UserResolver, error) {
 	isMe := args.ID == "@me" // Handle @me (current authenticated user)
 	user := &datastructure.User{}
@@ -630,6 +767,438 @@ func (*QueryResolver) SearchUsers(ctx context.Context, args struct {
 	return resolvers, nil
 }
 
+func (*QueryResolver) Reports(ctx context.Context, args struct {
+	Status     *datastructure.ReportStatus
+	Category   *datastructure.ReportCategory
+	TargetType *string
+	AssigneeID *string
+	After      *string
+	Before     *string
+	Page       *int32
+	Limit      *int32
+}) ([]*ReportResolver, error) {
+	usr, _ := ctx.Value(utils.UserKey).(*datastructure.User)
+	if usr == nil || !usr.HasPermission(datastructure.RolePermissionManageReports) {
+		return nil, resolvers.ErrAccessDenied
+	}
+
+	field, failed := GenerateSelectedFieldMap(ctx, resolvers.MaxDepth)
+	if failed {
+		return nil, resolvers.ErrDepth
+	}
+
+	limit := int64(20)
+	if args.Limit != nil {
+		limit = int64(*args.Limit)
+	}
+	if limit > resolvers.QueryLimit {
+		return nil, resolvers.ErrQueryLimit
+	}
+
+	// Pagination
+	page := int64(1)
+	if args.Page != nil && *args.Page > 1 {
+		page = int64(*args.Page)
+	}
+
+	match := reportQueueFilter(args.Status, args.TargetType)
+	if args.Category != nil {
+		if *args.Category == datastructure.ReportCategoryOther {
+			// Reports created before categories existed count as OTHER
+			match["category"] = bson.M{"$in": bson.A{*args.Category, nil}}
+		} else {
+			match["category"] = *args.Category
+		}
+	}
+	if args.AssigneeID != nil {
+		assigneeID, err := primitive.ObjectIDFromHex(*args.AssigneeID)
+		if err != nil {
+			return nil, resolvers.ErrUnknownUser
+		}
+		match["assignee_id"] = assigneeID
+	}
+
+	// Filter by age, using the creation time embedded in the object id
+	idRange := bson.M{}
+	if args.After != nil {
+		t, err := time.Parse("2006-01-02T15:04:05.999Z07:00", *args.After)
+		if err != nil {
+			return nil, resolvers.ErrInvalidDate
+		}
+		idRange["$gte"] = primitive.NewObjectIDFromTimestamp(t)
+	}
+	if args.Before != nil {
+		t, err := time.Parse("2006-01-02T15:04:05.999Z07:00", *args.Before)
+		if err != nil {
+			return nil, resolvers.ErrInvalidDate
+		}
+		idRange["$lt"] = primitive.NewObjectIDFromTimestamp(t)
+	}
+	if len(idRange) > 0 {
+		match["_id"] = idRange
+	}
+
+	// Oldest reports first, so the queue is worked in order
+	opts := options.Find().SetSort(bson.M{
+		"_id": 1,
+	}).SetLimit(limit).SetSkip((page - 1) * limit)
+
+	reports := []*datastructure.Report{}
+	cur, err := mongo.Collection(mongo.CollectionNameReports).Find(ctx, match, opts)
+	if err == nil {
+		err = cur.All(ctx, &reports)
+	}
+	if err != nil {
+		logrus.WithError(err).Error("mongo")
+		return nil, resolvers.ErrInternalServer
+	}
+
+	resolvers := make([]*ReportResolver, len(reports))
+	for i, r := range reports {
+		resolvers[i], err = GenerateReportResolver(ctx, r, field.Children)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return resolvers, nil
+}
+
+func (*QueryResolver) ReportCounts(ctx context.Context, args struct {
+	Status     *datastructure.ReportStatus
+	TargetType *string
+}) ([]*reportCategoryCountResolver, error) {
+	usr, _ := ctx.Value(utils.UserKey).(*datastructure.User)
+	if usr == nil || !usr.HasPermission(datastructure.RolePermissionManageReports) {
+		return nil, resolvers.ErrAccessDenied
+	}
+
+	cur, err := mongo.Collection(mongo.CollectionNameReports).Aggregate(ctx, mongo.Pipeline{
+		bson.D{
+			bson.E{Key: "$match", Value: reportQueueFilter(args.Status, args.TargetType)},
+		},
+		bson.D{
+			bson.E{
+				Key: "$group",
+				Value: bson.M{
+					"_id":   "$category",
+					"count": bson.M{"$sum": 1},
+				},
+			},
+		},
+	})
+	counts := []struct {
+		Category *datastructure.ReportCategory `bson:"_id"`
+		Count    int32                         `bson:"count"`
+	}{}
+	if err == nil {
+		err = cur.All(ctx, &counts)
+	}
+	if err != nil {
+		logrus.WithError(err).Error("mongo")
+		return nil, resolvers.ErrInternalServer
+	}
+
+	// Always list every category, reports created before categories existed count as OTHER
+	countMap := map[datastructure.ReportCategory]int32{}
+	for _, c := range counts {
+		category := datastructure.ReportCategoryOther
+		if c.Category != nil && *c.Category != "" {
+			category = *c.Category
+		}
+		countMap[category] += c.Count
+	}
+
+	result := make([]*reportCategoryCountResolver, len(datastructure.ReportCategories))
+	for i, category := range datastructure.ReportCategories {
+		result[i] = &reportCategoryCountResolver{
+			Category: string(category),
+			Count:    countMap[category],
+		}
+	}
+	return result, nil
+}
+
+// reportQueueFilter: Create the query matching reports in the moderation queue by status and target type
+func reportQueueFilter(status *datastructure.ReportStatus, targetType *string) bson.M {
+	match := bson.M{}
+	if status != nil {
+		switch *status {
+		case datastructure.ReportStatusOpen, datastructure.ReportStatusResolved:
+			// Reports created before statuses existed only have the cleared flag
+			match["$or"] = bson.A{
+				bson.M{"status": *status},
+				bson.M{"status": bson.M{"$exists": false}, "cleared": *status == datastructure.ReportStatusResolved},
+			}
+		default:
+			match["status"] = *status
+		}
+	}
+	if targetType != nil {
+		match["target.type"] = *targetType
+	}
+	return match
+}
+
+func (*QueryResolver) Job(ctx context.Context, args struct{ ID string }) (*jobResolver, error) {
+	usr, ok := ctx.Value(utils.UserKey).(*datastructure.User)
+	if !ok {
+		return nil, resolvers.ErrLoginRequired
+	}
+
+	id, err := primitive.ObjectIDFromHex(args.ID)
+	if err != nil {
+		return nil, nil
+	}
+
+	job := &datastructure.Job{}
+	res := mongo.Collection(mongo.CollectionNameJobs).FindOne(ctx, bson.M{
+		"_id": id,
+	})
+	err = res.Err()
+	if err == nil {
+		err = res.Decode(job)
+	}
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		logrus.WithError(err).Error("mongo")
+		return nil, resolvers.ErrInternalServer
+	}
+
+	// Jobs are only visible to whoever started them
+	if job.CreatedBy != usr.ID && !usr.HasPermission(datastructure.RolePermissionAdministrator) {
+		return nil, resolvers.ErrAccessDenied
+	}
+
+	field, failed := GenerateSelectedFieldMap(ctx, resolvers.MaxDepth)
+	if failed {
+		return nil, resolvers.ErrDepth
+	}
+
+	return GenerateJobResolver(ctx, job, field.Children)
+}
+
+func (*QueryResolver) BanAppeals(ctx context.Context, args struct {
+	Status *datastructure.BanAppealStatus
+	Page   *int32
+	Limit  *int32
+}) ([]*banAppealResolver, error) {
+	usr, _ := ctx.Value(utils.UserKey).(*datastructure.User)
+	if usr == nil || !usr.HasPermission(datastructure.RolePermissionBanUsers) {
+		return nil, resolvers.ErrAccessDenied
+	}
+
+	field, failed := GenerateSelectedFieldMap(ctx, resolvers.MaxDepth)
+	if failed {
+		return nil, resolvers.ErrDepth
+	}
+
+	limit := int64(20)
+	if args.Limit != nil {
+		limit = int64(*args.Limit)
+	}
+	if limit > resolvers.QueryLimit {
+		return nil, resolvers.ErrQueryLimit
+	}
+
+	// Pagination
+	page := int64(1)
+	if args.Page != nil && *args.Page > 1 {
+		page = int64(*args.Page)
+	}
+
+	// Pending appeals are shown by default, oldest first so the queue is worked in order
+	match := bson.M{"status": datastructure.BanAppealStatusPending}
+	if args.Status != nil {
+		match["status"] = *args.Status
+	}
+	opts := options.Find().SetSort(bson.M{
+		"_id": 1,
+	}).SetLimit(limit).SetSkip((page - 1) * limit)
+
+	appeals := []*datastructure.BanAppeal{}
+	cur, err := mongo.Collection(mongo.CollectionNameBanAppeals).Find(ctx, match, opts)
+	if err == nil {
+		err = cur.All(ctx, &appeals)
+	}
+	if err != nil {
+		logrus.WithError(err).Error("mongo")
+		return nil, resolvers.ErrInternalServer
+	}
+
+	resolvers := make([]*banAppealResolver, len(appeals))
+	for i, a := range appeals {
+		resolvers[i], err = GenerateBanAppealResolver(ctx, a, field.Children)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return resolvers, nil
+}
+
+func (*QueryResolver) ComposedNotifications(ctx context.Context, args struct {
+	Pending *bool
+	Page    *int32
+	Limit   *int32
+}) ([]*NotificationResolver, error) {
+	usr, _ := ctx.Value(utils.UserKey).(*datastructure.User)
+	if usr == nil || !usr.HasPermission(datastructure.RolePermissionSendNotifications) {
+		return nil, resolvers.ErrAccessDenied
+	}
+
+	field, failed := GenerateSelectedFieldMap(ctx, resolvers.MaxDepth)
+	if failed {
+		return nil, resolvers.ErrDepth
+	}
+
+	limit := int64(20)
+	if args.Limit != nil {
+		limit = int64(*args.Limit)
+	}
+	if limit > resolvers.QueryLimit {
+		return nil, resolvers.ErrQueryLimit
+	}
+
+	// Pagination
+	page := int64(1)
+	if args.Page != nil && *args.Page > 1 {
+		page = int64(*args.Page)
+	}
+
+	// Only notifications composed by staff, newest first
+	match := bson.M{"author_id": bson.M{"$exists": true}}
+	if args.Pending != nil {
+		if *args.Pending {
+			match["pending"] = true
+		} else {
+			match["pending"] = bson.M{"$ne": true}
+		}
+	}
+	opts := options.Find().SetSort(bson.M{
+		"_id": -1,
+	}).SetLimit(limit).SetSkip((page - 1) * limit)
+
+	notifications := []*datastructure.Notification{}
+	cur, err := mongo.Collection(mongo.CollectionNameNotifications).Find(ctx, match, opts)
+	if err == nil {
+		err = cur.All(ctx, &notifications)
+	}
+	if err != nil {
+		logrus.WithError(err).Error("mongo")
+		return nil, resolvers.ErrInternalServer
+	}
+
+	resolvers := make([]*NotificationResolver, len(notifications))
+	for i, n := range notifications {
+		resolvers[i], err = GenerateNotificationResolver(ctx, n, field.Children)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return resolvers, nil
+}
+
+func (*QueryResolver) Entitlements(ctx context.Context, args struct {
+	UserID *string
+	Kind   *datastructure.EntitlementKind
+	Ref    *string
+	Page   *int32
+	Limit  *int32
+}) ([]*entitlementResolver, error) {
+	usr, _ := ctx.Value(utils.UserKey).(*datastructure.User)
+	if usr == nil || !usr.HasPermission(datastructure.RolePermissionManageEntitlements) {
+		return nil, resolvers.ErrAccessDenied
+	}
+
+	field, failed := GenerateSelectedFieldMap(ctx, resolvers.MaxDepth)
+	if failed {
+		return nil, resolvers.ErrDepth
+	}
+
+	limit := int64(20)
+	if args.Limit != nil {
+		limit = int64(*args.Limit)
+	}
+	if limit > resolvers.QueryLimit {
+		return nil, resolvers.ErrQueryLimit
+	}
+
+	// Pagination
+	page := int64(1)
+	if args.Page != nil && *args.Page > 1 {
+		page = int64(*args.Page)
+	}
+
+	// Disabled and inactive entitlements are included, newest first
+	match := bson.M{}
+	if args.UserID != nil {
+		id, err := primitive.ObjectIDFromHex(*args.UserID)
+		if err != nil {
+			return nil, resolvers.ErrUnknownUser
+		}
+		match["user_id"] = id
+	}
+	if args.Kind != nil {
+		match["kind"] = *args.Kind
+	}
+	if args.Ref != nil {
+		id, err := primitive.ObjectIDFromHex(*args.Ref)
+		if err != nil {
+			return []*entitlementResolver{}, nil
+		}
+		match["data.ref"] = id
+	}
+	opts := options.Find().SetSort(bson.M{
+		"_id": -1,
+	}).SetLimit(limit).SetSkip((page - 1) * limit)
+
+	entitlements := []*datastructure.Entitlement{}
+	cur, err := mongo.Collection(mongo.CollectionNameEntitlements).Find(ctx, match, opts)
+	if err == nil {
+		err = cur.All(ctx, &entitlements)
+	}
+	if err != nil {
+		logrus.WithError(err).Error("mongo")
+		return nil, resolvers.ErrInternalServer
+	}
+
+	// Find the roles the users are currently entitled to, which role bindings are evaluated against
+	userIDs := []primitive.ObjectID{}
+	for _, e := range entitlements {
+		if bound, _ := actions.Entitlements.EvaluateRoleBinding(e, nil); bound && !utils.ContainsObjectID(userIDs, e.UserID) {
+			userIDs = append(userIDs, e.UserID)
+		}
+	}
+	roles := make(map[primitive.ObjectID][]primitive.ObjectID)
+	if len(userIDs) > 0 {
+		roleEntitlements := []*datastructure.Entitlement{}
+		cur, err := mongo.Collection(mongo.CollectionNameEntitlements).Find(ctx, actions.Entitlements.ActiveFilter(bson.M{
+			"kind":    datastructure.EntitlementKindRole,
+			"user_id": bson.M{"$in": userIDs},
+		}))
+		if err == nil {
+			err = cur.All(ctx, &roleEntitlements)
+		}
+		if err != nil {
+			logrus.WithError(err).Error("mongo")
+			return nil, resolvers.ErrInternalServer
+		}
+		for _, e := range roleEntitlements {
+			roles[e.UserID] = append(roles[e.UserID], actions.Entitlements.With(ctx, *e).ReadRoleData().ObjectReference)
+		}
+	}
+
+	resolvers := make([]*entitlementResolver, len(entitlements))
+	for i, e := range entitlements {
+		resolvers[i], err = GenerateEntitlementResolver(ctx, e, roles[e.UserID], field.Children)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return resolvers, nil
+}
+
 func (*QueryResolver) FeaturedBroadcast(ctx context.Context) (string, error) {
 	channel := redis.Client.Get(ctx, "meta:featured_broadcast").Val()
 	if channel == "" {
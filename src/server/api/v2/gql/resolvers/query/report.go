@@ -2,26 +2,50 @@ package query_resolvers
 
 import (
 	"context"
+	"time"
 
+	"github.com/SevenTV/ServerGo/src/mongo"
 	"github.com/SevenTV/ServerGo/src/mongo/datastructure"
+	"github.com/SevenTV/ServerGo/src/server/api/v2/gql/resolvers"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
 )
 
-type reportResolver struct {
+type ReportResolver struct {
 	ctx context.Context
 	v   *datastructure.Report
 
 	fields map[string]*SelectedField
 }
 
-func GenerateReportResolver(ctx context.Context, report *datastructure.Report, fields map[string]*SelectedField) (*reportResolver, error) {
-	return &reportResolver{
+func GenerateReportResolver(ctx context.Context, report *datastructure.Report, fields map[string]*SelectedField) (*ReportResolver, error) {
+	if _, ok := fields["audit_entries"]; ok && report.AuditEntries == nil {
+		report.AuditEntries = &[]*datastructure.AuditLog{}
+		cur, err := mongo.Collection(mongo.CollectionNameAudit).Find(ctx, bson.M{
+			"target.id":   report.ID,
+			"target.type": "reports",
+		})
+		if err == nil {
+			err = cur.All(ctx, report.AuditEntries)
+		}
+		if err != nil {
+			logrus.WithError(err).Error("mongo")
+			return nil, resolvers.ErrInternalServer
+		}
+	}
+
+	return &ReportResolver{
 		ctx:    ctx,
 		v:      report,
 		fields: fields,
 	}, nil
 }
 
-func (r *reportResolver) ReporterID() *string {
+func (r *ReportResolver) ID() string {
+	return r.v.ID.Hex()
+}
+
+func (r *ReportResolver) ReporterID() *string {
 	if r.v.ReporterID == nil {
 		return nil
 	}
@@ -29,7 +53,7 @@ func (r *reportResolver) ReporterID() *string {
 	return &hex
 }
 
-func (r *reportResolver) TargetID() *string {
+func (r *ReportResolver) TargetID() *string {
 	if r.v.Target.ID == nil {
 		return nil
 	}
@@ -37,40 +61,98 @@ func (r *reportResolver) TargetID() *string {
 	return &hex
 }
 
-func (r *reportResolver) TargetType() string {
+func (r *ReportResolver) TargetType() string {
 	return r.v.Target.Type
 }
 
-func (r *reportResolver) Reason() string {
+func (r *ReportResolver) Reason() string {
 	return r.v.Reason
 }
 
-func (r *reportResolver) Cleared() bool {
+func (r *ReportResolver) Category() string {
+	return string(r.v.GetCategory())
+}
+
+func (r *ReportResolver) Evidence() *reportEvidenceResolver {
+	if r.v.Evidence == nil {
+		return nil
+	}
+	return &reportEvidenceResolver{v: r.v.Evidence}
+}
+
+func (r *ReportResolver) Cleared() bool {
 	return r.v.Cleared
 }
 
-func (r *reportResolver) UTarget() (*UserResolver, error) {
+func (r *ReportResolver) Status() string {
+	return string(r.v.GetStatus())
+}
+
+func (r *ReportResolver) AssigneeID() *string {
+	if r.v.AssigneeID == nil {
+		return nil
+	}
+	hex := r.v.AssigneeID.Hex()
+	return &hex
+}
+
+func (r *ReportResolver) Assignee() (*UserResolver, error) {
+	if r.v.AssigneeID == nil {
+		return nil, nil
+	}
+	return GenerateUserResolver(r.ctx, nil, r.v.AssigneeID, r.fields["assignee"].Children)
+}
+
+func (r *ReportResolver) ActionTaken() *string {
+	return r.v.ActionTaken
+}
+
+func (r *ReportResolver) Notes() []*reportNoteResolver {
+	notes := make([]*reportNoteResolver, len(r.v.Notes))
+	for i, n := range r.v.Notes {
+		notes[i] = &reportNoteResolver{
+			ctx:    r.ctx,
+			v:      n,
+			fields: r.fields["notes"].Children,
+		}
+	}
+	return notes
+}
+
+func (r *ReportResolver) CreatedAt() string {
+	return r.v.ID.Timestamp().Format(time.RFC3339)
+}
+
+func (r *ReportResolver) ClosedAt() *string {
+	if r.v.ClosedAt == nil {
+		return nil
+	}
+	date := r.v.ClosedAt.Format(time.RFC3339)
+	return &date
+}
+
+func (r *ReportResolver) UTarget() (*UserResolver, error) {
 	if r.v.Target.Type == "users" {
 		return GenerateUserResolver(r.ctx, r.v.UTarget, r.v.Target.ID, r.fields["u_target"].Children)
 	}
 	return nil, nil
 }
 
-func (r *reportResolver) ETarget() (*EmoteResolver, error) {
+func (r *ReportResolver) ETarget() (*EmoteResolver, error) {
 	if r.v.Target.Type == "emotes" {
 		return GenerateEmoteResolver(r.ctx, r.v.ETarget, r.v.Target.ID, r.fields["e_target"].Children)
 	}
 	return nil, nil
 }
 
-func (r *reportResolver) Reporter() (*UserResolver, error) {
+func (r *ReportResolver) Reporter() (*UserResolver, error) {
 	if r.v.ReporterID != nil {
 		return GenerateUserResolver(r.ctx, r.v.Reporter, r.v.ReporterID, r.fields["reporter"].Children)
 	}
 	return nil, nil
 }
 
-func (r *reportResolver) AuditEntries() ([]string, error) {
+func (r *ReportResolver) AuditEntries() ([]string, error) {
 	if r.v.AuditEntries == nil {
 		return nil, nil
 	}
@@ -85,3 +167,46 @@ func (r *reportResolver) AuditEntries() ([]string, error) {
 	}
 	return logs, nil
 }
+
+type reportNoteResolver struct {
+	ctx context.Context
+	v   *datastructure.ReportNote
+
+	fields map[string]*SelectedField
+}
+
+func (r *reportNoteResolver) AuthorID() string {
+	return r.v.AuthorID.Hex()
+}
+
+func (r *reportNoteResolver) Author() (*UserResolver, error) {
+	return GenerateUserResolver(r.ctx, nil, &r.v.AuthorID, r.fields["author"].Children)
+}
+
+func (r *reportNoteResolver) Content() string {
+	return r.v.Content
+}
+
+func (r *reportNoteResolver) CreatedAt() string {
+	return r.v.CreatedAt.Format(time.RFC3339)
+}
+
+type reportEvidenceResolver struct {
+	v *datastructure.ReportEvidence
+}
+
+func (r *reportEvidenceResolver) Links() []string {
+	if r.v.Links == nil {
+		return []string{}
+	}
+	return r.v.Links
+}
+
+func (r *reportEvidenceResolver) ChatLog() *string {
+	return r.v.ChatLog
+}
+
+type reportCategoryCountResolver struct {
+	Category string `json:"category"`
+	Count    int32  `json:"count"`
+}
@@ -4,7 +4,6 @@ import (
 	"context"
 	"time"
 
-	"github.com/SevenTV/ServerGo/src/cache"
 	"github.com/SevenTV/ServerGo/src/mongo"
 	"github.com/SevenTV/ServerGo/src/mongo/datastructure"
 	"github.com/SevenTV/ServerGo/src/server/api/actions"
@@ -81,38 +80,16 @@ func GenerateUserResolver(ctx context.Context, user *datastructure.User, userID
 			return nil, resolvers.ErrInternalServer
 		}
 		ems := *user.OwnedEmotes
-		ids := make([]primitive.ObjectID, len(ems))
 		emotes := make(map[primitive.ObjectID]*datastructure.Emote, len(ems))
-		for i, e := range ems {
+		for _, e := range ems {
 			e.Owner = user
-			ids[i] = e.ID
 			emotes[e.ID] = e
 		}
 		if _, ok := v.Children["audit_entries"]; ok {
-			logs := []*datastructure.AuditLog{}
-			cur, err := mongo.Collection(mongo.CollectionNameAudit).Find(ctx, bson.M{
-				"target.id": bson.M{
-					"$in": ids,
-				},
-				"target.type": "emotes",
-			})
-			if err == nil {
-				err = cur.All(ctx, &logs)
-			}
-			if err != nil {
+			if err := fetchEmoteAuditEntries(ctx, emotes); err != nil {
 				logrus.WithError(err).Error("mongo")
 				return nil, resolvers.ErrInternalServer
 			}
-
-			for _, l := range logs {
-				e := emotes[*l.Target.ID]
-				var d []*datastructure.AuditLog
-				if e.AuditEntries != nil {
-					d = *e.AuditEntries
-				}
-				d = append(d, l)
-				e.AuditEntries = &d
-			}
 		}
 	}
 
@@ -134,36 +111,15 @@ func GenerateUserResolver(ctx context.Context, user *datastructure.User, userID
 				return nil, resolvers.ErrInternalServer
 			}
 			ems := *user.Emotes
-			ids := make([]primitive.ObjectID, len(ems))
 			emotes := make(map[primitive.ObjectID]*datastructure.Emote, len(ems))
-			for i, e := range ems {
-				ids[i] = e.ID
+			for _, e := range ems {
 				emotes[e.ID] = e
 			}
 			if _, ok := v.Children["audit_entries"]; ok {
-				logs := []*datastructure.AuditLog{}
-				cur, err := mongo.Collection(mongo.CollectionNameAudit).Find(ctx, bson.M{
-					"target.id": bson.M{
-						"$in": ids,
-					},
-					"target.type": "emotes",
-				})
-				if err == nil {
-					err = cur.All(ctx, &logs)
-				}
-				if err != nil {
+				if err := fetchEmoteAuditEntries(ctx, emotes); err != nil {
 					logrus.WithError(err).Error("mongo")
 					return nil, resolvers.ErrInternalServer
 				}
-				for _, l := range logs {
-					e := emotes[*l.Target.ID]
-					var d []*datastructure.AuditLog
-					if e.AuditEntries != nil {
-						d = *e.AuditEntries
-					}
-					d = append(d, l)
-					e.AuditEntries = &d
-				}
 			}
 		}
 	}
@@ -200,14 +156,14 @@ func GenerateUserResolver(ctx context.Context, user *datastructure.User, userID
 		}
 	}
 
-	if v, ok := fields["reports"]; ok && usrValid && (usr.Rank != datastructure.UserRankAdmin && usr.Rank != datastructure.UserRankModerator) && user.Reports == nil {
+	if v, ok := fields["reports"]; ok && usrValid && usr.HasPermission(datastructure.RolePermissionManageReports) && user.Reports == nil {
 		user.Reports = &[]*datastructure.Report{}
-		cur, err := mongo.Collection(mongo.CollectionNameUsers).Find(ctx, bson.M{
+		cur, err := mongo.Collection(mongo.CollectionNameReports).Find(ctx, bson.M{
 			"target.id":   user.ID,
 			"target.type": "users",
 		})
 		if err == nil {
-			err = cur.All(ctx, user.EditorIn)
+			err = cur.All(ctx, user.Reports)
 		}
 		if err != nil {
 			logrus.WithError(err).Error("mongo")
@@ -264,81 +220,11 @@ func GenerateUserResolver(ctx context.Context, user *datastructure.User, userID
 		_ = res.All(ctx, user.Bans)
 	}
 
-	if _, ok := fields["notifications"]; ok && usrValid && actorCanEdit {
-		// Find notifications readable by this user
-		pipeline := mongo.Pipeline{
-			bson.D{ // Step 1: Match only readstates where the target is the user
-				bson.E{
-					Key: "$match",
-					Value: bson.M{
-						"target": user.ID,
-					},
-				},
-			},
-			bson.D{
-				bson.E{
-					Key: "$sort",
-					Value: bson.M{
-						"_id": -1,
-					},
-				},
-			},
-			bson.D{
-				bson.E{
-					Key:   "$limit",
-					Value: 50,
-				},
-			},
-			bson.D{ // Step 2: Find the target notification from the other collection
-				bson.E{
-					Key: "$lookup",
-					Value: bson.M{
-						"from":         "notifications", // Target the collection containing notification data
-						"localField":   "notification",  // Use the notification field, which is the ID of the notification
-						"foreignField": "_id",           // Match with foreign collection's ObjectID
-						"as":           "notification",  // Output as "notification" field
-					},
-				},
-			},
-			bson.D{ // Step 3: Unwind the array of notifications (but this is ID match, therefore there is only 1)
-				bson.E{
-					Key:   "$unwind",
-					Value: "$notification",
-				},
-			},
-			bson.D{ // Step 4: Add the "read" field from the notification read state into the notification object
-				bson.E{
-					Key: "$addFields",
-					Value: bson.M{
-						"notification.read":    "$read",
-						"notification.read_at": "$read_at",
-					},
-				},
-			},
-			bson.D{ // Step 5: Replace the root input with the notification that now has the readstate information :tf:
-				bson.E{
-					Key:   "$replaceWith",
-					Value: "$notification",
-				},
-			},
-		}
-		cur, err := mongo.Collection(mongo.CollectionNameNotificationsRead).Aggregate(ctx, pipeline)
-		if err != nil {
-			return nil, err
-		}
-		if err := cur.All(ctx, &user.Notifications); err != nil {
-			return nil, err
-		}
-	}
-
 	if _, ok := fields["notification_count"]; ok && usrValid && actorCanEdit {
-		// Get count of notifications
-		count, err := cache.GetCollectionSize(ctx, "notifications_read", bson.M{
-			"target": user.ID,
-			"read":   false,
-		})
+		// Get count of unread notifications, kept in sync by writes and reads
+		count, err := actions.Notifications.GetUnreadCount(ctx, user.ID)
 		if err != nil {
-			return nil, err
+			return nil, resolvers.ErrInternalServer
 		}
 
 		user.NotificationCount = &count
@@ -349,12 +235,12 @@ func GenerateUserResolver(ctx context.Context, user *datastructure.User, userID
 		pipeline := mongo.Pipeline{
 			{{
 				Key: "$match",
-				Value: bson.M{
+				Value: actions.Entitlements.ActiveFilter(bson.M{
 					"user_id": userID,
 					"kind": bson.M{
 						"$in": bson.A{"BADGE", "PAINT"},
 					},
-				},
+				}),
 			}},
 			{{
 				Key: "$lookup",
@@ -617,9 +503,9 @@ func (r *UserResolver) ProfileImageURL() string {
 	}
 }
 
-func (r *UserResolver) Reports() (*[]*reportResolver, error) {
+func (r *UserResolver) Reports() (*[]*ReportResolver, error) {
 	u, ok := r.ctx.Value(utils.UserKey).(*datastructure.User)
-	if !ok || (u.Rank != datastructure.UserRankAdmin && u.Rank != datastructure.UserRankModerator) {
+	if !ok || !u.HasPermission(datastructure.RolePermissionManageReports) {
 		return nil, resolvers.ErrAccessDenied
 	}
 
@@ -628,7 +514,7 @@ func (r *UserResolver) Reports() (*[]*reportResolver, error) {
 	}
 
 	e := *r.v.Reports
-	reports := make([]*reportResolver, len(e))
+	reports := make([]*ReportResolver, len(e))
 	var err error
 	for i, l := range e {
 		reports[i], err = GenerateReportResolver(r.ctx, l, r.fields["reports"].Children)
@@ -712,7 +598,7 @@ func (r *UserResolver) EmoteSlots() int32 {
 		return 0
 	}
 
-	return r.v.GetEmoteSlots()
+	return r.ub.GetChannelEmoteSlots()
 }
 
 // Get user's folloer count
@@ -753,7 +639,41 @@ func (r *UserResolver) Broadcast() (*datastructure.Broadcast, error) {
 	return stream, nil
 }
 
-func (r *UserResolver) Notifications() ([]*NotificationResolver, error) {
+func (r *UserResolver) Notifications(args struct {
+	Cursor *string
+	Limit  *int32
+	Unread *bool
+}) ([]*NotificationResolver, error) {
+	// Only the user and their editors may read the user's notifications
+	usr, ok := r.ctx.Value(utils.UserKey).(*datastructure.User)
+	if !ok || (usr.ID != r.v.ID && !utils.ContainsObjectID(r.v.EditorIDs, usr.ID)) {
+		return []*NotificationResolver{}, nil
+	}
+
+	limit := int64(50)
+	if args.Limit != nil {
+		limit = int64(*args.Limit)
+	}
+	if limit < 1 || limit > resolvers.QueryLimit {
+		return nil, resolvers.ErrQueryLimit
+	}
+
+	// Pass the id of the last notification received as cursor to get the next page
+	var cursor *primitive.ObjectID
+	if args.Cursor != nil {
+		id, err := primitive.ObjectIDFromHex(*args.Cursor)
+		if err != nil {
+			return nil, resolvers.ErrInvalidCursor
+		}
+		cursor = &id
+	}
+
+	var err error
+	r.v.Notifications, err = actions.Notifications.GetUserNotifications(r.ctx, r.v.ID, cursor, limit, args.Unread != nil && *args.Unread)
+	if err != nil {
+		return nil, resolvers.ErrInternalServer
+	}
+
 	// Transform all notifications to builders
 	notifications := []actions.NotificationBuilder{}
 	for _, n := range r.v.Notifications {
@@ -848,6 +768,19 @@ func (r *UserResolver) Notifications() ([]*NotificationResolver, error) {
 	return resolvers, nil
 }
 
+func (r *UserResolver) MutedNotifications() *[]string {
+	// Only the user themselves may see their notification settings
+	if u, ok := r.ctx.Value(utils.UserKey).(*datastructure.User); !ok || u.ID != r.v.ID {
+		return nil
+	}
+
+	categories := make([]string, len(r.v.MutedNotifications))
+	for i, c := range r.v.MutedNotifications {
+		categories[i] = string(c)
+	}
+	return &categories
+}
+
 func (r *UserResolver) NotificationCount() int32 {
 	if r.v.NotificationCount == nil {
 		return 0
@@ -856,8 +789,60 @@ func (r *UserResolver) NotificationCount() int32 {
 	return int32(*r.v.NotificationCount)
 }
 
-func (r *UserResolver) Cosmetics(ctx context.Context) []*cosmeticResolver {
-	resolvers := []*cosmeticResolver{}
+func (r *UserResolver) PersonalEmoteSets() ([]*PersonalEmoteSetResolver, error) {
+	if r.ub.IsBanned() { // Omit if user is banned
+		return []*PersonalEmoteSetResolver{}, nil
+	}
+
+	sets, err := actions.Entitlements.GetPersonalEmoteSets(r.ctx, r.v.ID)
+	if err != nil {
+		return nil, resolvers.ErrInternalServer
+	}
+
+	var fields map[string]*SelectedField
+	if f, ok := r.fields["personal_emote_sets"]; ok {
+		fields = f.Children
+	}
+	result := make([]*PersonalEmoteSetResolver, len(sets))
+	for i, set := range sets {
+		result[i] = GeneratePersonalEmoteSetResolver(r.ctx, set, fields)
+	}
+	return result, nil
+}
+
+func (r *UserResolver) ActiveEmoteSetID() *string {
+	if r.v.ActiveEmoteSetID == nil {
+		return nil
+	}
+	id := r.v.ActiveEmoteSetID.Hex()
+	return &id
+}
+
+func (r *UserResolver) EmoteSets() ([]*EmoteSetResolver, error) {
+	if r.ub.IsBanned() { // Omit if user is banned
+		return []*EmoteSetResolver{}, nil
+	}
+
+	sets, err := actions.EmoteSets.GetByOwner(r.ctx, r.v)
+	if err != nil {
+		logrus.WithError(err).Error("mongo")
+		return nil, resolvers.ErrInternalServer
+	}
+
+	var fields map[string]*SelectedField
+	if f, ok := r.fields["emote_sets"]; ok {
+		fields = f.Children
+	}
+	slots := r.ub.GetEmoteSlots()
+	result := make([]*EmoteSetResolver, len(sets))
+	for i, set := range sets {
+		result[i] = GenerateEmoteSetResolver(r.ctx, set, actions.EmoteSets.IsActive(set, r.v), actions.EmoteSets.GetSlots(set, slots), fields)
+	}
+	return result, nil
+}
+
+func (r *UserResolver) Cosmetics(ctx context.Context) []*CosmeticResolver {
+	resolvers := []*CosmeticResolver{}
 	for _, cos := range r.v.Cosmetics {
 		resolvers = append(resolvers, GenerateCosmeticResolver(ctx, cos, r.fields))
 	}
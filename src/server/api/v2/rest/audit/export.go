@@ -0,0 +1,232 @@
+package audit
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/SevenTV/ServerGo/src/configure"
+	"github.com/SevenTV/ServerGo/src/mongo"
+	"github.com/SevenTV/ServerGo/src/mongo/datastructure"
+	"github.com/SevenTV/ServerGo/src/server/api/actions"
+	"github.com/SevenTV/ServerGo/src/server/api/v2/rest/restutil"
+	"github.com/SevenTV/ServerGo/src/server/middleware"
+	"github.com/gofiber/fiber/v2"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ExportAuditLogsRoute: Stream the audit log entries matching a filter as NDJSON or CSV, oldest first
+func ExportAuditLogsRoute(router fiber.Router) {
+	rl := configure.Config.GetIntSlice("limits.route.audit-export")
+	if len(rl) < 2 {
+		rl = []int{5, 60000} // Configs from before exports existed have no limit for them
+	}
+	router.Get(
+		"/export",
+		middleware.UserAuthMiddleware(true),
+		middleware.RateLimitMiddleware("audit-export", int32(rl[0]), time.Millisecond*time.Duration(rl[1])),
+		func(c *fiber.Ctx) error {
+			usr, ok := c.Locals("user").(*datastructure.User)
+			if !ok {
+				return restutil.ErrLoginRequired().Send(c)
+			}
+			if !usr.HasPermission(datastructure.RolePermissionAdministrator) {
+				return restutil.ErrAccessDenied().Send(c)
+			}
+
+			format := c.Query("format", "ndjson")
+			if format != "ndjson" && format != "csv" {
+				return restutil.ErrBadRequest().Send(c, "Unknown Format")
+			}
+
+			filter, err := parseFilter(c)
+			if err != nil {
+				return restutil.ErrBadRequest().Send(c, err.Error())
+			}
+
+			// The export outlives the request handler, so it can't use the request's context
+			ctx, cancel := context.WithTimeout(context.Background(), time.Minute*30)
+			cur, err := mongo.Collection(mongo.CollectionNameAudit).Find(ctx, actions.Audit.Query(filter), options.Find().SetSort(bson.M{
+				"_id": 1,
+			}))
+			if err != nil {
+				cancel()
+				logrus.WithError(err).Error("mongo")
+				return restutil.ErrInternalServer().Send(c, err.Error())
+			}
+
+			// Log the export, as it may contain sensitive information
+			logrus.WithFields(logrus.Fields{
+				"user_id": usr.ID.Hex(),
+				"format":  format,
+				"query":   string(c.Request().URI().QueryString()),
+			}).Info("audit, export started")
+
+			filename := fmt.Sprintf("audit-%s.%s", time.Now().Format("20060102-150405"), format)
+			c.Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+			if format == "csv" {
+				c.Set("Content-Type", "text/csv")
+			} else {
+				c.Set("Content-Type", "application/x-ndjson")
+			}
+
+			c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+				defer cancel()
+				defer cur.Close(ctx)
+
+				var err error
+				if format == "csv" {
+					err = writeCSV(ctx, w, cur)
+				} else {
+					err = writeNDJSON(ctx, w, cur)
+				}
+				if err != nil {
+					logrus.WithError(err).Error("audit, export failed")
+				}
+			})
+			return nil
+		},
+	)
+}
+
+// The amount of entries written between each flush of the response stream
+const exportFlushInterval = 500
+
+func writeNDJSON(ctx context.Context, w *bufio.Writer, cur *mongo.Cursor) error {
+	enc := json.NewEncoder(w)
+	for i := 1; cur.Next(ctx); i++ {
+		l := &datastructure.AuditLog{}
+		if err := cur.Decode(l); err != nil {
+			return err
+		}
+		if err := enc.Encode(l); err != nil {
+			return err
+		}
+
+		if i%exportFlushInterval == 0 {
+			if err := w.Flush(); err != nil {
+				return err
+			}
+		}
+	}
+	if err := cur.Err(); err != nil {
+		return err
+	}
+
+	return w.Flush()
+}
+
+func writeCSV(ctx context.Context, w *bufio.Writer, cur *mongo.Cursor) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"id", "created_at", "type", "action_user_id", "target_type", "target_id", "reason", "changes"}); err != nil {
+		return err
+	}
+
+	for i := 1; cur.Next(ctx); i++ {
+		l := &datastructure.AuditLog{}
+		if err := cur.Decode(l); err != nil {
+			return err
+		}
+
+		targetType, targetID := "", ""
+		if l.Target != nil {
+			targetType = l.Target.Type
+			if l.Target.ID != nil {
+				targetID = l.Target.ID.Hex()
+			}
+		}
+		reason := ""
+		if l.Reason != nil {
+			reason = *l.Reason
+		}
+		changes, err := json.Marshal(l.Changes)
+		if err != nil {
+			return err
+		}
+
+		if err := cw.Write([]string{
+			l.ID.Hex(),
+			l.ID.Timestamp().Format(time.RFC3339),
+			strconv.Itoa(int(l.Type)),
+			l.CreatedBy.Hex(),
+			targetType,
+			targetID,
+			reason,
+			string(changes),
+		}); err != nil {
+			return err
+		}
+
+		if i%exportFlushInterval == 0 {
+			cw.Flush()
+			if err := w.Flush(); err != nil {
+				return err
+			}
+		}
+	}
+	if err := cur.Err(); err != nil {
+		return err
+	}
+
+	cw.Flush()
+	if err := cw.Error(); err != nil {
+		return err
+	}
+	return w.Flush()
+}
+
+// parseFilter: Read the audit log filter from the query params
+func parseFilter(c *fiber.Ctx) (*actions.AuditLogFilter, error) {
+	filter := &actions.AuditLogFilter{}
+
+	if types := c.Query("types"); types != "" {
+		for _, s := range strings.Split(types, ",") {
+			t, err := strconv.Atoi(strings.TrimSpace(s))
+			if err != nil {
+				return nil, fmt.Errorf("Invalid Type")
+			}
+			filter.Types = append(filter.Types, int32(t))
+		}
+	}
+	if s := c.Query("actor_id"); s != "" {
+		id, err := primitive.ObjectIDFromHex(s)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid Actor")
+		}
+		filter.ActorID = &id
+	}
+	if s := c.Query("target_id"); s != "" {
+		id, err := primitive.ObjectIDFromHex(s)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid Target")
+		}
+		filter.TargetID = &id
+	}
+	if s := c.Query("target_type"); s != "" {
+		filter.TargetType = &s
+	}
+	if s := c.Query("after"); s != "" {
+		t, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid Date")
+		}
+		filter.After = &t
+	}
+	if s := c.Query("before"); s != "" {
+		t, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid Date")
+		}
+		filter.Before = &t
+	}
+
+	return filter, nil
+}
@@ -0,0 +1,167 @@
+package bans
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/SevenTV/ServerGo/src/configure"
+	"github.com/SevenTV/ServerGo/src/mongo"
+	"github.com/SevenTV/ServerGo/src/mongo/datastructure"
+	"github.com/SevenTV/ServerGo/src/server/api/actions"
+	"github.com/SevenTV/ServerGo/src/server/api/v2/rest/restutil"
+	"github.com/SevenTV/ServerGo/src/server/middleware"
+	"github.com/gofiber/fiber/v2"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const MAX_APPEAL_LENGTH = 2000
+
+// GetOwnBansRoute: List the active restrictions of the authenticated user, along with their latest appeal
+func GetOwnBansRoute(router fiber.Router) {
+	router.Get("/@me", middleware.BannedUserAuthMiddleware(), func(c *fiber.Ctx) error {
+		usr, ok := c.Locals("user").(*datastructure.User)
+		if !ok {
+			return restutil.ErrLoginRequired().Send(c)
+		}
+
+		bans := actions.Bans.GetRestrictions(usr.ID)
+		banIDs := make([]primitive.ObjectID, len(bans))
+		for i, ban := range bans {
+			banIDs[i] = ban.ID
+		}
+
+		// Find appeals made against the bans, most recent first
+		appeals := []*datastructure.BanAppeal{}
+		cur, err := mongo.Collection(mongo.CollectionNameBanAppeals).Find(c.Context(), bson.M{
+			"ban_id": bson.M{"$in": banIDs},
+		}, options.Find().SetSort(bson.M{"_id": -1}))
+		if err == nil {
+			err = cur.All(c.Context(), &appeals)
+		}
+		if err != nil {
+			logrus.WithError(err).Error("mongo")
+			return restutil.ErrInternalServer().Send(c, err.Error())
+		}
+
+		appealMap := map[primitive.ObjectID]*datastructure.BanAppeal{}
+		for _, appeal := range appeals {
+			if _, ok := appealMap[appeal.BanID]; !ok {
+				appealMap[appeal.BanID] = appeal
+			}
+		}
+
+		response := make([]*restutil.BanResponse, len(bans))
+		for i, ban := range bans {
+			response[i] = restutil.CreateBanResponse(ban, appealMap[ban.ID])
+		}
+
+		b, err := json.Marshal(&response)
+		if err != nil {
+			return restutil.ErrInternalServer().Send(c, err.Error())
+		}
+
+		return c.Send(b)
+	})
+}
+
+// CreateBanAppealRoute: Submit an appeal against one of the authenticated user's active bans
+func CreateBanAppealRoute(router fiber.Router) {
+	rl := configure.Config.GetIntSlice("limits.route.ban-appeal")
+	if len(rl) < 2 {
+		rl = []int{2, 3600000} // Configs from before appeals existed have no limit for them
+	}
+	router.Post(
+		"/:ban/appeal",
+		middleware.BannedUserAuthMiddleware(),
+		middleware.RateLimitMiddleware("ban-appeal", int32(rl[0]), time.Millisecond*time.Duration(rl[1])),
+		func(c *fiber.Ctx) error {
+			usr, ok := c.Locals("user").(*datastructure.User)
+			if !ok {
+				return restutil.ErrLoginRequired().Send(c)
+			}
+
+			banID, err := primitive.ObjectIDFromHex(c.Params("ban"))
+			if err != nil {
+				return restutil.MalformedObjectId().Send(c)
+			}
+
+			body := &banAppealBody{}
+			if err := c.BodyParser(body); err != nil {
+				return restutil.ErrBadRequest().Send(c, "Invalid Body")
+			}
+			body.Message = strings.TrimSpace(body.Message)
+			if body.Message == "" {
+				return restutil.ErrBadRequest().Send(c, "Missing Message")
+			}
+			if len(body.Message) > MAX_APPEAL_LENGTH {
+				return restutil.ErrBadRequest().Send(c, "Message Too Long")
+			}
+
+			// Only active bans against the user can be appealed
+			var ban *datastructure.Ban
+			for _, b := range actions.Bans.GetRestrictions(usr.ID) {
+				if b.ID == banID {
+					ban = b
+					break
+				}
+			}
+			if ban == nil {
+				return restutil.ErrUnknownBan().Send(c)
+			}
+
+			// Only one appeal may be pending at a time for a given ban
+			count, err := mongo.Collection(mongo.CollectionNameBanAppeals).CountDocuments(c.Context(), bson.M{
+				"ban_id": ban.ID,
+				"status": datastructure.BanAppealStatusPending,
+			})
+			if err != nil {
+				logrus.WithError(err).Error("mongo")
+				return restutil.ErrInternalServer().Send(c, err.Error())
+			}
+			if count > 0 {
+				return restutil.ErrBadRequest().Send(c, "An Appeal Is Already Pending")
+			}
+
+			appeal := &datastructure.BanAppeal{
+				BanID:   ban.ID,
+				UserID:  usr.ID,
+				Message: body.Message,
+				Status:  datastructure.BanAppealStatusPending,
+			}
+			res, err := mongo.Collection(mongo.CollectionNameBanAppeals).InsertOne(c.Context(), appeal)
+			if err != nil {
+				logrus.WithError(err).Error("mongo")
+				return restutil.ErrInternalServer().Send(c, err.Error())
+			}
+			appeal.ID, _ = res.InsertedID.(primitive.ObjectID)
+
+			_, err = mongo.Collection(mongo.CollectionNameAudit).InsertOne(c.Context(), &datastructure.AuditLog{
+				Type:      datastructure.AuditLogTypeUserBanAppeal,
+				CreatedBy: usr.ID,
+				Target:    &datastructure.Target{ID: &usr.ID, Type: "users"},
+				Changes: []*datastructure.AuditLogChange{
+					{Key: "appeal_id", OldValue: nil, NewValue: appeal.ID},
+					{Key: "ban_id", OldValue: nil, NewValue: ban.ID},
+				},
+			})
+			if err != nil {
+				logrus.WithError(err).Error("mongo")
+			}
+
+			b, err := json.Marshal(restutil.CreateBanAppealResponse(appeal))
+			if err != nil {
+				return restutil.ErrInternalServer().Send(c, err.Error())
+			}
+
+			return c.Status(201).Send(b)
+		},
+	)
+}
+
+type banAppealBody struct {
+	Message string `json:"message"`
+}
@@ -57,13 +57,13 @@ func Avatar(router fiber.Router) {
 					"pipeline": mongo.Pipeline{
 						bson.D{bson.E{
 							Key: "$match",
-							Value: bson.M{
-								"disabled": bson.M{"$not": bson.M{"$eq": true}}, // here we make sure the entitlement is active
-								"kind":     "ROLE",
+							// here we make sure the entitlement is active
+							Value: actions.Entitlements.ActiveFilter(bson.M{
+								"kind": "ROLE",
 								"$expr": bson.M{
 									"$eq": bson.A{"$user_id", "$$user_id"},
 								},
-							},
+							}),
 						}},
 					},
 					"as": "entitled_roles", // output to entitled_roles
@@ -0,0 +1,209 @@
+package cosmetics
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"mime/multipart"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/SevenTV/ServerGo/src/aws"
+	"github.com/SevenTV/ServerGo/src/configure"
+	"github.com/SevenTV/ServerGo/src/mongo"
+	"github.com/SevenTV/ServerGo/src/mongo/datastructure"
+	"github.com/SevenTV/ServerGo/src/server/api/v2/rest/restutil"
+	"github.com/SevenTV/ServerGo/src/server/middleware"
+	"github.com/SevenTV/ServerGo/src/utils"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const MAX_BADGE_FILE_SIZE = 1000000 // 1MB
+const MAX_BADGE_PIXEL_SIZE = 1000
+const MIN_BADGE_PIXEL_SIZE = 72 // The size of the largest rendition, so that the image isn't upscaled
+
+// UploadBadgeImageRoute: Upload the artwork of a badge, which is resized to each badge size and published to the CDN
+func UploadBadgeImageRoute(router fiber.Router) {
+	router.Post(
+		"/badges/:id/image",
+		middleware.UserAuthMiddleware(true),
+		func(c *fiber.Ctx) error {
+			c.Set("Content-Type", "application/json")
+			usr, ok := c.Locals("user").(*datastructure.User)
+			if !ok {
+				return restutil.ErrLoginRequired().Send(c)
+			}
+			if !usr.HasPermission(datastructure.RolePermissionManageCosmetics) {
+				return restutil.ErrAccessDenied().Send(c)
+			}
+
+			badgeID, err := primitive.ObjectIDFromHex(c.Params("id"))
+			if err != nil {
+				return restutil.MalformedObjectId().Send(c)
+			}
+
+			ctx := c.Context()
+			badge := &datastructure.Cosmetic{}
+			if err := mongo.Collection(mongo.CollectionNameCosmetics).FindOne(ctx, bson.M{
+				"_id":  badgeID,
+				"kind": datastructure.CosmeticKindBadge,
+			}).Decode(badge); err != nil {
+				if err == mongo.ErrNoDocuments {
+					return restutil.ErrUnknownBadge().Send(c)
+				}
+				logrus.WithError(err).Error("mongo")
+				return restutil.ErrInternalServer().Send(c, err.Error())
+			}
+
+			req := c.Request()
+			if !req.IsBodyStream() {
+				return restutil.ErrBadRequest().Send(c, "Not A File Stream")
+			}
+
+			// The temp directory where the renditions will be created
+			id, _ := uuid.NewRandom()
+			fileDir := fmt.Sprintf("%s/%s", configure.Config.GetString("temp_file_store"), id.String())
+			if err := os.MkdirAll(fileDir, 0777); err != nil {
+				logrus.WithError(err).Error("mkdir")
+				return restutil.ErrInternalServer().Send(c)
+			}
+			ogFilePath := fmt.Sprintf("%v/og", fileDir) // The original file's path in temp
+
+			// Remove temp dir once this function completes
+			defer os.RemoveAll(fileDir)
+
+			// Read the file from the form data
+			var contentType string
+			mr := multipart.NewReader(ctx.RequestBodyStream(), utils.B2S(req.Header.MultipartFormBoundary()))
+			for {
+				part, err := mr.NextPart()
+				if err == io.EOF {
+					break
+				} else if err != nil {
+					logrus.WithError(err).Error("multipart_reader")
+					break
+				}
+				if part.FormName() != "file" {
+					continue
+				}
+
+				contentType = part.Header.Get("Content-Type")
+				if !utils.Contains([]string{"image/png", "image/gif", "image/jpeg"}, contentType) {
+					return restutil.ErrBadRequest().Send(c, "Unsupported File Type (want jpg, png or gif)")
+				}
+
+				b, err := io.ReadAll(io.LimitReader(part, MAX_BADGE_FILE_SIZE+1))
+				if err != nil {
+					return restutil.ErrBadRequest().Send(c, "File Not Readable")
+				}
+				if len(b) > MAX_BADGE_FILE_SIZE {
+					return restutil.ErrBadRequest().Send(c, "Input File Too Large. Must be <1MB")
+				}
+				if err := os.WriteFile(ogFilePath, b, 0644); err != nil {
+					logrus.WithError(err).Error("write")
+					return restutil.ErrInternalServer().Send(c)
+				}
+			}
+			if contentType == "" {
+				return restutil.ErrBadRequest().Send(c, "Uncomplete Form")
+			}
+
+			// Check the dimensions of the image
+			ogFile, err := os.Open(ogFilePath)
+			if err != nil {
+				logrus.WithError(err).Error("could not open original file")
+				return restutil.ErrInternalServer().Send(c)
+			}
+			cfg, _, err := image.DecodeConfig(ogFile)
+			ogFile.Close()
+			if err != nil {
+				return restutil.ErrBadRequest().Send(c, fmt.Sprintf("Couldn't decode image: %v", err.Error()))
+			}
+			if cfg.Width > MAX_BADGE_PIXEL_SIZE || cfg.Height > MAX_BADGE_PIXEL_SIZE {
+				return restutil.ErrBadRequest().Send(c, fmt.Sprintf("Too Many Pixels (maximum %dx%d)", MAX_BADGE_PIXEL_SIZE, MAX_BADGE_PIXEL_SIZE))
+			} else if cfg.Width < MIN_BADGE_PIXEL_SIZE && cfg.Height < MIN_BADGE_PIXEL_SIZE {
+				return restutil.ErrBadRequest().Send(c, fmt.Sprintf("Too Few Pixels (minimum %dpx on the longest side)", MIN_BADGE_PIXEL_SIZE))
+			}
+
+			// Resize the frame(s). Badges are displayed as squares, so images of another ratio are padded
+			files := datastructure.CosmeticUtil.GetBadgeFilesMeta(fileDir)
+			for _, file := range files {
+				sizes := strings.Split(file[2], "x")
+				maxWidth, _ := strconv.ParseFloat(sizes[0], 32)
+				maxHeight, _ := strconv.ParseFloat(sizes[1], 32)
+				quality, _ := strconv.Atoi(file[3])
+
+				width, height := utils.GetSizeRatio(
+					[]float64{float64(cfg.Width), float64(cfg.Height)},
+					[]float64{maxWidth, maxHeight},
+				)
+				if err := utils.ResizeImage(
+					ogFilePath, file[0]+".webp",
+					uint(width), uint(height), uint(maxWidth), uint(maxHeight), uint(quality),
+				); err != nil {
+					if errors.Is(err, utils.ErrImageNotReadable) {
+						return restutil.ErrBadRequest().Send(c, err.Error())
+					}
+					logrus.WithError(err).Error("cmd")
+					return restutil.ErrInternalServer().Send(c)
+				}
+			}
+
+			// Upload the renditions, replacing the current artwork
+			mime := "image/webp"
+			wg := &sync.WaitGroup{}
+			wg.Add(len(files))
+			var errored int32
+			for _, path := range files {
+				go func(path []string) {
+					defer wg.Done()
+					data, err := os.ReadFile(path[0] + ".webp")
+					if err != nil {
+						logrus.WithError(err).Error("read")
+						atomic.StoreInt32(&errored, 1)
+						return
+					}
+
+					if err := aws.UploadFile(configure.Config.GetString("aws_cdn_bucket"), fmt.Sprintf("badge/%s/%s", badge.ID.Hex(), path[1]), data, &mime); err != nil {
+						logrus.WithError(err).Error("aws")
+						atomic.StoreInt32(&errored, 1)
+					}
+				}(path)
+			}
+			wg.Wait()
+			if atomic.LoadInt32(&errored) == 1 {
+				return restutil.ErrInternalServer().Send(c)
+			}
+
+			_, err = mongo.Collection(mongo.CollectionNameAudit).InsertOne(ctx, &datastructure.AuditLog{
+				Type:      datastructure.AuditLogTypeCosmeticEdit,
+				CreatedBy: usr.ID,
+				Target:    &datastructure.Target{ID: &badge.ID, Type: "cosmetics"},
+				Changes: []*datastructure.AuditLogChange{
+					{Key: "image", OldValue: nil, NewValue: fmt.Sprintf("%s %dx%d", contentType, cfg.Width, cfg.Height)},
+				},
+			})
+			if err != nil {
+				logrus.WithError(err).Error("mongo")
+			}
+
+			b, err := json.Marshal(restutil.CreateBadgeResponse(badge, []*datastructure.User{}, "object_id"))
+			if err != nil {
+				return restutil.ErrInternalServer().Send(c, err.Error())
+			}
+			return c.Status(200).Send(b)
+		},
+	)
+}
@@ -2,9 +2,11 @@ package cosmetics
 
 import (
 	"encoding/json"
+	"strconv"
 
 	"github.com/SevenTV/ServerGo/src/mongo"
 	"github.com/SevenTV/ServerGo/src/mongo/datastructure"
+	"github.com/SevenTV/ServerGo/src/server/api/actions"
 	"github.com/SevenTV/ServerGo/src/server/api/v2/rest/restutil"
 	"github.com/SevenTV/ServerGo/src/utils"
 	"github.com/gofiber/fiber/v2"
@@ -16,6 +18,7 @@ import (
 /*
 * Query Params:
 * user_identifier: "object_id", "twitch_id", "login"
+* since: a snapshot version, to only get the users whose cosmetics changed after it
  */
 func GetBadges(router fiber.Router) {
 	Avatar(router)
@@ -30,7 +33,25 @@ func GetBadges(router fiber.Router) {
 			return restutil.ErrMissingQueryParams().Send(c, `user_identifier: must be 'object_id', 'twitch_id' or 'login'`)
 		}
 
-		// Retrieve all users of badges
+		var since *int64
+		if s := c.Query("since"); s != "" {
+			v, err := strconv.ParseInt(s, 10, 64)
+			if err != nil || v < 0 {
+				return restutil.ErrBadRequest().Send(c, "since: must be a snapshot version")
+			}
+			since = &v
+		}
+
+		// Serve the precomputed snapshot
+		snapshot, err := actions.Cosmetics.GetSnapshot(ctx)
+		if err != nil {
+			return restutil.ErrInternalServer().Send(c, err.Error())
+		}
+		if snapshot != nil {
+			return sendCosmeticsSnapshot(c, snapshot, since, idType)
+		}
+
+		// The snapshot wasn't made yet, so retrieve all users of badges
 		pipeline := mongo.Pipeline{
 			{{
 				Key:   "$sort",
@@ -44,8 +65,7 @@ func GetBadges(router fiber.Router) {
 					"pipeline": mongo.Pipeline{
 						{{
 							Key: "$match",
-							Value: bson.M{
-								"disabled": bson.M{"$not": bson.M{"$eq": true}},
+							Value: actions.Entitlements.ActiveFilter(bson.M{
 								"$or": bson.A{
 									bson.M{
 										"data.selected": true,
@@ -58,7 +78,7 @@ func GetBadges(router fiber.Router) {
 									},
 									bson.M{"kind": "ROLE"},
 								},
-							},
+							}),
 						}},
 						{{
 							Key: "$group",
@@ -189,9 +209,74 @@ func GetBadges(router fiber.Router) {
 	})
 }
 
+// sendCosmeticsSnapshot: Send the cosmetics of all users in the snapshot,
+// or only of those whose cosmetics changed after the given version
+func sendCosmeticsSnapshot(c *fiber.Ctx, snapshot *actions.CosmeticsSnapshot, since *int64, idType string) error {
+	ctx := c.Context()
+	result := GetCosmeticsResult{
+		Version: snapshot.Version,
+		Badges:  []*restutil.BadgeCosmeticResponse{},
+		Paints:  []*restutil.PaintCosmeticResponse{},
+	}
+
+	users := snapshot.Users
+	if since != nil {
+		changed, ok, err := actions.Cosmetics.GetSnapshotChanges(ctx, *since, snapshot)
+		if err != nil {
+			return restutil.ErrInternalServer().Send(c, err.Error())
+		}
+		// If the changes since that version are no longer known, the full snapshot is sent instead
+		if ok {
+			changedUsers := make([]*datastructure.User, len(changed))
+			for i, u := range changed {
+				changedUsers[i] = u.User()
+			}
+			result.Partial = true
+			result.ChangedUsers = restutil.SelectUserIDType(changedUsers, idType)
+			users = changed
+		}
+	}
+
+	// Group the users by the cosmetics they display
+	cosmeticUsers := make(map[primitive.ObjectID][]*datastructure.User)
+	for _, u := range users {
+		if u.BadgeID != nil {
+			cosmeticUsers[*u.BadgeID] = append(cosmeticUsers[*u.BadgeID], u.User())
+		}
+		if u.PaintID != nil {
+			cosmeticUsers[*u.PaintID] = append(cosmeticUsers[*u.PaintID], u.User())
+		}
+	}
+
+	for _, cos := range snapshot.Cosmetics {
+		cosUsers, ok := cosmeticUsers[cos.ID]
+		if !ok {
+			continue
+		}
+
+		switch cos.Kind {
+		case datastructure.CosmeticKindBadge:
+			result.Badges = append(result.Badges, restutil.CreateBadgeResponse(cos, cosUsers, idType))
+		case datastructure.CosmeticKindNametagPaint:
+			result.Paints = append(result.Paints, restutil.CreatePaintResponse(cos, cosUsers, idType))
+		}
+	}
+
+	b, err := json.Marshal(&result)
+	if err != nil {
+		return restutil.ErrInternalServer().Send(c, err.Error())
+	}
+	return c.Status(200).Send(b)
+}
+
 type GetCosmeticsResult struct {
-	Badges []*restutil.BadgeCosmeticResponse `json:"badges"`
-	Paints []*restutil.PaintCosmeticResponse `json:"paints"`
+	Version int64 `json:"version,omitempty"`
+	// Set if only the users whose cosmetics changed since the requested version are listed.
+	// Clients should clear the cosmetics of the changed users before applying those listed
+	Partial      bool                              `json:"partial,omitempty"`
+	ChangedUsers []string                          `json:"changed_users,omitempty"`
+	Badges       []*restutil.BadgeCosmeticResponse `json:"badges"`
+	Paints       []*restutil.PaintCosmeticResponse `json:"paints"`
 }
 
 type cosmeticsResult struct {
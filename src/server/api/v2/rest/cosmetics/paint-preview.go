@@ -0,0 +1,70 @@
+package cosmetics
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/SevenTV/ServerGo/src/mongo/datastructure"
+	"github.com/SevenTV/ServerGo/src/server/api/v2/rest/restutil"
+	"github.com/SevenTV/ServerGo/src/server/middleware"
+	"github.com/gofiber/fiber/v2"
+	"github.com/sirupsen/logrus"
+)
+
+const MAX_PAINT_PREVIEW_TEXT_LENGTH = 25 // The longest a Twitch username can be
+const DEFAULT_PAINT_PREVIEW_TEXT = "7TV"
+
+/*
+* Query Params:
+* text: the sample text to apply the paint to
+ */
+// PaintPreviewRoute: Render a paint definition applied to sample text, so that it can be seen before the paint is created
+func PaintPreviewRoute(router fiber.Router) {
+	router.Post(
+		"/paints/preview",
+		middleware.UserAuthMiddleware(true),
+		func(c *fiber.Ctx) error {
+			c.Set("Content-Type", "application/json")
+			usr, ok := c.Locals("user").(*datastructure.User)
+			if !ok {
+				return restutil.ErrLoginRequired().Send(c)
+			}
+			if !usr.HasPermission(datastructure.RolePermissionManageCosmetics) {
+				return restutil.ErrAccessDenied().Send(c)
+			}
+
+			text := strings.TrimSpace(c.Query("text", DEFAULT_PAINT_PREVIEW_TEXT))
+			if text == "" {
+				text = DEFAULT_PAINT_PREVIEW_TEXT
+			}
+			if utf8.RuneCountInString(text) > MAX_PAINT_PREVIEW_TEXT_LENGTH {
+				return restutil.ErrBadRequest().Send(c, fmt.Sprintf("Text Too Long (max %d characters)", MAX_PAINT_PREVIEW_TEXT_LENGTH))
+			}
+
+			// The body is the paint's data, as it would be stored
+			paint := &datastructure.CosmeticDataPaint{}
+			if err := json.Unmarshal(c.Body(), paint); err != nil {
+				return restutil.ErrBadRequest().Send(c, "Invalid Paint Data")
+			}
+			if err := datastructure.CosmeticUtil.ValidatePaint(paint); err != nil {
+				return restutil.ErrBadRequest().Send(c, fmt.Sprintf("Invalid Paint: %s", err.Error()))
+			}
+
+			b, mime, err := RenderPaintPreview(paint, text)
+			if err != nil {
+				if errors.Is(err, ErrPaintImageNotReadable) {
+					return restutil.ErrBadRequest().Send(c, err.Error())
+				}
+				logrus.WithError(err).Error("RenderPaintPreview")
+				return restutil.ErrInternalServer().Send(c, "Rendering Failure")
+			}
+
+			c.Set("Content-Type", mime)
+			c.Set("Cache-Control", "no-store")
+			return c.Status(200).Send(b)
+		},
+	)
+}
@@ -0,0 +1,498 @@
+package cosmetics
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/SevenTV/ServerGo/src/mongo/datastructure"
+	"github.com/sizeofint/webpanimation"
+	"golang.org/x/image/draw"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/gobold"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/math/fixed"
+	_ "golang.org/x/image/webp"
+)
+
+const PAINT_PREVIEW_FONT_SIZE = 48
+const PAINT_PREVIEW_PADDING = 8
+const PAINT_PREVIEW_MAX_SHADOW_EXTENT = 64 // How far shadows may extend the canvas, in pixels
+const PAINT_PREVIEW_MAX_BLUR = 32          // The largest blur deviation rendered, in pixels
+const PAINT_PREVIEW_FRAME_DELAY = 50       // Milliseconds per frame of an animated preview
+const PAINT_PREVIEW_MAX_FRAMES = 60
+const PAINT_PREVIEW_MAX_IMAGE_SIZE = 5000000   // 5MB
+const PAINT_PREVIEW_MAX_IMAGE_DIMENSION = 4096 // The largest width or height of a paint image, before and after scaling
+const PAINT_PREVIEW_MAX_IMAGE_REDIRECTS = 5
+
+var ErrPaintImageNotReadable = errors.New("Paint Image Not Readable")
+
+var (
+	paintPreviewFace     font.Face
+	paintPreviewFaceErr  error
+	paintPreviewFaceOnce sync.Once
+)
+
+var paintImageClient = &http.Client{
+	Timeout:   10 * time.Second,
+	Transport: newPaintImageTransport(),
+	// Redirects may not leave the host the paint image is served from
+	CheckRedirect: func(req *http.Request, via []*http.Request) error {
+		if len(via) >= PAINT_PREVIEW_MAX_IMAGE_REDIRECTS {
+			return fmt.Errorf("stopped after %d redirects", PAINT_PREVIEW_MAX_IMAGE_REDIRECTS)
+		}
+		if req.URL.Scheme != "https" || req.URL.Host != via[0].URL.Host {
+			return fmt.Errorf("redirect to %s is not allowed", req.URL.Host)
+		}
+		return nil
+	},
+}
+
+// newPaintImageTransport: Get a transport which only connects to public addresses.
+// Any https url is accepted for a paint image, so it must not be able to reach the internal network.
+// The address is checked once resolved, so a hostname can't be made to point at an internal address
+func newPaintImageTransport() *http.Transport {
+	dialer := &net.Dialer{
+		Timeout: 5 * time.Second,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			ip := net.ParseIP(host)
+			if ip == nil || ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+				ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() || ip.IsMulticast() {
+				return fmt.Errorf("address %s is not allowed", host)
+			}
+			return nil
+		},
+	}
+
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	t.Proxy = nil // A proxy would make the connection on the server's behalf
+	t.DialContext = dialer.DialContext
+	return t
+}
+
+// getPaintPreviewFace: Get the font which sample text is written in, loading it on first use
+func getPaintPreviewFace() (font.Face, error) {
+	paintPreviewFaceOnce.Do(func() {
+		f, err := opentype.Parse(gobold.TTF)
+		if err != nil {
+			paintPreviewFaceErr = err
+			return
+		}
+
+		paintPreviewFace, paintPreviewFaceErr = opentype.NewFace(f, &opentype.FaceOptions{
+			Size:    PAINT_PREVIEW_FONT_SIZE,
+			DPI:     72,
+			Hinting: font.HintingFull,
+		})
+	})
+	return paintPreviewFace, paintPreviewFaceErr
+}
+
+// A premultiplied RGBA color, with components between 0 and 1
+type paintColor [4]float64
+
+func decodePaintColor(c int32) paintColor {
+	u := uint32(c)
+	a := float64(u&0xFF) / 255
+	return paintColor{
+		float64(u>>24&0xFF) / 255 * a,
+		float64(u>>16&0xFF) / 255 * a,
+		float64(u>>8&0xFF) / 255 * a,
+		a,
+	}
+}
+
+func (c paintColor) lerp(to paintColor, t float64) paintColor {
+	for i := range c {
+		c[i] += (to[i] - c[i]) * t
+	}
+	return c
+}
+
+// over: Composite the color over another
+func (c paintColor) over(below paintColor) paintColor {
+	for i := range c {
+		c[i] += below[i] * (1 - c[3])
+	}
+	return c
+}
+
+// A layer of premultiplied pixels
+type paintLayer struct {
+	width, height int
+	pix           []paintColor
+}
+
+func newPaintLayer(width, height int) *paintLayer {
+	return &paintLayer{width, height, make([]paintColor, width*height)}
+}
+
+func (l *paintLayer) toImage() *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, l.width, l.height))
+	for i, c := range l.pix {
+		if c[3] <= 0 {
+			continue
+		}
+		for j := 0; j < 3; j++ {
+			img.Pix[i*4+j] = uint8(math.Round(math.Min(c[j]/c[3], 1) * 255))
+		}
+		img.Pix[i*4+3] = uint8(math.Round(math.Min(c[3], 1) * 255))
+	}
+	return img
+}
+
+// paintRenderer draws sample text filled with a paint, as the extension displays it in chat
+type paintRenderer struct {
+	paint   *datastructure.CosmeticDataPaint
+	base    paintColor  // The color of the text under the paint
+	image   image.Image // The image of a url paint, scaled to cover the text
+	shadows []datastructure.CosmeticPaintDropShadow
+
+	mask          *image.Alpha // The shape of the text on the canvas
+	textX, textY  int          // Where the text starts on the canvas
+	textW, textH  int
+	width, height int
+}
+
+// newPaintRenderer: Lay out the text and prepare the paint for rendering. The paint must be valid
+func newPaintRenderer(paint *datastructure.CosmeticDataPaint, text string) (*paintRenderer, error) {
+	face, err := getPaintPreviewFace()
+	if err != nil {
+		return nil, err
+	}
+
+	r := &paintRenderer{
+		paint:   paint,
+		base:    paintColor{1, 1, 1, 1},
+		shadows: paint.DropShadows,
+	}
+	if paint.Color != nil {
+		r.base = decodePaintColor(*paint.Color)
+	}
+	// Older paints define a single drop shadow
+	if len(r.shadows) == 0 && (paint.DropShadow.Radius > 0 || paint.DropShadow.Color != 0) {
+		r.shadows = []datastructure.CosmeticPaintDropShadow{paint.DropShadow}
+	}
+
+	// Make room for the shadows, each one applying to the result of the previous
+	extent := 0.0
+	for _, s := range r.shadows {
+		extent += math.Max(math.Abs(s.OffsetX), math.Abs(s.OffsetY)) + 3*math.Min(s.Radius/2, PAINT_PREVIEW_MAX_BLUR)
+	}
+	pad := PAINT_PREVIEW_PADDING + int(math.Ceil(math.Min(extent, PAINT_PREVIEW_MAX_SHADOW_EXTENT)))
+
+	metrics := face.Metrics()
+	r.textW = font.MeasureString(face, text).Ceil()
+	r.textH = (metrics.Ascent + metrics.Descent).Ceil()
+	r.textX, r.textY = pad, pad
+	r.width, r.height = r.textW+2*pad, r.textH+2*pad
+
+	r.mask = image.NewAlpha(image.Rect(0, 0, r.width, r.height))
+	d := font.Drawer{
+		Dst:  r.mask,
+		Src:  image.Opaque,
+		Face: face,
+		Dot:  fixed.P(r.textX, r.textY+metrics.Ascent.Ceil()),
+	}
+	d.DrawString(text)
+
+	if paint.Function == datastructure.CosmeticPaintFunctionImageURL {
+		if r.image, err = fetchPaintImage(paint.ImageURL, r.textW, r.textH); err != nil {
+			return nil, err
+		}
+	}
+
+	return r, nil
+}
+
+// fetchPaintImage: Download the image of a url paint, scaling it to cover an area like a CSS background
+func fetchPaintImage(url string, width, height int) (image.Image, error) {
+	resp, err := paintImageClient.Get(url)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrPaintImageNotReadable, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%w: status %d", ErrPaintImageNotReadable, resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, PAINT_PREVIEW_MAX_IMAGE_SIZE))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrPaintImageNotReadable, err)
+	}
+
+	// Check the dimensions before decoding, as a small file can hold a huge image
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrPaintImageNotReadable, err)
+	}
+	if cfg.Width == 0 || cfg.Height == 0 {
+		return nil, ErrPaintImageNotReadable
+	}
+	if cfg.Width > PAINT_PREVIEW_MAX_IMAGE_DIMENSION || cfg.Height > PAINT_PREVIEW_MAX_IMAGE_DIMENSION {
+		return nil, fmt.Errorf("%w: larger than %dx%d", ErrPaintImageNotReadable, PAINT_PREVIEW_MAX_IMAGE_DIMENSION, PAINT_PREVIEW_MAX_IMAGE_DIMENSION)
+	}
+
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrPaintImageNotReadable, err)
+	}
+
+	b := src.Bounds()
+	if b.Dx() == 0 || b.Dy() == 0 {
+		return nil, ErrPaintImageNotReadable
+	}
+	scale := math.Max(float64(width)/float64(b.Dx()), float64(height)/float64(b.Dy()))
+
+	// Keep only as much of the scaled image as fits the size limit, so that an image with an extreme aspect ratio stays small
+	w := math.Min(math.Ceil(float64(b.Dx())*scale), PAINT_PREVIEW_MAX_IMAGE_DIMENSION)
+	h := math.Min(math.Ceil(float64(b.Dy())*scale), PAINT_PREVIEW_MAX_IMAGE_DIMENSION)
+	area := image.Rect(b.Min.X, b.Min.Y, b.Min.X+int(math.Ceil(w/scale)), b.Min.Y+int(math.Ceil(h/scale))).Intersect(b)
+
+	dst := image.NewNRGBA(image.Rect(0, 0, int(w), int(h)))
+	draw.CatmullRom.Scale(dst, dst.Rect, src, area, draw.Src, nil)
+	return dst, nil
+}
+
+// gradientAt: Get the color of the gradient at a position along it
+func (r *paintRenderer) gradientAt(t float64) paintColor {
+	stops := r.paint.Stops
+	first, last := stops[0].At, stops[len(stops)-1].At
+	if r.paint.Repeat && last > first {
+		t = first + math.Mod(math.Mod(t-first, last-first)+(last-first), last-first)
+	}
+
+	if t <= first {
+		return decodePaintColor(stops[0].Color)
+	}
+	for i := 1; i < len(stops); i++ {
+		if t > stops[i].At {
+			continue
+		}
+
+		from, to := stops[i-1], stops[i]
+		if to.At <= from.At {
+			return decodePaintColor(to.Color)
+		}
+		return decodePaintColor(from.Color).lerp(decodePaintColor(to.Color), (t-from.At)/(to.At-from.At))
+	}
+	return decodePaintColor(stops[len(stops)-1].Color)
+}
+
+// fillAt: Get the color of the paint at a position relative to the text
+func (r *paintRenderer) fillAt(x, y float64) paintColor {
+	w, h := float64(r.textW), float64(r.textH)
+
+	switch r.paint.Function {
+	case datastructure.CosmeticPaintFunctionLinearGradient:
+		// As in CSS, 0deg points up and angles go clockwise, with the gradient line spanning the corners
+		a := float64(r.paint.Angle) * math.Pi / 180
+		sin, cos := math.Sin(a), math.Cos(a)
+		length := math.Abs(w*sin) + math.Abs(h*cos)
+		if length == 0 {
+			return r.gradientAt(0)
+		}
+		return r.gradientAt(((x-w/2)*sin-(y-h/2)*cos)/length + 0.5)
+	case datastructure.CosmeticPaintFunctionRadialGradient:
+		// Gradients end at the farthest corner, as is the default in CSS
+		dx, dy := x-w/2, y-h/2
+		if r.paint.Shape == "circle" {
+			radius := math.Hypot(w/2, h/2)
+			if radius == 0 {
+				return r.gradientAt(0)
+			}
+			return r.gradientAt(math.Hypot(dx, dy) / radius)
+		}
+		rx, ry := w/2*math.Sqrt2, h/2*math.Sqrt2
+		if rx == 0 || ry == 0 {
+			return r.gradientAt(0)
+		}
+		return r.gradientAt(math.Hypot(dx/rx, dy/ry))
+	case datastructure.CosmeticPaintFunctionImageURL:
+		// The image repeats, as a CSS background does
+		b := r.image.Bounds()
+		ix := int(math.Floor(x)) % b.Dx()
+		iy := int(math.Floor(y)) % b.Dy()
+		if ix < 0 {
+			ix += b.Dx()
+		}
+		if iy < 0 {
+			iy += b.Dy()
+		}
+		c := color.NRGBAModel.Convert(r.image.At(b.Min.X+ix, b.Min.Y+iy)).(color.NRGBA)
+		a := float64(c.A) / 255
+		return paintColor{float64(c.R) / 255 * a, float64(c.G) / 255 * a, float64(c.B) / 255 * a, a}
+	}
+	return r.base
+}
+
+// render: Draw a frame, with the paint moved by a fraction of the text's size
+func (r *paintRenderer) render(offsetX, offsetY float64) *image.NRGBA {
+	layer := newPaintLayer(r.width, r.height)
+	ox, oy := offsetX*float64(r.textW), offsetY*float64(r.textH)
+
+	for y := 0; y < r.height; y++ {
+		for x := 0; x < r.width; x++ {
+			coverage := float64(r.mask.AlphaAt(x, y).A) / 255
+			if coverage == 0 {
+				continue
+			}
+
+			c := r.fillAt(float64(x-r.textX)+0.5-ox, float64(y-r.textY)+0.5-oy).over(r.base)
+			for i := range c {
+				c[i] *= coverage
+			}
+			layer.pix[y*r.width+x] = c
+		}
+	}
+
+	for _, s := range r.shadows {
+		layer = r.applyDropShadow(layer, s)
+	}
+	return layer.toImage()
+}
+
+// applyDropShadow: Draw a blurred and offset copy of the layer's shape under it, like a CSS drop-shadow filter
+func (r *paintRenderer) applyDropShadow(layer *paintLayer, s datastructure.CosmeticPaintDropShadow) *paintLayer {
+	w, h := layer.width, layer.height
+	alpha := make([]float64, w*h)
+	dx, dy := int(math.Round(s.OffsetX)), int(math.Round(s.OffsetY))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			sx, sy := x-dx, y-dy
+			if sx < 0 || sy < 0 || sx >= w || sy >= h {
+				continue
+			}
+			alpha[y*w+x] = layer.pix[sy*w+sx][3]
+		}
+	}
+
+	// Three box blurs approximate a gaussian blur, whose deviation is half the shadow's radius
+	sigma := math.Min(s.Radius/2, PAINT_PREVIEW_MAX_BLUR)
+	if box := int(math.Round(math.Sqrt(sigma*sigma+0.25) - 0.5)); box > 0 {
+		for i := 0; i < 3; i++ {
+			alpha = boxBlur(alpha, w, h, box, 1, w)
+			alpha = boxBlur(alpha, h, w, box, w, 1)
+		}
+	}
+
+	shadow := decodePaintColor(s.Color)
+	result := newPaintLayer(w, h)
+	for i, c := range layer.pix {
+		sc := shadow
+		for j := range sc {
+			sc[j] *= alpha[i]
+		}
+		result.pix[i] = c.over(sc)
+	}
+	return result
+}
+
+// boxBlur: Average each value with its neighbours within the radius, along lines of the given length.
+// step is the distance between values of a line, stride the distance between lines
+func boxBlur(values []float64, length, lines, radius, step, stride int) []float64 {
+	out := make([]float64, len(values))
+	size := float64(2*radius + 1)
+	for l := 0; l < lines; l++ {
+		start := l * stride
+		sum := 0.0
+		for i := -radius; i <= radius; i++ {
+			if i >= 0 && i < length {
+				sum += values[start+i*step]
+			}
+		}
+		for i := 0; i < length; i++ {
+			out[start+i*step] = sum / size
+			if j := i - radius; j >= 0 {
+				sum -= values[start+j*step]
+			}
+			if j := i + radius + 1; j < length {
+				sum += values[start+j*step]
+			}
+		}
+	}
+	return out
+}
+
+// keyframePosition: Get the paint's offset at a point of its animation, between 0 and 1
+func keyframePosition(keyframes []datastructure.CosmeticPaintAnimationKeyframe, t float64) (float64, float64) {
+	if t <= keyframes[0].At {
+		return keyframes[0].X, keyframes[0].Y
+	}
+	for i := 1; i < len(keyframes); i++ {
+		from, to := keyframes[i-1], keyframes[i]
+		if t > to.At {
+			continue
+		}
+
+		f := (t - from.At) / (to.At - from.At)
+		return from.X + (to.X-from.X)*f, from.Y + (to.Y-from.Y)*f
+	}
+	last := keyframes[len(keyframes)-1]
+	return last.X, last.Y
+}
+
+// RenderPaintPreview: Render sample text filled with the paint, as a PNG or as an animated WebP if the paint is animated.
+// Returns the image and its MIME type
+func RenderPaintPreview(paint *datastructure.CosmeticDataPaint, text string) ([]byte, string, error) {
+	r, err := newPaintRenderer(paint, text)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var b bytes.Buffer
+	keyframes := paint.Animation.Keyframes
+	if len(keyframes) == 0 {
+		if err := png.Encode(&b, r.render(0, 0)); err != nil {
+			return nil, "", err
+		}
+		return b.Bytes(), "image/png", nil
+	}
+
+	// One frame per delay, within the limit, over the duration of the animation
+	duration := int(paint.Animation.Speed)
+	frames := duration / PAINT_PREVIEW_FRAME_DELAY
+	if frames > PAINT_PREVIEW_MAX_FRAMES {
+		frames = PAINT_PREVIEW_MAX_FRAMES
+	} else if frames < 2 {
+		frames = 2
+	}
+
+	anim := webpanimation.NewWebpAnimation(r.width, r.height, 0)
+	anim.WebPAnimEncoderOptions.SetKmin(3)
+	anim.WebPAnimEncoderOptions.SetKmax(5)
+	defer anim.ReleaseMemory()
+
+	cfg := webpanimation.NewWebpConfig()
+	cfg.SetLossless(0)
+	cfg.SetQuality(90)
+
+	for i := 0; i < frames; i++ {
+		x, y := keyframePosition(keyframes, float64(i)/float64(frames))
+		if err := anim.AddFrame(r.render(x, y), i*duration/frames, cfg); err != nil {
+			return nil, "", err
+		}
+	}
+	if err := anim.AddFrame(nil, duration, cfg); err != nil {
+		return nil, "", err
+	}
+
+	if err := anim.Encode(&b); err != nil {
+		return nil, "", err
+	}
+	return b.Bytes(), "image/webp", nil
+}
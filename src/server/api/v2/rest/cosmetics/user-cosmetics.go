@@ -0,0 +1,157 @@
+package cosmetics
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/SevenTV/ServerGo/src/mongo"
+	"github.com/SevenTV/ServerGo/src/mongo/datastructure"
+	"github.com/SevenTV/ServerGo/src/server/api/actions"
+	"github.com/SevenTV/ServerGo/src/server/api/v2/rest/restutil"
+	"github.com/SevenTV/ServerGo/src/utils"
+	"github.com/gofiber/fiber/v2"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const MAX_USER_COSMETICS_BATCH = 100
+
+/*
+* Query Params:
+* user_identifier: "object_id", "twitch_id", "login"
+* users: comma-separated list of user identifiers
+ */
+// GetUserCosmeticsRoute: Get the badge and paint displayed by each of the requested users
+func GetUserCosmeticsRoute(router fiber.Router) {
+	router.Get("/users", func(c *fiber.Ctx) error {
+		ctx := c.Context()
+		c.Set("Cache-Control", "max-age=150 s-maxage=300")
+
+		idType := c.Query("user_identifier")
+		if !utils.Contains([]string{"object_id", "twitch_id", "login"}, idType) {
+			return restutil.ErrMissingQueryParams().Send(c, `user_identifier: must be 'object_id', 'twitch_id' or 'login'`)
+		}
+
+		// Parse the list of users, ignoring duplicates
+		identifiers := []string{}
+		seen := make(map[string]bool)
+		for _, s := range strings.Split(c.Query("users"), ",") {
+			s = strings.TrimSpace(s)
+			if idType == "login" {
+				s = strings.ToLower(s)
+			}
+			if s == "" || seen[s] {
+				continue
+			}
+			identifiers = append(identifiers, s)
+			seen[s] = true
+		}
+		if len(identifiers) == 0 {
+			return restutil.ErrMissingQueryParams().Send(c, "users: must be a comma-separated list of users")
+		}
+		if len(identifiers) > MAX_USER_COSMETICS_BATCH {
+			return restutil.ErrBadRequest().Send(c, fmt.Sprintf("Too Many Users (max %d)", MAX_USER_COSMETICS_BATCH))
+		}
+
+		var filter bson.M
+		switch idType {
+		case "object_id":
+			ids := make([]primitive.ObjectID, 0, len(identifiers))
+			for _, s := range identifiers {
+				id, err := primitive.ObjectIDFromHex(s)
+				if err != nil {
+					return restutil.MalformedObjectId().Send(c)
+				}
+				ids = append(ids, id)
+			}
+			filter = bson.M{"_id": bson.M{"$in": ids}}
+		case "twitch_id":
+			filter = bson.M{"id": bson.M{"$in": identifiers}}
+		case "login":
+			filter = bson.M{"login": bson.M{"$in": identifiers}}
+		}
+
+		// Find the users, only fetching their identifiers
+		users := []*datastructure.User{}
+		cur, err := mongo.Collection(mongo.CollectionNameUsers).Find(ctx, filter, options.Find().SetProjection(bson.M{
+			"_id":   1,
+			"id":    1,
+			"login": 1,
+		}))
+		if err != nil {
+			logrus.WithError(err).Error("mongo")
+			return restutil.ErrInternalServer().Send(c, err.Error())
+		}
+		if err = cur.All(ctx, &users); err != nil {
+			logrus.WithError(err).Error("mongo")
+			return restutil.ErrInternalServer().Send(c, err.Error())
+		}
+
+		userIDs := make([]primitive.ObjectID, len(users))
+		for i, u := range users {
+			userIDs[i] = u.ID
+		}
+		userCosmetics, err := actions.Cosmetics.GetUserCosmetics(ctx, userIDs)
+		if err != nil {
+			return restutil.ErrInternalServer().Send(c, err.Error())
+		}
+
+		// Group the users by the cosmetics they display
+		cosmeticUsers := make(map[primitive.ObjectID][]*datastructure.User)
+		for _, u := range users {
+			uc := userCosmetics[u.ID]
+			if uc.BadgeID != nil {
+				cosmeticUsers[*uc.BadgeID] = append(cosmeticUsers[*uc.BadgeID], u)
+			}
+			if uc.PaintID != nil {
+				cosmeticUsers[*uc.PaintID] = append(cosmeticUsers[*uc.PaintID], u)
+			}
+		}
+
+		result := GetCosmeticsResult{
+			Badges: []*restutil.BadgeCosmeticResponse{},
+			Paints: []*restutil.PaintCosmeticResponse{},
+		}
+		if len(cosmeticUsers) > 0 {
+			ids := make([]primitive.ObjectID, 0, len(cosmeticUsers))
+			for id := range cosmeticUsers {
+				ids = append(ids, id)
+			}
+
+			cosmetics := []*datastructure.Cosmetic{}
+			cur, err := mongo.Collection(mongo.CollectionNameCosmetics).Find(ctx, bson.M{
+				"_id": bson.M{"$in": ids},
+			}, options.Find().SetProjection(bson.M{"user_ids": 0}))
+			if err != nil {
+				logrus.WithError(err).Error("mongo")
+				return restutil.ErrInternalServer().Send(c, err.Error())
+			}
+			if err = cur.All(ctx, &cosmetics); err != nil {
+				logrus.WithError(err).Error("mongo")
+				return restutil.ErrInternalServer().Send(c, err.Error())
+			}
+			sort.Slice(cosmetics, func(i, j int) bool {
+				return cosmetics[i].Priority > cosmetics[j].Priority
+			})
+
+			for _, cos := range cosmetics {
+				switch cos.Kind {
+				case datastructure.CosmeticKindBadge:
+					result.Badges = append(result.Badges, restutil.CreateBadgeResponse(cos, cosmeticUsers[cos.ID], idType))
+				case datastructure.CosmeticKindNametagPaint:
+					result.Paints = append(result.Paints, restutil.CreatePaintResponse(cos, cosmeticUsers[cos.ID], idType))
+				}
+			}
+		}
+
+		b, err := json.Marshal(&result)
+		if err != nil {
+			return restutil.ErrInternalServer().Send(c, err.Error())
+		}
+		return c.Status(200).Send(b)
+	})
+}
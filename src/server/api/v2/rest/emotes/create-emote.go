@@ -1,6 +1,7 @@
 package emotes
 
 import (
+	"errors"
 	"fmt"
 	"image/gif"
 	"image/jpeg"
@@ -19,6 +20,7 @@ import (
 	"github.com/SevenTV/ServerGo/src/discord"
 	"github.com/SevenTV/ServerGo/src/mongo"
 	"github.com/SevenTV/ServerGo/src/mongo/datastructure"
+	"github.com/SevenTV/ServerGo/src/server/api/actions"
 	"github.com/SevenTV/ServerGo/src/server/api/v2/rest/restutil"
 	"github.com/SevenTV/ServerGo/src/server/middleware"
 	"github.com/SevenTV/ServerGo/src/utils"
@@ -28,7 +30,6 @@ import (
 	"github.com/sirupsen/logrus"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
-	"gopkg.in/gographics/imagick.v3/imagick"
 )
 
 const MAX_FRAME_COUNT = 4096
@@ -52,6 +53,9 @@ func CreateEmoteRoute(router fiber.Router) {
 			if !usr.HasPermission(datastructure.RolePermissionEmoteCreate) {
 				return restutil.ErrAccessDenied().Send(c)
 			}
+			if restricted, reason := actions.Bans.IsUserRestricted(usr.ID, datastructure.BanTypeUpload); restricted {
+				return restutil.ErrRestricted().Send(c, reason)
+			}
 
 			req := c.Request()
 			fctx := c.Context()
@@ -291,58 +295,11 @@ func CreateEmoteRoute(router fiber.Router) {
 				sizeX[i] = int16(width)
 				sizeY[i] = int16(height)
 
-				// Create new boundaries for frames
-				mw := imagick.NewMagickWand() // Get magick wand & read the original image
-				if err = mw.SetResourceLimit(imagick.RESOURCE_MEMORY, 500); err != nil {
-					logrus.WithError(err).Error("SetResourceLimit")
-				}
-				if err := mw.ReadImage(ogFilePath); err != nil {
-					return restutil.ErrBadRequest().Send(c, fmt.Sprintf("Input File Not Readable: %s", err))
-				}
-
-				// Merge all frames with coalesce
-				aw := mw.CoalesceImages()
-				if err = aw.SetResourceLimit(imagick.RESOURCE_MEMORY, 500); err != nil {
-					logrus.WithError(err).Error("SetResourceLimit")
-				}
-				mw.Destroy()
-				defer aw.Destroy()
-
-				// Set delays
-				mw = imagick.NewMagickWand()
-				if err = mw.SetResourceLimit(imagick.RESOURCE_MEMORY, 500); err != nil {
-					logrus.WithError(err).Error("SetResourceLimit")
-				}
-				defer mw.Destroy()
-
-				// Add each frame to our animated image
-				mw.ResetIterator()
-				for ind := 0; ind < int(aw.GetNumberImages()); ind++ {
-					aw.SetIteratorIndex(ind)
-					img := aw.GetImage()
-
-					if err = img.ResizeImage(uint(width), uint(height), imagick.FILTER_LANCZOS); err != nil {
-						logrus.WithError(err).Errorf("ResizeImage i=%v", ind)
-						continue
-					}
-					if err = mw.AddImage(img); err != nil {
-						logrus.WithError(err).Errorf("AddImage i=%v", ind)
-					}
-					img.Destroy()
-				}
-
-				// Done - convert to WEBP
 				q, _ := strconv.Atoi(quality)
-				if err = mw.SetImageCompressionQuality(uint(q)); err != nil {
-					logrus.WithError(err).Error("SetImageCompressionQuality")
-				}
-				if err = mw.SetImageFormat("webp"); err != nil {
-					logrus.WithError(err).Error("SetImageFormat")
-				}
-
-				// Write to file
-				err = mw.WriteImages(outFile, true)
-				if err != nil {
+				if err := utils.ResizeImage(ogFilePath, outFile, uint(width), uint(height), uint(width), uint(height), uint(q)); err != nil {
+					if errors.Is(err, utils.ErrImageNotReadable) {
+						return restutil.ErrBadRequest().Send(c, err.Error())
+					}
 					logrus.WithError(err).Error("cmd")
 					return restutil.ErrInternalServer().Send(c)
 				}
@@ -6,8 +6,11 @@ import (
 
 	"github.com/SevenTV/ServerGo/src/configure"
 	"github.com/SevenTV/ServerGo/src/redis"
+	"github.com/SevenTV/ServerGo/src/server/api/v2/rest/audit"
+	"github.com/SevenTV/ServerGo/src/server/api/v2/rest/bans"
 	"github.com/SevenTV/ServerGo/src/server/api/v2/rest/cosmetics"
 	"github.com/SevenTV/ServerGo/src/server/api/v2/rest/emotes"
+	"github.com/SevenTV/ServerGo/src/server/api/v2/rest/subscriptions"
 	"github.com/SevenTV/ServerGo/src/server/api/v2/rest/users"
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/rewrite/v2"
@@ -34,10 +37,25 @@ func RestV2(app fiber.Router) fiber.Router {
 	userGroup := restGroup.Group("/users")
 	users.GetUser(userGroup)
 	users.GetChannelEmotesRoute(userGroup)
+	users.GetPersonalEmotesRoute(userGroup)
 	users.EditProfilePicture(userGroup)
+	users.NotificationEventsRoute(userGroup)
 
 	cosmeticsGroup := restGroup.Group("/cosmetics")
 	cosmetics.GetBadges(cosmeticsGroup)
+	cosmetics.GetUserCosmeticsRoute(cosmeticsGroup)
+	cosmetics.UploadBadgeImageRoute(cosmeticsGroup)
+	cosmetics.PaintPreviewRoute(cosmeticsGroup)
+
+	banGroup := restGroup.Group("/bans")
+	bans.GetOwnBansRoute(banGroup)
+	bans.CreateBanAppealRoute(banGroup)
+
+	auditGroup := restGroup.Group("/audit")
+	audit.ExportAuditLogsRoute(auditGroup)
+
+	subscriptionsGroup := restGroup.Group("/subscriptions")
+	subscriptions.WebhookRoute(subscriptionsGroup)
 
 	restGroup.Get("/webext", func(c *fiber.Ctx) error {
 		// result := &WebExtResult{}
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/SevenTV/ServerGo/src/mongo/datastructure"
 	"github.com/SevenTV/ServerGo/src/utils"
@@ -36,12 +37,15 @@ func createErrorResponse(status int, message string) *ErrorResponse {
 var (
 	ErrUnknownEmote       = func() *ErrorResponse { return createErrorResponse(404, "Unknown Emote") }
 	ErrUnknownUser        = func() *ErrorResponse { return createErrorResponse(404, "Unknown User") }
+	ErrUnknownBan         = func() *ErrorResponse { return createErrorResponse(404, "Unknown Ban") }
+	ErrUnknownBadge       = func() *ErrorResponse { return createErrorResponse(404, "Unknown Badge") }
 	MalformedObjectId     = func() *ErrorResponse { return createErrorResponse(400, "Malformed Object ID") }
 	ErrInternalServer     = func() *ErrorResponse { return createErrorResponse(500, "Internal Server Error (%s)") }
 	ErrBadRequest         = func() *ErrorResponse { return createErrorResponse(400, "Bad Request (%s)") }
 	ErrLoginRequired      = func() *ErrorResponse { return createErrorResponse(403, "Authentication Required") }
 	ErrAccessDenied       = func() *ErrorResponse { return createErrorResponse(403, "Insufficient Privilege") }
 	ErrMissingQueryParams = func() *ErrorResponse { return createErrorResponse(400, "Missing Query Params (%s)") }
+	ErrRestricted         = func() *ErrorResponse { return createErrorResponse(403, "Restricted (%s)") }
 )
 
 func CreateEmoteResponse(emote *datastructure.Emote, owner *datastructure.User) EmoteResponse {
@@ -93,6 +97,12 @@ type EmoteResponse struct {
 	URLs             [][]string    `json:"urls"`
 }
 
+type PersonalEmoteSetResponse struct {
+	ID         string          `json:"id"`
+	UnicodeTag string          `json:"unicode_tag"`
+	Emotes     []EmoteResponse `json:"emotes"`
+}
+
 func CreateUserResponse(user *datastructure.User, opt ...UserResponseOptions) *UserResponse {
 	var options UserResponseOptions
 	if len(opt) > 0 {
@@ -127,7 +137,7 @@ type UserResponse struct {
 
 func CreateBadgeResponse(badge *datastructure.Cosmetic, users []*datastructure.User, idType string) *BadgeCosmeticResponse {
 	// Get user list
-	userIDs := selectUserIDType(users, idType)
+	userIDs := SelectUserIDType(users, idType)
 
 	// Generate URLs
 	urls := make([][]string, 3)
@@ -162,7 +172,7 @@ func CreateBadgeResponse(badge *datastructure.Cosmetic, users []*datastructure.U
 
 func CreatePaintResponse(paint *datastructure.Cosmetic, users []*datastructure.User, idType string) *PaintCosmeticResponse {
 	// Get user list
-	userIDs := selectUserIDType(users, idType)
+	userIDs := SelectUserIDType(users, idType)
 
 	data := paint.ReadPaint()
 	if data == nil {
@@ -189,7 +199,7 @@ func CreatePaintResponse(paint *datastructure.Cosmetic, users []*datastructure.U
 	}
 }
 
-func selectUserIDType(users []*datastructure.User, t string) []string {
+func SelectUserIDType(users []*datastructure.User, t string) []string {
 	userIDs := make([]string, len(users))
 	for i, u := range users {
 		switch t {
@@ -229,3 +239,53 @@ type PaintCosmeticResponse struct {
 	DropShadows []datastructure.CosmeticPaintDropShadow   `json:"drop_shadows,omitempty"`
 	Animation   datastructure.CosmeticPaintAnimation      `json:"animation,omitempty"`
 }
+
+func CreateBanResponse(ban *datastructure.Ban, appeal *datastructure.BanAppeal) *BanResponse {
+	response := &BanResponse{
+		ID:     ban.ID.Hex(),
+		Type:   string(ban.GetType()),
+		Reason: ban.Reason,
+	}
+	if ban.ExpireAt != nil {
+		response.ExpireAt = ban.ExpireAt.Format(time.RFC3339)
+	}
+	if appeal != nil {
+		response.Appeal = CreateBanAppealResponse(appeal)
+	}
+
+	return response
+}
+
+func CreateBanAppealResponse(appeal *datastructure.BanAppeal) *BanAppealResponse {
+	response := &BanAppealResponse{
+		ID:         appeal.ID.Hex(),
+		BanID:      appeal.BanID.Hex(),
+		Message:    appeal.Message,
+		Status:     string(appeal.Status),
+		ReviewNote: appeal.ReviewNote,
+		CreatedAt:  appeal.ID.Timestamp().Format(time.RFC3339),
+	}
+	if appeal.ReviewedAt != nil {
+		response.ReviewedAt = appeal.ReviewedAt.Format(time.RFC3339)
+	}
+
+	return response
+}
+
+type BanResponse struct {
+	ID       string             `json:"id"`
+	Type     string             `json:"type"`
+	Reason   string             `json:"reason"`
+	ExpireAt string             `json:"expire_at,omitempty"`
+	Appeal   *BanAppealResponse `json:"appeal"`
+}
+
+type BanAppealResponse struct {
+	ID         string  `json:"id"`
+	BanID      string  `json:"ban_id"`
+	Message    string  `json:"message"`
+	Status     string  `json:"status"`
+	ReviewNote *string `json:"review_note"`
+	CreatedAt  string  `json:"created_at"`
+	ReviewedAt string  `json:"reviewed_at,omitempty"`
+}
@@ -0,0 +1,128 @@
+package subscriptions
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/SevenTV/ServerGo/src/configure"
+	"github.com/SevenTV/ServerGo/src/server/api/actions"
+	"github.com/SevenTV/ServerGo/src/server/api/v2/rest/restutil"
+	"github.com/gofiber/fiber/v2"
+	"github.com/sirupsen/logrus"
+)
+
+const SIGNATURE_HEADER = "X-Signature"
+
+var errInvalidSignature = fmt.Errorf("invalid signature")
+
+/*
+* Headers:
+* X-Signature: t=<unix timestamp>,v1=<hex encoded HMAC-SHA256 of "<timestamp>.<body>" using the webhook secret>
+ */
+// WebhookRoute: Receive subscription events from the payment provider
+func WebhookRoute(router fiber.Router) {
+	router.Post(
+		"/webhook",
+		func(c *fiber.Ctx) error {
+			secret := configure.Config.GetString("subscriptions.webhook_secret")
+			if secret == "" {
+				return restutil.ErrRestricted().Send(c, "Subscriptions Not Configured")
+			}
+
+			body := c.Body()
+			if err := verifySignature(c.Get(SIGNATURE_HEADER), body, secret, time.Now()); err != nil {
+				return restutil.ErrAccessDenied().Send(c)
+			}
+
+			event := actions.SubscriptionEvent{}
+			if err := json.Unmarshal(body, &event); err != nil {
+				return restutil.ErrBadRequest().Send(c, "Invalid Event")
+			}
+
+			provider := configure.Config.GetString("subscriptions.provider")
+			sub, err := actions.Subscriptions.ApplyEvent(c.Context(), provider, event)
+			switch err {
+			case nil:
+			case actions.ErrInvalidSubscriptionEvent:
+				return restutil.ErrBadRequest().Send(c, "Invalid Event")
+			case actions.ErrUnknownSubscriptionTier:
+				return restutil.ErrBadRequest().Send(c, "Unknown Price")
+			case actions.ErrUnknownSubscriptionUser:
+				return restutil.ErrUnknownUser().Send(c)
+			default:
+				return restutil.ErrInternalServer().Send(c, "Event Not Applied")
+			}
+
+			logrus.WithFields(logrus.Fields{
+				"event_id": event.ID,
+				"type":     event.Type,
+				"applied":  sub != nil,
+			}).Info("subscriptions, received webhook event")
+
+			b, _ := json.Marshal(&WebhookResult{
+				Received: true,
+			})
+			return c.Status(200).Send(b)
+		},
+	)
+}
+
+type WebhookResult struct {
+	Received bool `json:"received"`
+}
+
+// verifySignature: Check that a webhook delivery was signed with the secret shared with the provider,
+// recently enough that it can't be an old delivery being replayed
+func verifySignature(header string, body []byte, secret string, now time.Time) error {
+	var timestamp string
+	signatures := []string{}
+	for _, part := range strings.Split(header, ",") {
+		kv := strings.SplitN(strings.TrimSpace(part), "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		switch kv[0] {
+		case "t":
+			timestamp = kv[1]
+		case "v1":
+			signatures = append(signatures, kv[1])
+		}
+	}
+	if timestamp == "" || len(signatures) == 0 {
+		return errInvalidSignature
+	}
+
+	t, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return errInvalidSignature
+	}
+	tolerance := configure.Config.GetDuration("subscriptions.webhook_tolerance")
+	if tolerance <= 0 {
+		tolerance = 5 * time.Minute
+	}
+	if math.Abs(float64(now.Unix()-t)) > tolerance.Seconds() {
+		return errInvalidSignature
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	expected := mac.Sum(nil)
+
+	// The provider may sign with several secrets while rotating them
+	for _, s := range signatures {
+		sig, err := hex.DecodeString(s)
+		if err == nil && hmac.Equal(sig, expected) {
+			return nil
+		}
+	}
+	return errInvalidSignature
+}
@@ -0,0 +1,87 @@
+package subscriptions
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(fmt.Sprintf("%d.%s", timestamp, body)))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestVerifySignature(t *testing.T) {
+	const secret = "whsec_test"
+	body := []byte(`{"id":"evt_1","type":"subscription.renewed"}`)
+	now := time.Unix(1700000000, 0)
+	ts := now.Unix()
+
+	tests := []struct {
+		name   string
+		header string
+		valid  bool
+	}{
+		{
+			name:   "valid signature",
+			header: fmt.Sprintf("t=%d,v1=%s", ts, sign(secret, ts, body)),
+			valid:  true,
+		},
+		{
+			name:   "wrong secret",
+			header: fmt.Sprintf("t=%d,v1=%s", ts, sign("whsec_other", ts, body)),
+			valid:  false,
+		},
+		{
+			name:   "timestamp outside tolerance",
+			header: fmt.Sprintf("t=%d,v1=%s", ts-3600, sign(secret, ts-3600, body)),
+			valid:  false,
+		},
+		{
+			name:   "timestamp in the future outside tolerance",
+			header: fmt.Sprintf("t=%d,v1=%s", ts+3600, sign(secret, ts+3600, body)),
+			valid:  false,
+		},
+		{
+			name:   "malformed header",
+			header: "garbage",
+			valid:  false,
+		},
+		{
+			name:   "missing signature",
+			header: fmt.Sprintf("t=%d", ts),
+			valid:  false,
+		},
+		{
+			name:   "non-numeric timestamp",
+			header: fmt.Sprintf("t=abc,v1=%s", sign(secret, ts, body)),
+			valid:  false,
+		},
+		{
+			name:   "multiple v1 entries with one valid",
+			header: fmt.Sprintf("t=%d, v1=%s, v1=%s", ts, sign("whsec_old", ts, body), sign(secret, ts, body)),
+			valid:  true,
+		},
+		{
+			name:   "multiple v1 entries with none valid",
+			header: fmt.Sprintf("t=%d,v1=%s,v1=nothex", ts, sign("whsec_old", ts, body)),
+			valid:  false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := verifySignature(tt.header, body, secret, now)
+			if tt.valid && err != nil {
+				t.Errorf("expected the signature to be accepted, got %v", err)
+			}
+			if !tt.valid && err == nil {
+				t.Error("expected the signature to be rejected")
+			}
+		})
+	}
+}
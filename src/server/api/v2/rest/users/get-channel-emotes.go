@@ -39,7 +39,7 @@ func GetChannelEmotesRoute(router fiber.Router) {
 			}
 			channel = &ub.User
 
-			// Build query for emotes
+			// Build query for emotes. The channel's emotes are those of its active emote set
 			var emotes []*datastructure.Emote
 			emoteFilter := bson.M{
 				"_id": bson.M{
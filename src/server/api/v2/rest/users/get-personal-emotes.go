@@ -0,0 +1,87 @@
+package users
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/SevenTV/ServerGo/src/mongo"
+	"github.com/SevenTV/ServerGo/src/mongo/datastructure"
+	"github.com/SevenTV/ServerGo/src/server/api/actions"
+	"github.com/SevenTV/ServerGo/src/server/api/v2/rest/restutil"
+	"github.com/SevenTV/ServerGo/src/server/middleware"
+	"github.com/gofiber/fiber/v2"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// GetPersonalEmotesRoute: Get the personal emote sets of a user by their Twitch ID,
+// so that chat clients can show the user's personal emotes in any channel
+func GetPersonalEmotesRoute(router fiber.Router) {
+	router.Get("/:user/personal-emotes", middleware.RateLimitMiddleware("get-personal-emotes", 100, 9*time.Second),
+		func(c *fiber.Ctx) error {
+			ctx := c.Context()
+			c.Set("Cache-Control", "max-age=30")
+
+			ub, err := actions.Users.Get(ctx, bson.M{"id": c.Params("user")})
+			if err != nil {
+				if err == mongo.ErrNoDocuments {
+					return restutil.ErrUnknownUser().Send(c)
+				}
+				logrus.WithError(err).Error("mongo")
+				return restutil.ErrInternalServer().Send(c, err.Error())
+			}
+			if ub.IsBanned() {
+				return c.SendString("[]")
+			}
+
+			sets, err := actions.Entitlements.GetPersonalEmoteSets(ctx, ub.User.ID)
+			if err != nil {
+				return restutil.ErrInternalServer().Send(c, err.Error())
+			}
+
+			// Find the owners of the emotes
+			ownerIDs := []primitive.ObjectID{}
+			for _, set := range sets {
+				for _, e := range set.Emotes {
+					ownerIDs = append(ownerIDs, e.OwnerID)
+				}
+			}
+			owners := []*datastructure.User{}
+			ownerMap := map[primitive.ObjectID]*datastructure.User{}
+			if len(ownerIDs) > 0 {
+				cur, err := mongo.Collection(mongo.CollectionNameUsers).Find(ctx, bson.M{
+					"_id": bson.M{"$in": ownerIDs},
+				})
+				if err == nil {
+					err = cur.All(ctx, &owners)
+				}
+				if err != nil {
+					return restutil.ErrInternalServer().Send(c, err.Error())
+				}
+				for _, o := range owners {
+					ownerMap[o.ID] = o
+				}
+			}
+
+			response := make([]restutil.PersonalEmoteSetResponse, len(sets))
+			for i, set := range sets {
+				emotes := make([]restutil.EmoteResponse, len(set.Emotes))
+				for j, e := range set.Emotes {
+					emotes[j] = restutil.CreateEmoteResponse(e, ownerMap[e.OwnerID])
+				}
+				response[i] = restutil.PersonalEmoteSetResponse{
+					ID:         set.ID,
+					UnicodeTag: set.UnicodeTag,
+					Emotes:     emotes,
+				}
+			}
+
+			j, err := json.Marshal(response)
+			if err != nil {
+				return restutil.ErrInternalServer().Send(c, err.Error())
+			}
+
+			return c.Send(j)
+		})
+}
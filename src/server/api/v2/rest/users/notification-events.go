@@ -0,0 +1,93 @@
+package users
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/SevenTV/ServerGo/src/mongo/datastructure"
+	"github.com/SevenTV/ServerGo/src/server/api/actions"
+	"github.com/SevenTV/ServerGo/src/server/api/v2/rest/restutil"
+	"github.com/SevenTV/ServerGo/src/server/middleware"
+	"github.com/gofiber/fiber/v2"
+	"github.com/sirupsen/logrus"
+)
+
+// The interval at which a comment is sent to keep idle connections open and detect closed ones
+const notificationEventsKeepAlive = 30 * time.Second
+
+// NotificationEventsRoute: Stream the authenticated user's new notifications and read state changes as server-sent events
+func NotificationEventsRoute(router fiber.Router) {
+	router.Get(
+		"/@me/notifications/events",
+		middleware.UserAuthMiddleware(true),
+		func(c *fiber.Ctx) error {
+			usr, ok := c.Locals("user").(*datastructure.User)
+			if !ok {
+				return restutil.ErrLoginRequired().Send(c)
+			}
+
+			// The stream outlives the request handler, so it can't use the request's context
+			ctx, cancel := context.WithCancel(context.Background())
+			ch := make(chan []byte, 16)
+			actions.Notifications.Subscribe(ctx, usr.ID, ch)
+
+			count, err := actions.Notifications.GetUnreadCount(ctx, usr.ID)
+			if err != nil {
+				cancel()
+				return restutil.ErrInternalServer().Send(c, err.Error())
+			}
+
+			c.Set("Content-Type", "text/event-stream")
+			c.Set("Cache-Control", "no-cache")
+			c.Set("Connection", "keep-alive")
+			c.Set("X-Accel-Buffering", "no")
+
+			c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+				defer cancel()
+
+				write := func(event actions.NotificationEventType, data []byte) error {
+					if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, data); err != nil {
+						return err
+					}
+					return w.Flush()
+				}
+
+				// Start with the current unread count, so the client doesn't need to query it
+				initial, _ := json.Marshal(actions.NotificationEvent{
+					Type:        actions.NotificationEventCount,
+					UnreadCount: &count,
+				})
+				if err := write(actions.NotificationEventCount, initial); err != nil {
+					return
+				}
+
+				ticker := time.NewTicker(notificationEventsKeepAlive)
+				defer ticker.Stop()
+				for {
+					select {
+					case payload := <-ch:
+						event := actions.NotificationEvent{}
+						if err := json.Unmarshal(payload, &event); err != nil {
+							logrus.WithError(err).Error("notifications, bad event payload")
+							continue
+						}
+						if err := write(event.Type, payload); err != nil {
+							return
+						}
+					case <-ticker.C:
+						if _, err := w.WriteString(": keep-alive\n\n"); err != nil {
+							return
+						}
+						if err := w.Flush(); err != nil {
+							return
+						}
+					}
+				}
+			})
+			return nil
+		},
+	)
+}
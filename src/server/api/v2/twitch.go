@@ -286,15 +286,22 @@ func Twitch(app fiber.Router) fiber.Router {
 		// Check ban?
 		if banned, reason := actions.Bans.IsUserBanned(mongoUser.ID); banned {
 			var ban *datastructure.Ban
-			res := mongo.Collection(mongo.CollectionNameBans).FindOne(c.Context(), bson.M{"user_id": mongoUser.ID, "expire_at": bson.M{"$gt": time.Now()}})
+			res := mongo.Collection(mongo.CollectionNameBans).FindOne(c.Context(), bson.M{
+				"user_id": mongoUser.ID,
+				"type":    bson.M{"$in": bson.A{datastructure.BanTypeFull, nil}},
+				"$or": bson.A{
+					bson.M{"expire_at": nil},
+					bson.M{"expire_at": bson.M{"$gt": time.Now()}},
+				},
+			})
 			err = res.Err()
 			if err == nil {
 				_ = res.Decode(&ban)
-				respError = fmt.Errorf(
-					"You are currently banned for '%v'%v",
-					reason,
-					fmt.Sprintf(" until %v", utils.Ternary(ban != nil && !ban.ExpireAt.IsZero(), ban.ExpireAt.Format("Mon, 02 Jan 2006 15:04:05 MST"), "the universe fades out")),
-				)
+				until := "the universe fades out"
+				if ban != nil && ban.ExpireAt != nil {
+					until = ban.ExpireAt.Format("Mon, 02 Jan 2006 15:04:05 MST")
+				}
+				respError = fmt.Errorf("You are currently banned for '%v' until %v", reason, until)
 			} else {
 				logrus.WithError(err).Error("mongo")
 			}
@@ -23,6 +23,16 @@ type PayloadJWT struct {
 }
 
 func UserAuthMiddleware(required bool) func(c *fiber.Ctx) error {
+	return userAuthMiddleware(required, false)
+}
+
+// BannedUserAuthMiddleware: Require authentication, but let banned users through.
+// Only to be used on routes that banned users must be able to reach, such as ban appeals
+func BannedUserAuthMiddleware() func(c *fiber.Ctx) error {
+	return userAuthMiddleware(true, true)
+}
+
+func userAuthMiddleware(required bool, allowBanned bool) func(c *fiber.Ctx) error {
 	return func(c *fiber.Ctx) error {
 		auth := strings.Split(c.Get("Authorization"), " ")
 		if len(auth) != 2 && auth[0] != "Bearer" {
@@ -119,7 +129,7 @@ func UserAuthMiddleware(required bool) func(c *fiber.Ctx) error {
 		}
 
 		banned, reason := actions.Bans.IsUserBanned(user.ID)
-		if banned {
+		if banned && !allowBanned {
 			if !required {
 				return c.Next()
 			}
@@ -0,0 +1,86 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+	"gopkg.in/gographics/imagick.v3/imagick"
+)
+
+// ErrImageNotReadable is returned when an uploaded file can't be read as an image
+var ErrImageNotReadable = errors.New("Input File Not Readable")
+
+// ResizeImage: Resize all frames of the image at inPath to width x height, then write them to outPath as WebP.
+// The frames are centered on a transparent canvas of canvasWidth x canvasHeight if it is larger than the frames
+func ResizeImage(inPath, outPath string, width, height, canvasWidth, canvasHeight, quality uint) error {
+	// Get magick wand & read the original image
+	mw := imagick.NewMagickWand()
+	if err := mw.SetResourceLimit(imagick.RESOURCE_MEMORY, 500); err != nil {
+		logrus.WithError(err).Error("SetResourceLimit")
+	}
+	if err := mw.ReadImage(inPath); err != nil {
+		mw.Destroy()
+		return fmt.Errorf("%w: %s", ErrImageNotReadable, err)
+	}
+
+	// Merge all frames with coalesce
+	aw := mw.CoalesceImages()
+	if err := aw.SetResourceLimit(imagick.RESOURCE_MEMORY, 500); err != nil {
+		logrus.WithError(err).Error("SetResourceLimit")
+	}
+	mw.Destroy()
+	defer aw.Destroy()
+
+	// Set delays
+	mw = imagick.NewMagickWand()
+	if err := mw.SetResourceLimit(imagick.RESOURCE_MEMORY, 500); err != nil {
+		logrus.WithError(err).Error("SetResourceLimit")
+	}
+	defer mw.Destroy()
+
+	var background *imagick.PixelWand
+	if canvasWidth > width || canvasHeight > height {
+		background = imagick.NewPixelWand()
+		background.SetColor("none")
+		defer background.Destroy()
+	}
+
+	// Add each frame to our animated image
+	mw.ResetIterator()
+	for ind := 0; ind < int(aw.GetNumberImages()); ind++ {
+		aw.SetIteratorIndex(ind)
+		img := aw.GetImage()
+
+		if err := img.ResizeImage(width, height, imagick.FILTER_LANCZOS); err != nil {
+			logrus.WithError(err).Errorf("ResizeImage i=%v", ind)
+			img.Destroy()
+			continue
+		}
+		if background != nil {
+			if err := img.SetImageBackgroundColor(background); err != nil {
+				logrus.WithError(err).Errorf("SetImageBackgroundColor i=%v", ind)
+			}
+			x := -(int(canvasWidth) - int(width)) / 2
+			y := -(int(canvasHeight) - int(height)) / 2
+			if err := img.ExtentImage(canvasWidth, canvasHeight, x, y); err != nil {
+				logrus.WithError(err).Errorf("ExtentImage i=%v", ind)
+			}
+		}
+		if err := mw.AddImage(img); err != nil {
+			logrus.WithError(err).Errorf("AddImage i=%v", ind)
+		}
+		img.Destroy()
+	}
+
+	// Done - convert to WEBP
+	if err := mw.SetImageCompressionQuality(quality); err != nil {
+		logrus.WithError(err).Error("SetImageCompressionQuality")
+	}
+	if err := mw.SetImageFormat("webp"); err != nil {
+		logrus.WithError(err).Error("SetImageFormat")
+	}
+
+	// Write to file
+	return mw.WriteImages(outPath, true)
+}